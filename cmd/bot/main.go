@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/dm-popov-sdg/nagger/internal/bot"
 	"github.com/dm-popov-sdg/nagger/internal/config"
@@ -38,7 +39,16 @@ func main() {
 	log.Println("Successfully connected to MongoDB")
 
 	// Create Telegram bot
-	telegramBot, err := bot.NewBot(cfg.TelegramToken, mongodb)
+	telegramBot, err := bot.NewBot(cfg.TelegramToken, mongodb, bot.Options{
+		AdminIDs:            cfg.AdminIDs,
+		DefaultReminderTime: cfg.ReminderTime,
+		DefaultTimezone:     cfg.ReminderTimezone,
+		CallbackSecret:      cfg.CallbackSecret,
+		UpdateWorkers:       cfg.UpdateWorkers,
+		UpdateQueueSize:     cfg.UpdateQueueSize,
+		Webhook:             webhookOptions(cfg),
+		ReminderTemplate:    cfg.ReminderTemplate,
+	})
 	if err != nil {
 		log.Fatalf("Failed to create bot: %v", err)
 	}
@@ -50,6 +60,9 @@ func main() {
 		telegramBot,
 		cfg.ReminderTime,
 		cfg.ReminderTimezone,
+		mongodb,
+		cfg.InstanceID,
+		time.Duration(cfg.LeaderLeaseTTL)*time.Second,
 	)
 	if err != nil {
 		log.Fatalf("Failed to create scheduler: %v", err)
@@ -67,9 +80,7 @@ func main() {
 	errChan := make(chan error, 1)
 	go func() {
 		log.Println("Starting bot...")
-		if err := telegramBot.Start(ctx); err != nil {
-			errChan <- err
-		}
+		errChan <- telegramBot.Start(ctx)
 	}()
 
 	// Wait for termination signal or error
@@ -77,6 +88,10 @@ func main() {
 	case <-sigChan:
 		log.Println("Received termination signal, shutting down...")
 		cancel()
+		// Start returns once the updates already received are handled
+		if err := <-errChan; err != nil {
+			log.Printf("Bot error: %v", err)
+		}
 	case err := <-errChan:
 		log.Printf("Bot error: %v", err)
 		cancel()
@@ -127,6 +142,9 @@ func (s *settingsAdapter) GetUserSettings(ctx context.Context, chatID int64) (*s
 		ChatID:       settings.ChatID,
 		ReminderTime: settings.ReminderTime,
 		Timezone:     settings.Timezone,
+		Paused:       settings.Paused,
+		SkipWeekends: settings.SkipWeekends,
+		Digest:       settings.Digest && settings.ChatID > 0,
 	}, nil
 }
 
@@ -143,8 +161,28 @@ func (s *settingsAdapter) GetAllUserSettings(ctx context.Context) (map[int64]*sc
 			ChatID:       userSettings.ChatID,
 			ReminderTime: userSettings.ReminderTime,
 			Timezone:     userSettings.Timezone,
+			Paused:       userSettings.Paused,
+			SkipWeekends: userSettings.SkipWeekends,
+			Digest:       userSettings.Digest && userSettings.ChatID > 0, // Digests go to private chats only
 		}
 	}
 
 	return result, nil
 }
+
+// webhookOptions returns the webhook settings, or nil in polling mode
+func webhookOptions(cfg *config.Config) *bot.WebhookOptions {
+	if cfg.UpdateMode != config.UpdateModeWebhook {
+		return nil
+	}
+	return &bot.WebhookOptions{
+		URL:              cfg.WebhookURL,
+		Listen:           cfg.WebhookListen,
+		SecretToken:      cfg.WebhookSecret,
+		CertFile:         cfg.WebhookCertFile,
+		KeyFile:          cfg.WebhookKeyFile,
+		UploadCert:       cfg.WebhookSelfSigned,
+		MaxConnections:   cfg.WebhookMaxConnections,
+		DeleteOnShutdown: cfg.WebhookDeleteOnShutdown,
+	}
+}
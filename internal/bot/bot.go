@@ -2,13 +2,16 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
 	"github.com/dm-popov-sdg/nagger/internal/storage"
+	"github.com/dm-popov-sdg/nagger/internal/timezone"
 	"github.com/dm-popov-sdg/nagger/internal/types"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -16,12 +19,50 @@ import (
 
 // Bot represents the Telegram bot
 type Bot struct {
-	api     *tgbotapi.BotAPI
-	storage *storage.MongoDB
+	api      *tgbotapi.BotAPI
+	storage  store
+	sender   *sender
+	adminIDs []int64
+
+	callbacks  callbackCodec
+	routes     map[string]callbackHandler
+	commands   map[string]*command
+	handler    handlerFunc
+	metrics    *metrics
+	limiter    *rateLimiter
+	members    *memberCache
+	chatAdmins *chatAdmins
+	reminders  *reminderRefresher
+
+	updateWorkers   int
+	updateQueueSize int
+	webhook         *WebhookOptions
+
+	defaultReminderTime string
+	defaultTimezone     string
+	defaultTemplate     string
+}
+
+// Options holds optional bot settings
+type Options struct {
+	AdminIDs            []int64         // Telegram user IDs allowed to run admin commands
+	DefaultReminderTime string          // Reminder time for chats without settings, format: "HH:MM"
+	DefaultTimezone     string          // Timezone for chats without settings
+	CallbackSecret      string          // Signs inline button payloads when set
+	UpdateWorkers       int             // Updates handled concurrently; defaults to 16
+	UpdateQueueSize     int             // Updates buffered per worker; defaults to 64
+	Webhook             *WebhookOptions // Receive updates over HTTPS instead of long polling when set
+	ReminderTemplate    string          // Reminder template for chats without their own; empty for the built-in text
 }
 
 // NewBot creates a new Telegram bot instance
-func NewBot(token string, storage *storage.MongoDB) (*Bot, error) {
+func NewBot(token string, storage *storage.MongoDB, opts Options) (*Bot, error) {
+	if opts.ReminderTemplate != "" {
+		if err := validateReminderTemplate(opts.ReminderTemplate); err != nil {
+			return nil, fmt.Errorf("invalid reminder template: %w", err)
+		}
+	}
+
 	api, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create bot: %w", err)
@@ -29,234 +70,523 @@ func NewBot(token string, storage *storage.MongoDB) (*Bot, error) {
 
 	log.Printf("Authorized on account %s", api.Self.UserName)
 
-	return &Bot{
-		api:     api,
-		storage: storage,
-	}, nil
+	b := &Bot{
+		api:        api,
+		storage:    storage,
+		sender:     newSender(api, realClock{}, defaultSendLimits()),
+		adminIDs:   opts.AdminIDs,
+		callbacks:  callbackCodec{secret: []byte(opts.CallbackSecret)},
+		metrics:    newMetrics(),
+		limiter:    newRateLimiter(realClock{}, userBurst, userRefill),
+		members:    newMemberCache(),
+		chatAdmins: newChatAdmins(),
+
+		updateWorkers:   opts.UpdateWorkers,
+		updateQueueSize: opts.UpdateQueueSize,
+		webhook:         opts.Webhook,
+
+		defaultReminderTime: opts.DefaultReminderTime,
+		defaultTimezone:     opts.DefaultTimezone,
+		defaultTemplate:     opts.ReminderTemplate,
+	}
+	b.routes = b.callbackRoutes()
+	b.handler = b.buildHandler()
+	b.reminders = newReminderRefresher(reminderRefreshDelay, b.renderReminders)
+
+	if b.updateWorkers <= 0 {
+		b.updateWorkers = defaultUpdateWorkers
+	}
+	if b.updateQueueSize <= 0 {
+		b.updateQueueSize = defaultUpdateQueueSize
+	}
+
+	return b, nil
 }
 
-// Start starts the bot
+// Start starts the bot. After ctx is cancelled it stops fetching updates and
+// returns once the updates already received are handled.
 func (b *Bot) Start(ctx context.Context) error {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-
-	updates := b.api.GetUpdatesChan(u)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case update := <-updates:
-			if update.Message != nil {
-				b.handleMessage(ctx, update.Message)
-			} else if update.CallbackQuery != nil {
-				b.handleCallbackQuery(ctx, update.CallbackQuery)
-			}
-		}
-	}
+	// Handlers and the sender outlive ctx so queued updates can still be answered
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	go b.sender.run(handlerCtx)
+	outboxDone := make(chan struct{})
+	go func() {
+		defer close(outboxDone)
+		b.runOutbox(ctx)
+	}()
+	b.setCommandMenus()
+
+	d := newDispatcher(b.updateWorkers, b.updateQueueSize, b.dispatch)
+	d.start(handlerCtx)
+
+	var err error
+	if b.webhook != nil {
+		err = b.serveWebhook(ctx, d)
+	} else {
+		b.poll(ctx, d)
+	}
+
+	log.Println("Handling queued updates before shutdown...")
+	if !d.drain(drainTimeout) {
+		log.Printf("Gave up on queued updates after %s", drainTimeout)
+	}
+	// The sender stops with the handlers, so claimed outbox messages are settled first
+	<-outboxDone
+	return err
 }
 
+// handleMessage handles messages that are not commands
 func (b *Bot) handleMessage(ctx context.Context, message *tgbotapi.Message) {
-	if !message.IsCommand() {
+	if message.Location != nil {
+		b.handleLocation(ctx, message)
 		return
 	}
 
-	switch message.Command() {
-	case "start":
-		b.handleStart(message)
-	case "help":
-		b.handleHelp(message)
-	case "add":
-		b.handleAdd(ctx, message)
-	case "list":
-		b.handleList(ctx, message)
-	case "done":
-		b.handleDone(ctx, message)
-	case "delete":
-		b.handleDelete(ctx, message)
-	case "setreminder":
-		b.handleSetReminder(ctx, message)
-	default:
-		b.sendMessage(message.Chat.ID, "Unknown command. Use /help to see available commands.")
+	if !b.handleConversationMessage(ctx, message) {
+		b.handleQuickCapture(ctx, message)
 	}
 }
 
-func (b *Bot) handleStart(message *tgbotapi.Message) {
-	text := `Welcome to Nagger Bot! 🤖
-
-I'll help you manage your tasks and remind you about them every day.
-
-Use /help to see available commands.`
-	b.sendMessage(message.Chat.ID, text)
+func init() {
+	registerCommands(helpTasks, (*Bot).taskCommands)
 }
 
-func (b *Bot) handleHelp(message *tgbotapi.Message) {
-	text := `Available commands:
+// taskCommands are the commands that manage a chat's own tasks
+func (b *Bot) taskCommands() []*command {
+	return []*command{
+		{name: "add", usage: "[@user...] <task>", handler: b.handleAdd},
+		{name: "list", handler: b.handleList},
+		{name: "done", usage: "<task>", handler: b.handleDone},
+		{name: "edit", usage: "<task> <text>", handler: b.handleEdit},
+		{name: "delete", usage: "<task>", handler: b.handleDelete},
+		{name: "setreminder", usage: "<HH:MM> [timezone]", handler: b.handleSetReminder},
+	}
+}
 
-/add <task> - Add a new task
-/list - Show all active tasks
-/done <task_number> - Mark a task as completed for today
-/delete <task_number> - Close a task permanently (no more reminders)
-/setreminder <HH:MM> [timezone] - Set your daily reminder time (24-hour format)
-/help - Show this help message
+func (b *Bot) handleStart(ctx context.Context, message *tgbotapi.Message) {
+	// Sending /start again is how a user who blocked the bot opts back in
+	reactivated, err := b.storage.ActivateChat(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error activating chat %d: %v", message.Chat.ID, err)
+	} else if reactivated {
+		log.Printf("Chat %d is reachable again, reminders resumed", message.Chat.ID)
+	}
+	b.rememberChatName(ctx, message.Chat)
 
-I'll send you a reminder about your tasks every day at your configured time.
+	// Invite links to shared lists open the bot with a join_<token> payload
+	if token, ok := strings.CutPrefix(message.CommandArguments(), joinPayloadPrefix); ok {
+		b.joinList(ctx, message, token)
+		return
+	}
 
-Examples:
-/setreminder 09:00 - Set reminder to 9:00 AM UTC
-/setreminder 14:30 America/New_York - Set reminder to 2:30 PM EST/EDT`
-	b.sendMessage(message.Chat.ID, text)
+	if !b.permit(ctx, message, permSettings, nil) {
+		return
+	}
+	b.startOnboarding(ctx, message)
 }
 
+// handleAdd adds a task. In groups it can be assigned: /add @alice @bob <task>.
 func (b *Bot) handleAdd(ctx context.Context, message *tgbotapi.Message) {
-	description := strings.TrimSpace(message.CommandArguments())
+	assignees, description, ok := b.parseAssignees(ctx, message, 0)
+	if !ok {
+		return
+	}
 	if description == "" {
-		b.sendMessage(message.Chat.ID, "Please provide a task description. Usage: /add <task>")
+		var data map[string]string
+		if len(assignees) > 0 {
+			data = map[string]string{"assignees": formatIDs(assignees)}
+		}
+		p := b.printer(ctx, message.Chat.ID, message.From)
+		b.prompt(ctx, message, flowAdd, "description", data, p.T("add.prompt"), p.T("add.placeholder"))
 		return
 	}
 
-	task := &storage.Task{
-		ChatID:      message.Chat.ID,
-		UserID:      message.From.ID,
-		Description: description,
-	}
+	b.addTask(ctx, message, &storage.Task{Description: description, Assignees: assignees})
+}
 
-	if err := b.storage.AddTask(ctx, task); err != nil {
-		log.Printf("Error adding task: %v", err)
-		b.sendMessage(message.Chat.ID, "Failed to add task. Please try again.")
+func (b *Bot) continueAdd(ctx context.Context, message *tgbotapi.Message, conv *storage.Conversation) {
+	assignees, description, ok := b.parseAssignees(ctx, message, 0)
+	if !ok {
+		return
+	}
+	if description == "" {
+		b.sendMessage(message.Chat.ID, b.printer(ctx, message.Chat.ID, message.From).T("add.text_only"))
 		return
 	}
 
-	b.sendMessage(message.Chat.ID, fmt.Sprintf("✅ Task added: %s", description))
+	// Members mentioned with the command and in the answer are both assigned
+	merged := parseIDs(conv.Data["assignees"])
+	for _, id := range assignees {
+		merged = appendID(merged, id)
+	}
+
+	b.endConversation(ctx, message.Chat.ID, message.From.ID)
+	b.addTask(ctx, message, &storage.Task{
+		Description: description,
+		Assignees:   merged,
+		PerMember:   conv.Data["per_member"] == "1",
+	})
 }
 
-func (b *Bot) handleList(ctx context.Context, message *tgbotapi.Message) {
-	tasks, err := b.storage.GetTasksByChatID(ctx, message.Chat.ID)
-	if err != nil {
-		log.Printf("Error getting tasks: %v", err)
-		b.sendMessage(message.Chat.ID, "Failed to get tasks. Please try again.")
-		return
+// addTask stores a task from the message's sender and confirms it. It returns false on failure.
+func (b *Bot) addTask(ctx context.Context, message *tgbotapi.Message, task *storage.Task) bool {
+	task.ChatID = message.Chat.ID
+	task.UserID = message.From.ID
+	p := b.printer(ctx, message.Chat.ID, message.From)
+
+	if err := b.storage.AddTask(ctx, task); err != nil {
+		log.Printf("Error adding task: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("add.failed"))
+		return false
 	}
+	b.remindersChanged(ctx, task.ChatID)
 
-	if len(tasks) == 0 {
-		b.sendMessage(message.Chat.ID, "You have no active tasks. Great job! 🎉")
-		return
+	text := formatHTML(p.T("add.added"), bold(task.Description)) + " " + string(taskIDHTML(*task))
+	switch {
+	case task.Rotation != nil:
+		text += "\n" + escapeHTML(formatTurn(p, *task.Rotation, b.chatMembers(ctx, message.Chat.ID)))
+	case task.PerMember && len(task.Assignees) > 0:
+		text += "\n" + escapeHTML(p.T("add.each_of", memberNames(p, task.Assignees, b.chatMembers(ctx, message.Chat.ID))))
+	case task.PerMember:
+		text += "\n" + escapeHTML(p.T("add.every_member"))
+	case len(task.Assignees) > 0:
+		text += "\n" + escapeHTML(p.T("add.assigned", memberNames(p, task.Assignees, b.chatMembers(ctx, message.Chat.ID))))
 	}
+	b.sendHTML(ctx, message.Chat.ID, text)
+	return true
+}
 
-	var text strings.Builder
-	text.WriteString("📋 Your tasks:\n\n")
-	for i, task := range tasks {
-		statusEmoji := ""
-		switch task.Status {
-		case storage.TaskStatusCompletedToday:
-			statusEmoji = " ✅"
-		case storage.TaskStatusActive, "":
-			statusEmoji = ""
+func (b *Bot) handleDone(ctx context.Context, message *tgbotapi.Message) {
+	tasks := b.findTasks(ctx, message, message.CommandArguments(), "/done <task>", taskActionDone)
+	b.applyToTasks(ctx, message, tasks, taskActionDone)
+}
+
+// completeTask completes a task, or the user's part of a per-member task, and returns
+// the reply in HTML
+func (b *Bot) completeTask(ctx context.Context, p *i18n.Printer, task *storage.Task, userID int64) string {
+	if task.PerMember {
+		return b.completeTaskForMember(ctx, p, task, userID)
+	}
+	if task.Rotation != nil && task.Rotation.Period == storage.RotationOnCompletion {
+		next, err := b.completeTurn(ctx, task)
+		if err != nil {
+			log.Printf("Error completing task: %v", err)
+			return escapeHTML(p.T("done.failed"))
 		}
-		text.WriteString(fmt.Sprintf("%d. %s%s\n", i+1, task.Description, statusEmoji))
+		return formatHTML(p.T("done.next_turn"), strike(task.Description),
+			memberNames(p, []int64{next}, b.chatMembers(ctx, task.ChatID)))
+	}
+
+	if err := b.storage.CompleteTask(ctx, task.ID); err != nil {
+		log.Printf("Error completing task: %v", err)
+		return escapeHTML(p.T("done.failed"))
 	}
+	b.remindersChanged(ctx, task.ChatID)
+	b.recordStreak(ctx, task.ChatID)
 
-	b.sendMessage(message.Chat.ID, text.String())
+	return formatHTML(p.T("done.completed"), strike(task.Description))
 }
 
-func (b *Bot) handleDone(ctx context.Context, message *tgbotapi.Message) {
-	taskNumber, err := b.parseTaskNumber(message.CommandArguments())
-	if err != nil {
-		b.sendMessage(message.Chat.ID, "Please provide a valid task number. Usage: /done <task_number>")
-		return
+// completeTaskForMember completes the user's part of a per-member task
+func (b *Bot) completeTaskForMember(ctx context.Context, p *i18n.Printer, task *storage.Task, userID int64) string {
+	if len(task.Assignees) > 0 && !task.IsAssignedTo(userID) {
+		return escapeHTML(p.T("done.for_assignees", memberNames(p, task.Assignees, b.chatMembers(ctx, task.ChatID))))
 	}
 
-	tasks, err := b.storage.GetTasksByChatID(ctx, message.Chat.ID)
+	done, total, err := b.completeForMember(ctx, task, userID)
 	if err != nil {
-		log.Printf("Error getting tasks: %v", err)
-		b.sendMessage(message.Chat.ID, "Failed to get tasks. Please try again.")
+		log.Printf("Error completing task: %v", err)
+		return escapeHTML(p.T("done.failed"))
+	}
+
+	if done >= total {
+		return formatHTML(p.T("done.by_everyone"), strike(task.Description))
+	}
+	return formatHTML(p.T("done.for_you"), bold(task.Description), done, total)
+}
+
+func (b *Bot) handleEdit(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "" {
+		b.prompt(ctx, message, flowEdit, "number", nil, p.T("edit.prompt_number"), p.T("edit.placeholder"))
 		return
 	}
 
-	if taskNumber < 1 || taskNumber > len(tasks) {
-		b.sendMessage(message.Chat.ID, fmt.Sprintf("Invalid task number. You have %d tasks.", len(tasks)))
+	taskArg, description, _ := strings.Cut(args, " ")
+	task := b.findTask(ctx, message, taskArg, "/edit <task> <text>")
+	if task == nil || !b.permit(ctx, message, permEdit, task) {
 		return
 	}
 
-	task := tasks[taskNumber-1]
-	if err := b.storage.CompleteTask(ctx, task.ID); err != nil {
-		log.Printf("Error completing task: %v", err)
-		b.sendMessage(message.Chat.ID, "Failed to complete task. Please try again.")
+	description = strings.TrimSpace(description)
+	if description == "" {
+		data := map[string]string{"task_id": task.ID.Hex()}
+		b.prompt(ctx, message, flowEdit, "description", data, p.T("edit.prompt_text", task.Description), task.Description)
 		return
 	}
 
-	b.sendMessage(message.Chat.ID, fmt.Sprintf("✅ Task completed: %s", task.Description))
+	b.editTask(ctx, message, task, description)
 }
 
-func (b *Bot) handleDelete(ctx context.Context, message *tgbotapi.Message) {
-	taskNumber, err := b.parseTaskNumber(message.CommandArguments())
-	if err != nil {
-		b.sendMessage(message.Chat.ID, "Please provide a valid task number. Usage: /delete <task_number>")
+func (b *Bot) continueEdit(ctx context.Context, message *tgbotapi.Message, conv *storage.Conversation) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	text := strings.TrimSpace(message.Text)
+	if text == "" {
+		b.sendMessage(message.Chat.ID, p.T("conversation.text_only"))
 		return
 	}
 
-	tasks, err := b.storage.GetTasksByChatID(ctx, message.Chat.ID)
-	if err != nil {
-		log.Printf("Error getting tasks: %v", err)
-		b.sendMessage(message.Chat.ID, "Failed to get tasks. Please try again.")
-		return
+	switch conv.Step {
+	case "number":
+		task := b.findTask(ctx, message, text, p.T("task.number_hint"))
+		if task == nil || !b.permit(ctx, message, permEdit, task) {
+			return
+		}
+		data := map[string]string{"task_id": task.ID.Hex()}
+		b.prompt(ctx, message, flowEdit, "description", data, p.T("edit.prompt_text", task.Description), task.Description)
+
+	case "description":
+		taskID, err := primitive.ObjectIDFromHex(conv.Data["task_id"])
+		if err != nil {
+			log.Printf("Invalid task ID in conversation: %v", err)
+			b.endConversation(ctx, message.Chat.ID, message.From.ID)
+			return
+		}
+
+		b.endConversation(ctx, message.Chat.ID, message.From.ID)
+		task, err := b.getTask(ctx, message.Chat.ID, taskID)
+		if err != nil {
+			log.Printf("Error getting task: %v", err)
+			b.sendMessage(message.Chat.ID, p.T("edit.failed"))
+			return
+		}
+		if task == nil {
+			b.sendMessage(message.Chat.ID, p.T("task.gone"))
+			return
+		}
+		if !b.permit(ctx, message, permEdit, task) {
+			return
+		}
+		b.editTask(ctx, message, task, text)
 	}
+}
 
-	if taskNumber < 1 || taskNumber > len(tasks) {
-		b.sendMessage(message.Chat.ID, fmt.Sprintf("Invalid task number. You have %d tasks.", len(tasks)))
+func (b *Bot) editTask(ctx context.Context, message *tgbotapi.Message, task *storage.Task, description string) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	if err := b.storage.UpdateTaskDescription(ctx, task.ID, description); err != nil {
+		log.Printf("Error updating task: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("edit.failed"))
 		return
 	}
+	b.remindersChanged(ctx, task.ChatID)
 
-	task := tasks[taskNumber-1]
+	b.sendHTML(ctx, message.Chat.ID, formatHTML(p.T("edit.updated"), bold(description)))
+}
+
+func (b *Bot) handleDelete(ctx context.Context, message *tgbotapi.Message) {
+	tasks := b.findTasks(ctx, message, message.CommandArguments(), "/delete <task>", taskActionDelete)
+	b.applyToTasks(ctx, message, tasks, taskActionDelete)
+}
+
+// closeTask closes a task and returns the reply in HTML
+func (b *Bot) closeTask(ctx context.Context, p *i18n.Printer, task *storage.Task) string {
 	if err := b.storage.CloseTask(ctx, task.ID); err != nil {
 		log.Printf("Error closing task: %v", err)
-		b.sendMessage(message.Chat.ID, "Failed to close task. Please try again.")
-		return
+		return escapeHTML(p.T("delete.failed"))
 	}
+	b.remindersChanged(ctx, task.ChatID)
 
-	b.sendMessage(message.Chat.ID, fmt.Sprintf("🗑️ Task closed: %s", task.Description))
+	return formatHTML(p.T("delete.closed"), strike(task.Description))
 }
 
 func (b *Bot) handleSetReminder(ctx context.Context, message *tgbotapi.Message) {
+	if !b.permit(ctx, message, permSettings, nil) {
+		return
+	}
+
+	p := b.printer(ctx, message.Chat.ID, message.From)
 	args := strings.Fields(message.CommandArguments())
 	if len(args) == 0 {
-		b.sendMessage(message.Chat.ID, "Please provide a reminder time. Usage: /setreminder <HH:MM> [timezone]\nExample: /setreminder 09:00 UTC")
+		b.prompt(ctx, message, flowSetReminder, "time", nil, p.T("reminder.prompt_time"), p.T("reminder.placeholder_time"))
 		return
 	}
 
 	reminderTime := args[0]
 	// Validate time format
 	if !isValidTimeFormat(reminderTime) {
-		b.sendMessage(message.Chat.ID, "Invalid time format. Please use 24-hour format HH:MM (e.g., 09:00, 14:30)")
+		b.sendMessage(message.Chat.ID, p.T("reminder.invalid_time"))
+		return
+	}
+
+	// City names may contain spaces
+	b.saveReminderTime(ctx, message, reminderTime, strings.Join(args[1:], " "))
+}
+
+func (b *Bot) continueSetReminder(ctx context.Context, message *tgbotapi.Message, conv *storage.Conversation) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	text := strings.TrimSpace(message.Text)
+
+	switch conv.Step {
+	case "time":
+		if !isValidTimeFormat(text) {
+			b.sendMessage(message.Chat.ID, p.T("reminder.invalid_time_cancel"))
+			return
+		}
+
+		settings, err := b.getSettings(ctx, message.Chat.ID)
+		if err != nil {
+			log.Printf("Error getting user settings: %v", err)
+			b.sendMessage(message.Chat.ID, p.T("reminder.save_failed"))
+			return
+		}
+
+		data := map[string]string{"time": text}
+		b.prompt(ctx, message, flowSetReminder, "timezone", data,
+			p.T("reminder.prompt_timezone", settings.Timezone), p.T("reminder.placeholder_timezone"))
+
+	case "timezone":
+		if text == "" {
+			b.sendMessage(message.Chat.ID, p.T("conversation.text_only"))
+			return
+		}
+		if text == "-" {
+			text = ""
+		} else if _, err := timezone.Parse(text); err != nil {
+			// Keep the conversation so the user can try another spelling
+			b.sendMessage(message.Chat.ID, invalidTimezoneText(p, text, err))
+			return
+		}
+
+		b.endConversation(ctx, message.Chat.ID, message.From.ID)
+		b.saveReminderTime(ctx, message, conv.Data["time"], text)
+	}
+}
+
+// saveReminderTime stores the reminder time and, if tzInput is not empty, the timezone
+func (b *Bot) saveReminderTime(ctx context.Context, message *tgbotapi.Message, reminderTime, tzInput string) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	settings, err := b.getSettings(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("reminder.save_failed"))
 		return
 	}
 
-	// Default timezone is UTC
-	timezone := "UTC"
-	if len(args) > 1 {
-		timezone = args[1]
-		// Validate timezone
-		if !isValidTimezone(timezone) {
-			b.sendMessage(message.Chat.ID, fmt.Sprintf("Invalid timezone: %s. Please use a valid timezone (e.g., UTC, America/New_York)", timezone))
+	// Keep the current timezone unless a new one is given
+	if tzInput != "" {
+		tzName, err := timezone.Parse(tzInput)
+		if err != nil {
+			b.sendMessage(message.Chat.ID, invalidTimezoneText(p, tzInput, err))
 			return
 		}
+		settings.Timezone = tzName
+	}
+
+	settings.UserID = message.From.ID
+	settings.ReminderTime = reminderTime
+
+	if err := b.storage.SetUserSettings(ctx, settings); err != nil {
+		log.Printf("Error setting user settings: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("reminder.save_failed"))
+		return
 	}
 
-	// Create or update user settings
-	settings := &storage.UserSettings{
-		ChatID:       message.Chat.ID,
-		UserID:       message.From.ID,
-		ReminderTime: reminderTime,
-		Timezone:     timezone,
+	b.sendMessage(message.Chat.ID, p.T("reminder.set", settings.ReminderTime, settings.Timezone))
+}
+
+func (b *Bot) handleLocation(ctx context.Context, message *tgbotapi.Message) {
+	if !b.permit(ctx, message, permSettings, nil) {
+		return
+	}
+
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	settings, err := b.getSettings(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("timezone.save_failed"))
+		return
+	}
+
+	tzName, exact := timezone.FromCoordinates(message.Location.Latitude, message.Location.Longitude)
+	if !exact {
+		b.sendTimezoneGuess(ctx, p, message.Chat.ID, settings, tzName)
+		return
+	}
+
+	settings.Timezone = tzName
+	if message.From != nil {
+		settings.UserID = message.From.ID
 	}
 
 	if err := b.storage.SetUserSettings(ctx, settings); err != nil {
 		log.Printf("Error setting user settings: %v", err)
-		b.sendMessage(message.Chat.ID, "Failed to save reminder settings. Please try again.")
+		b.sendMessage(message.Chat.ID, p.T("timezone.save_failed"))
+		return
+	}
+
+	if b.handleOnboardingLocation(ctx, message.Chat, message.From, settings) {
 		return
 	}
 
-	b.sendMessage(message.Chat.ID, fmt.Sprintf("✅ Reminder time set to %s %s", reminderTime, timezone))
+	if loc, err := timezone.Load(settings.Timezone); err == nil {
+		b.sendMessage(message.Chat.ID, p.T("timezone.set_local", settings.Timezone, p.Time(time.Now().In(loc)), settings.ReminderTime))
+		return
+	}
+	b.sendMessage(message.Chat.ID, p.T("timezone.set", settings.Timezone, settings.ReminderTime))
+}
+
+// sendTimezoneGuess asks to confirm a timezone guessed for a location outside the
+// boundary map, offering the region list instead. The buttons belong to the onboarding
+// wizard while it waits for the timezone and to the /settings menu otherwise.
+func (b *Bot) sendTimezoneGuess(ctx context.Context, p *i18n.Printer, chatID int64, settings *storage.UserSettings, tzName string) {
+	prefix, list := settingsCallbackPrefix, settingsCallbackPrefix+"tz"
+	if settings.Onboarding == storage.OnboardingTimezone {
+		prefix, list = onboardingCallbackPrefix, onboardingCallbackPrefix+"tzlist"
+	}
+
+	localTime := ""
+	if loc, err := timezone.Load(tzName); err == nil {
+		localTime = p.Time(time.Now().In(loc))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(p.T("timezone.guess_confirm", tzName), prefix+"tzz_"+tzName),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(p.T("timezone.guess_list"), list),
+		),
+	)
+	if _, err := b.sendHTMLMarkup(ctx, chatID, escapeHTML(p.T("timezone.guess", tzName, localTime)), b.signedKeyboard(chatID, &keyboard)); err != nil {
+		log.Printf("Error sending timezone guess: %v", err)
+	}
+}
+
+// getSettings returns the chat's settings, or the configured defaults if it has none
+func (b *Bot) getSettings(ctx context.Context, chatID int64) (*storage.UserSettings, error) {
+	settings, err := b.storage.GetUserSettings(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = &storage.UserSettings{
+			ChatID:       chatID,
+			ReminderTime: b.defaultReminderTime,
+			Timezone:     b.defaultTimezone,
+		}
+	}
+	return settings, nil
+}
+
+// invalidTimezoneText explains a rejected timezone and suggests close matches
+func invalidTimezoneText(p *i18n.Printer, input string, err error) string {
+	var notFound *timezone.NotFoundError
+	if errors.As(err, &notFound) && len(notFound.Suggestions) > 0 {
+		return p.T("timezone.did_you_mean", input, strings.Join(notFound.Suggestions, ", "))
+	}
+	return p.T("timezone.unknown", input)
 }
 
 func isValidTimeFormat(timeStr string) bool {
@@ -279,155 +609,141 @@ func isValidTimeFormat(timeStr string) bool {
 	return true
 }
 
-func isValidTimezone(tz string) bool {
-	// Try to load the timezone
-	_, err := time.LoadLocation(tz)
-	return err == nil
-}
-
-func (b *Bot) parseTaskNumber(arg string) (int, error) {
-	arg = strings.TrimSpace(arg)
-	if arg == "" {
-		return 0, fmt.Errorf("empty argument")
-	}
-	return strconv.Atoi(arg)
-}
-
 func (b *Bot) sendMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	if _, err := b.api.Send(msg); err != nil {
-		log.Printf("Error sending message: %v", err)
-	}
+	b.sendHTML(context.Background(), chatID, escapeHTML(text))
 }
 
-// SendDailyReminder sends a daily reminder about active tasks
+// SendDailyReminder queues a daily reminder about active tasks
 func (b *Bot) SendDailyReminder(ctx context.Context, chatID int64, tasks []string) error {
 	if len(tasks) == 0 {
 		return nil
 	}
 
+	p := b.printer(ctx, chatID, nil)
 	var text strings.Builder
-	text.WriteString("🔔 Daily Reminder!\n\n")
-	text.WriteString(fmt.Sprintf("You have %d active task(s):", len(tasks)))
+	text.WriteString(p.T("reminder.header") + "\n\n")
+	text.WriteString(p.N("reminder.active", len(tasks)))
 
-	msg := tgbotapi.NewMessage(chatID, text.String())
-	_, err := b.api.Send(msg)
-	return err
+	return b.enqueueMessage(ctx, chatID, outboxKindReminder, text.String(), nil, nil)
 }
 
-// SendDailyReminderWithTasks sends a daily reminder with inline keyboard for task completion
+// SendDailyReminderWithTasks queues a daily reminder with inline keyboard for task completion.
+// In groups it mentions the members the tasks are waiting for.
 func (b *Bot) SendDailyReminderWithTasks(ctx context.Context, chatID int64, tasks []types.TaskWithID) error {
 	if len(tasks) == 0 {
 		return nil
 	}
 
-	var text strings.Builder
-	text.WriteString("🔔 Daily Reminder!\n\n")
-	text.WriteString(fmt.Sprintf("You have %d active task(s). Click on a task to mark it as done:", len(tasks)))
+	chatTasks := storageTasks(tasks)
+	members := b.chatMembers(ctx, chatID)
+	b.refreshRotations(ctx, chatID, chatTasks, members)
 
-	// Create inline keyboard with tasks
-	var rows [][]tgbotapi.InlineKeyboardButton
-	for _, task := range tasks {
-		statusEmoji := "⬜"
-		if task.GetStatus() == string(storage.TaskStatusCompletedToday) {
-			statusEmoji = "✅"
+	keyboard := b.callbacks.signKeyboard(chatID, reminderKeyboard(chatTasks, 0, members))
+
+	p := b.printer(ctx, chatID, nil)
+	if header, ok := b.renderReminder(ctx, chatID, chatTasks); ok {
+		var assignments entityText
+		writeAssignments(p, &assignments, chatTasks, members)
+		if text := header + assignments.HTML(); htmlLength(text) <= messageTextLimit {
+			return b.enqueueHTML(ctx, chatID, outboxKindReminder, text, &keyboard)
 		}
-		buttonText := fmt.Sprintf("%s %s", statusEmoji, task.GetDescription())
-		buttonData := fmt.Sprintf("complete_%s", task.GetID())
-		button := tgbotapi.NewInlineKeyboardButtonData(buttonText, buttonData)
-		row := tgbotapi.NewInlineKeyboardRow(button)
-		rows = append(rows, row)
+		log.Printf("Reminder template of chat %d is too long with the assignments, using the default", chatID)
 	}
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
-	msg := tgbotapi.NewMessage(chatID, text.String())
-	msg.ReplyMarkup = keyboard
-	_, err := b.api.Send(msg)
-	return err
+	var text entityText
+	text.WriteString(p.T("reminder.header") + "\n\n")
+	text.WriteString(p.N("reminder.active_tap", len(tasks)))
+	writeAssignments(p, &text, chatTasks, members)
+
+	return b.enqueueMessage(ctx, chatID, outboxKindReminder, text.String(), text.entities, &keyboard)
 }
 
-func (b *Bot) handleCallbackQuery(ctx context.Context, query *tgbotapi.CallbackQuery) {
-	// Acknowledge the callback query
-	callback := tgbotapi.NewCallback(query.ID, "")
-	if _, err := b.api.Request(callback); err != nil {
-		log.Printf("Error acknowledging callback: %v", err)
+// storageTasks recovers the tasks behind the scheduler's interface values
+func storageTasks(tasks []types.TaskWithID) []storage.Task {
+	result := make([]storage.Task, 0, len(tasks))
+	for _, task := range tasks {
+		if t, ok := task.(storage.Task); ok {
+			result = append(result, t)
+			continue
+		}
+		id, _ := primitive.ObjectIDFromHex(task.GetID())
+		result = append(result, storage.Task{
+			ID:          id,
+			Description: task.GetDescription(),
+			Status:      storage.TaskStatus(task.GetStatus()),
+		})
+	}
+	return result
+}
+
+// handleCompleteCallback toggles a task from a daily reminder. Args: task ID, page.
+func (b *Bot) handleCompleteCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) callbackAnswer {
+	if len(args) != 2 {
+		return answerStale
 	}
+	page, _ := strconv.Atoi(args[1])
 
-	// Check if this is a task completion callback
-	if strings.HasPrefix(query.Data, "complete_") {
-		taskIDHex := strings.TrimPrefix(query.Data, "complete_")
-		taskID, err := primitive.ObjectIDFromHex(taskIDHex)
-		if err != nil {
-			log.Printf("Invalid task ID in callback: %v", err)
-			return
-		}
+	task, answer := b.taskForCallback(ctx, query, args[0])
+	if task == nil {
+		// Show the current tasks instead of the stale button
+		b.updateReminderKeyboard(ctx, query.Message, page)
+		return answer
+	}
+	if denied, ok := b.permitCallback(ctx, query, permComplete, task); !ok {
+		return denied
+	}
 
-		// Get the task to check its current status
-		tasks, err := b.storage.GetTasksByChatID(ctx, query.Message.Chat.ID)
-		if err != nil {
-			log.Printf("Error getting tasks: %v", err)
-			return
-		}
+	answer, err := b.toggleTask(ctx, b.printer(ctx, query.Message.Chat.ID, query.From), task, query.From.ID)
+	if err != nil {
+		log.Printf("Error updating task: %v", err)
+		return answerError
+	}
 
-		var task *storage.Task
-		for i := range tasks {
-			if tasks[i].ID == taskID {
-				task = &tasks[i]
-				break
-			}
-		}
+	b.updateReminderKeyboard(ctx, query.Message, page)
+	return answer
+}
 
-		if task == nil {
-			log.Printf("Task not found: %s", taskIDHex)
-			return
-		}
+// handleReminderPageCallback turns the page of a daily reminder. Args: page.
+func (b *Bot) handleReminderPageCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) callbackAnswer {
+	if len(args) != 1 {
+		return answerStale
+	}
+	page, err := strconv.Atoi(args[0])
+	if err != nil {
+		return answerStale
+	}
 
-		// Toggle task status
-		if task.Status == storage.TaskStatusCompletedToday {
-			// Reactivate the task
-			err = b.storage.ReactivateTask(ctx, taskID)
-			if err != nil {
-				log.Printf("Error reactivating task: %v", err)
-				return
-			}
-		} else {
-			// Complete the task
-			err = b.storage.CompleteTask(ctx, taskID)
-			if err != nil {
-				log.Printf("Error completing task: %v", err)
-				return
-			}
-		}
+	b.updateReminderKeyboard(ctx, query.Message, page)
+	return answerNone
+}
 
-		// Get updated tasks and rebuild the keyboard
-		updatedTasks, err := b.storage.GetTasksByChatID(ctx, query.Message.Chat.ID)
-		if err != nil {
-			log.Printf("Error getting updated tasks: %v", err)
-			return
-		}
+// taskForCallback loads a task of the callback's chat.
+// If there is none, it returns the answer to show instead.
+func (b *Bot) taskForCallback(ctx context.Context, query *tgbotapi.CallbackQuery, idHex string) (*storage.Task, callbackAnswer) {
+	taskID, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		log.Printf("Invalid task ID in callback: %v", err)
+		return nil, answerStale
+	}
 
-		// Rebuild inline keyboard with updated status
-		var rows [][]tgbotapi.InlineKeyboardButton
-		for _, t := range updatedTasks {
-			statusEmoji := "⬜"
-			if t.Status == storage.TaskStatusCompletedToday {
-				statusEmoji = "✅"
-			}
-			buttonText := fmt.Sprintf("%s %s", statusEmoji, t.Description)
-			buttonData := fmt.Sprintf("complete_%s", t.ID.Hex())
-			button := tgbotapi.NewInlineKeyboardButtonData(buttonText, buttonData)
-			row := tgbotapi.NewInlineKeyboardRow(button)
-			rows = append(rows, row)
-		}
+	task, err := b.getTask(ctx, query.Message.Chat.ID, taskID)
+	if err != nil {
+		log.Printf("Error getting task: %v", err)
+		return nil, answerError
+	}
+	if task == nil {
+		return nil, callbackAnswer{text: b.printer(ctx, query.Message.Chat.ID, query.From).T("task.gone")}
+	}
+	return task, answerNone
+}
 
-		keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
-		edit := tgbotapi.NewEditMessageReplyMarkup(
-			query.Message.Chat.ID,
-			query.Message.MessageID,
-			keyboard,
-		)
-		if _, err := b.api.Send(edit); err != nil {
-			log.Printf("Error updating message: %v", err)
-		}
+// updateReminderKeyboard redraws a daily reminder's keyboard at the given page right away.
+// The chat's other reminders follow once they are re-rendered.
+func (b *Bot) updateReminderKeyboard(ctx context.Context, message *tgbotapi.Message, page int) {
+	tasks, members, err := b.reminderTasks(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting updated tasks: %v", err)
+		return
 	}
+	b.editReminder(ctx, message.Chat.ID, message.MessageID, page, "", tasks, members)
 }
@@ -0,0 +1,217 @@
+package bot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Callback data is "<version>|<action>|<arg>|...", followed by "|<signature>"
+// when a secret is configured. Telegram limits it to 64 bytes.
+const (
+	callbackVersion   = "1"
+	callbackSeparator = "|"
+	maxCallbackData   = 64
+	signatureBytes    = 6 // 8 characters in base64
+)
+
+// Callback actions
+const (
+	callbackSettings     = "s"
+	callbackOnboarding   = "o"
+	callbackList         = "l"
+	callbackComplete     = "r"
+	callbackReminderPage = "rp"
+	callbackUndoCapture  = "u"
+	callbackRotation     = "ro"
+	callbackPermissions  = "pm"
+	callbackDigest       = "dg"
+	callbackLanguage     = "lg"
+	callbackPickTask     = "pk"
+	callbackNoop         = "n"
+)
+
+// Prefixes for menus that append their own sub-action as the single argument
+const (
+	settingsCallbackPrefix   = callbackVersion + callbackSeparator + callbackSettings + callbackSeparator
+	onboardingCallbackPrefix = callbackVersion + callbackSeparator + callbackOnboarding + callbackSeparator
+)
+
+// legacyCompletePrefix is used by reminders sent before callback data was versioned
+const legacyCompletePrefix = "complete_"
+
+// callbackAnswer is the toast, or alert if alert is set, shown after a button press
+type callbackAnswer struct {
+	text  string
+	key   string // Message to translate for the user instead of text
+	alert bool
+}
+
+var (
+	answerNone  = callbackAnswer{}
+	answerStale = callbackAnswer{key: "callback.stale", alert: true}
+	answerError = callbackAnswer{key: "error.generic", alert: true}
+)
+
+// callbackHandler handles one callback action. args are the payload's arguments.
+type callbackHandler func(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) callbackAnswer
+
+// callbackCodec signs and verifies callback data
+type callbackCodec struct {
+	secret []byte
+}
+
+// callbackData builds an unsigned payload; keyboards are signed before they are sent
+func callbackData(action string, args ...string) string {
+	return strings.Join(append([]string{callbackVersion, action}, args...), callbackSeparator)
+}
+
+// signature binds a payload to a chat so a button can't be replayed elsewhere
+func (c callbackCodec) signature(chatID int64, data string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(strconv.FormatInt(chatID, 10) + callbackSeparator + data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:signatureBytes])
+}
+
+var errCallbackTooLong = errors.New("callback data too long")
+
+// sign appends the payload's signature. Telegram rejects a whole keyboard if one of its
+// payloads is over the limit, so such payloads are an error.
+func (c callbackCodec) sign(chatID int64, data string) (string, error) {
+	if len(c.secret) > 0 {
+		data += callbackSeparator + c.signature(chatID, data)
+	}
+	if len(data) > maxCallbackData {
+		return "", fmt.Errorf("%w: %q is %d bytes, Telegram allows %d", errCallbackTooLong, data, len(data), maxCallbackData)
+	}
+	return data, nil
+}
+
+// verify checks the signature and returns the payload without it
+func (c callbackCodec) verify(chatID int64, data string) (string, bool) {
+	if len(c.secret) == 0 {
+		return data, true
+	}
+
+	i := strings.LastIndex(data, callbackSeparator)
+	if i < 0 {
+		return "", false
+	}
+	payload, sig := data[:i], data[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(c.signature(chatID, payload))) {
+		return "", false
+	}
+	return payload, true
+}
+
+// signKeyboard returns a copy of keyboard with every callback payload signed for the chat.
+// Buttons whose payloads don't fit are left out, so that the rest of the keyboard still works.
+func (c callbackCodec) signKeyboard(chatID int64, keyboard tgbotapi.InlineKeyboardMarkup) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(keyboard.InlineKeyboard))
+	for _, row := range keyboard.InlineKeyboard {
+		signed := make([]tgbotapi.InlineKeyboardButton, 0, len(row))
+		for _, button := range row {
+			if button.CallbackData != nil {
+				data, err := c.sign(chatID, *button.CallbackData)
+				if err != nil {
+					log.Printf("Error signing button %q: %v", button.Text, err)
+					continue
+				}
+				button.CallbackData = &data
+			}
+			signed = append(signed, button)
+		}
+		if len(signed) > 0 {
+			rows = append(rows, signed)
+		}
+	}
+	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// signedKeyboard is signKeyboard for optional keyboards
+func (b *Bot) signedKeyboard(chatID int64, keyboard *tgbotapi.InlineKeyboardMarkup) *tgbotapi.InlineKeyboardMarkup {
+	if keyboard == nil {
+		return nil
+	}
+	signed := b.callbacks.signKeyboard(chatID, *keyboard)
+	return &signed
+}
+
+// callbackRoutes maps callback actions to their handlers
+func (b *Bot) callbackRoutes() map[string]callbackHandler {
+	return map[string]callbackHandler{
+		callbackSettings:     b.handleSettingsCallback,
+		callbackOnboarding:   b.handleOnboardingCallback,
+		callbackList:         b.handleListCallback,
+		callbackComplete:     b.handleCompleteCallback,
+		callbackReminderPage: b.handleReminderPageCallback,
+		callbackUndoCapture:  b.handleCaptureCallback,
+		callbackRotation:     b.handleRotationCallback,
+		callbackPermissions:  b.handlePermissionsCallback,
+		callbackDigest:       b.handleDigestCallback,
+		callbackLanguage:     b.handleLanguageCallback,
+		callbackPickTask:     b.handlePickCallback,
+		callbackNoop: func(context.Context, *tgbotapi.CallbackQuery, []string) callbackAnswer {
+			return answerNone
+		},
+	}
+}
+
+func (b *Bot) handleCallbackQuery(ctx context.Context, query *tgbotapi.CallbackQuery) {
+	answer := b.routeCallback(ctx, query)
+	if answer.key != "" {
+		chatID := query.From.ID
+		if query.Message != nil {
+			chatID = query.Message.Chat.ID
+		}
+		answer.text = b.printer(ctx, chatID, query.From).T(answer.key)
+	}
+
+	callback := tgbotapi.NewCallback(query.ID, answer.text)
+	callback.ShowAlert = answer.alert
+	if _, err := b.api.Request(callback); err != nil {
+		log.Printf("Error answering callback: %v", err)
+	}
+}
+
+// routeCallback decodes the payload and runs its handler
+func (b *Bot) routeCallback(ctx context.Context, query *tgbotapi.CallbackQuery) callbackAnswer {
+	// Buttons on inline-mode messages carry no chat
+	if query.Message == nil {
+		return answerStale
+	}
+	chatID := query.Message.Chat.ID
+
+	// Reminders sent before versioning are unsigned; the task is still checked against the chat
+	if strings.HasPrefix(query.Data, legacyCompletePrefix) {
+		taskID := strings.TrimPrefix(query.Data, legacyCompletePrefix)
+		return b.handleCompleteCallback(ctx, query, []string{taskID, "0"})
+	}
+
+	payload, ok := b.callbacks.verify(chatID, query.Data)
+	if !ok {
+		log.Printf("Rejected callback with invalid signature in chat %d: %s", chatID, query.Data)
+		return answerStale
+	}
+
+	parts := strings.Split(payload, callbackSeparator)
+	if len(parts) < 2 || parts[0] != callbackVersion {
+		log.Printf("Unsupported callback data: %s", query.Data)
+		return answerStale
+	}
+
+	handler, ok := b.routes[parts[1]]
+	if !ok {
+		log.Printf("Unknown callback action: %s", query.Data)
+		return answerStale
+	}
+	return handler(ctx, query, parts[2:])
+}
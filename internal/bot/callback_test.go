@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dm-popov-sdg/nagger/internal/timezone"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestCallbackCodecSignVerify(t *testing.T) {
+	codec := callbackCodec{secret: []byte("secret")}
+	payload := callbackData(callbackList, "d", "65a1b2c3d4e5f60718293a4b", "0")
+
+	signed, err := codec.sign(42, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(signed, payload+callbackSeparator) {
+		t.Fatalf("sign() = %q, want payload followed by a signature", signed)
+	}
+
+	got, ok := codec.verify(42, signed)
+	if !ok || got != payload {
+		t.Errorf("verify() = %q, %v; want %q, true", got, ok, payload)
+	}
+
+	otherSigned, err := callbackCodec{secret: []byte("other")}.sign(42, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		chatID int64
+		data   string
+	}{
+		{name: "Other chat", chatID: 43, data: signed},
+		{name: "Tampered payload", chatID: 42, data: strings.Replace(signed, "|d|", "|c|", 1)},
+		{name: "Unsigned", chatID: 42, data: payload},
+		{name: "Other secret", chatID: 42, data: otherSigned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := codec.verify(tt.chatID, tt.data); ok {
+				t.Errorf("verify(%d, %q) accepted an invalid payload", tt.chatID, tt.data)
+			}
+		})
+	}
+}
+
+func TestCallbackCodecWithoutSecret(t *testing.T) {
+	codec := callbackCodec{}
+	payload := callbackData(callbackReminderPage, "2")
+
+	if signed, err := codec.sign(42, payload); err != nil || signed != payload {
+		t.Errorf("sign() without secret = %q, %v; want %q", signed, err, payload)
+	}
+	if got, ok := codec.verify(42, payload); !ok || got != payload {
+		t.Errorf("verify() without secret = %q, %v; want %q, true", got, ok, payload)
+	}
+}
+
+func TestCallbackDataFitsLimit(t *testing.T) {
+	codec := callbackCodec{secret: []byte("secret")}
+	const taskID = "65a1b2c3d4e5f60718293a4b"
+	// Signatures don't depend on the chat's length, but digest payloads carry a chat ID
+	const chatID = math.MinInt64
+
+	// The longest payloads each menu produces
+	payloads := []string{
+		callbackData(callbackList, "z7", taskID, "999"),
+		callbackData(callbackComplete, taskID, "999"),
+		callbackData(callbackUndoCapture, taskID),
+		callbackData(callbackDigest, taskID, strconv.FormatInt(chatID, 10), digestViewDaily),
+		callbackData(callbackPickTask, taskActionDelete, taskID),
+		callbackData(callbackRotation, "w", taskID),
+		callbackData(callbackPermissions, string(permSettings)),
+		callbackData(callbackLanguage, languageAuto),
+		settingsCallbackPrefix + "tzz_UTC-12:00",
+		onboardingCallbackPrefix + "tzz_UTC+14:00",
+	}
+	for _, region := range timezone.Regions() {
+		payloads = append(payloads,
+			fmt.Sprintf("%stzr_%s_%d", settingsCallbackPrefix, region, 99),
+			fmt.Sprintf("%stzr_%s_%d", onboardingCallbackPrefix, region, 99))
+		for _, zone := range timezone.ZonesInRegion(region) {
+			payloads = append(payloads, settingsCallbackPrefix+"tzz_"+zone, onboardingCallbackPrefix+"tzz_"+zone)
+		}
+	}
+
+	for _, payload := range payloads {
+		if _, err := codec.sign(chatID, payload); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if _, err := codec.sign(chatID, callbackData(callbackList, strings.Repeat("x", maxCallbackData))); !errors.Is(err, errCallbackTooLong) {
+		t.Errorf("sign() of an oversized payload error = %v; want %v", err, errCallbackTooLong)
+	}
+}
+
+func TestSignKeyboard(t *testing.T) {
+	codec := callbackCodec{secret: []byte("secret")}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Done", callbackData(callbackList, "p", "1")),
+		tgbotapi.NewInlineKeyboardButtonURL("Docs", "https://example.com"),
+	))
+
+	signed := codec.signKeyboard(42, keyboard)
+
+	if *keyboard.InlineKeyboard[0][0].CallbackData != callbackData(callbackList, "p", "1") {
+		t.Error("signKeyboard() modified the original keyboard")
+	}
+	if _, ok := codec.verify(42, *signed.InlineKeyboard[0][0].CallbackData); !ok {
+		t.Error("signKeyboard() produced a payload that does not verify")
+	}
+	if signed.InlineKeyboard[0][1].CallbackData != nil {
+		t.Error("signKeyboard() added callback data to a URL button")
+	}
+
+	// A button that doesn't fit is left out rather than breaking the keyboard
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Too long", callbackData(callbackList, strings.Repeat("x", maxCallbackData))),
+	))
+	signed = codec.signKeyboard(42, keyboard)
+	if len(signed.InlineKeyboard) != 1 || len(signed.InlineKeyboard[0]) != 2 {
+		t.Errorf("signKeyboard() kept the oversized button: %+v", signed.InlineKeyboard)
+	}
+}
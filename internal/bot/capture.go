@@ -0,0 +1,279 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func init() {
+	registerCommands(helpCapture, (*Bot).captureCommands)
+}
+
+// captureCommands are the commands for adding tasks from plain messages
+func (b *Bot) captureCommands() []*command {
+	return []*command{
+		{name: "quickcapture", usage: "[on|off]", handler: b.handleQuickCaptureCommand},
+	}
+}
+
+// handleQuickCaptureCommand turns quick capture on or off: /quickcapture [on|off]
+func (b *Bot) handleQuickCaptureCommand(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	if !message.Chat.IsPrivate() {
+		b.sendMessage(message.Chat.ID, p.T("capture.private_only"))
+		return
+	}
+
+	settings, err := b.getSettings(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("capture.failed"))
+		return
+	}
+
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "":
+		settings.QuickCapture = !settings.QuickCapture
+	case "on":
+		settings.QuickCapture = true
+	case "off":
+		settings.QuickCapture = false
+	default:
+		b.sendMessage(message.Chat.ID, p.T("capture.usage"))
+		return
+	}
+
+	settings.UserID = message.From.ID
+	if err := b.storage.SetUserSettings(ctx, settings); err != nil {
+		log.Printf("Error setting user settings: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("capture.failed"))
+		return
+	}
+
+	if settings.QuickCapture {
+		b.sendMessage(message.Chat.ID, p.T("capture.on"))
+	} else {
+		b.sendMessage(message.Chat.ID, p.T("capture.off"))
+	}
+}
+
+// handleQuickCapture turns a plain or forwarded message into tasks if the chat opted in.
+// It returns true if the message was consumed.
+func (b *Bot) handleQuickCapture(ctx context.Context, message *tgbotapi.Message) bool {
+	if !message.Chat.IsPrivate() || message.From == nil {
+		return false
+	}
+
+	settings, err := b.storage.GetUserSettings(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		return false
+	}
+	if settings == nil || !settings.QuickCapture {
+		return false
+	}
+
+	var descriptions []string
+	source := forwardSource(message)
+	if source != nil {
+		// A forwarded message is a single task, whatever its layout
+		text := message.Text
+		if text == "" {
+			text = message.Caption
+		}
+		if text = strings.Join(strings.Fields(text), " "); text != "" {
+			descriptions = append(descriptions, text)
+		}
+	} else {
+		descriptions = captureLines(message.Text)
+	}
+	if len(descriptions) == 0 {
+		return false
+	}
+
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	captureID := primitive.NewObjectID().Hex()
+	for _, description := range descriptions {
+		task := &storage.Task{
+			ChatID:      message.Chat.ID,
+			UserID:      message.From.ID,
+			Description: description,
+			CaptureID:   captureID,
+			Source:      source,
+		}
+		if err := b.storage.AddTask(ctx, task); err != nil {
+			log.Printf("Error adding task: %v", err)
+			// Don't leave half of the message behind
+			if _, err := b.storage.DeleteCapturedTasks(ctx, message.Chat.ID, captureID); err != nil {
+				log.Printf("Error removing captured tasks: %v", err)
+			}
+			b.sendMessage(message.Chat.ID, p.T("add.failed"))
+			return true
+		}
+	}
+	b.remindersChanged(ctx, message.Chat.ID)
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, captureText(p, descriptions, source))
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.ReplyToMessageID = message.MessageID
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = b.callbacks.signKeyboard(message.Chat.ID, tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(p.T("list.undo_button"), callbackData(callbackUndoCapture, captureID)),
+	)))
+	if _, err := b.sender.send(ctx, message.Chat.ID, priorityInteractive, msg); err != nil {
+		log.Printf("Error sending capture confirmation: %v", err)
+		b.handleSendError(ctx, message.Chat.ID, err)
+	}
+	return true
+}
+
+// handleCaptureCallback handles the Undo button of a quick-capture confirmation. Args: capture ID.
+func (b *Bot) handleCaptureCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) callbackAnswer {
+	if len(args) != 1 || args[0] == "" {
+		return answerStale
+	}
+	chatID := query.Message.Chat.ID
+
+	deleted, err := b.storage.DeleteCapturedTasks(ctx, chatID, args[0])
+	if err != nil {
+		log.Printf("Error removing captured tasks: %v", err)
+		return answerError
+	}
+	if deleted > 0 {
+		b.remindersChanged(ctx, chatID)
+	}
+
+	p := b.printer(ctx, chatID, query.From)
+	text := p.T("capture.nothing_to_undo")
+	if deleted > 0 {
+		text = p.N("capture.removed", int(deleted))
+	}
+
+	if err := b.editHTML(ctx, chatID, query.Message.MessageID, escapeHTML(text), nil); err != nil {
+		log.Printf("Error updating capture message: %v", err)
+	}
+	return callbackAnswer{text: text}
+}
+
+// captureLines splits a message into task descriptions, one per non-empty line
+func captureLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		// Drop list markers people paste along with their lists
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimLeft(line, "-*•"))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// forwardSource describes where a forwarded message came from, or returns nil for other messages
+func forwardSource(message *tgbotapi.Message) *storage.TaskSource {
+	switch {
+	case message.ForwardFromChat != nil:
+		chat := message.ForwardFromChat
+		source := &storage.TaskSource{SenderName: chat.Title, Username: chat.UserName}
+		if message.ForwardSignature != "" {
+			source.SenderName = fmt.Sprintf("%s (%s)", chat.Title, message.ForwardSignature)
+		}
+		if message.ForwardFromMessageID != 0 {
+			if chat.UserName != "" {
+				source.Link = fmt.Sprintf("https://t.me/%s/%d", chat.UserName, message.ForwardFromMessageID)
+			} else if id := fmt.Sprint(chat.ID); strings.HasPrefix(id, "-100") {
+				// Private channels are linked by their internal ID, readable by members only
+				source.Link = fmt.Sprintf("https://t.me/c/%s/%d", strings.TrimPrefix(id, "-100"), message.ForwardFromMessageID)
+			}
+		}
+		return source
+	case message.ForwardFrom != nil:
+		user := message.ForwardFrom
+		name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+		return &storage.TaskSource{SenderName: name, Username: user.UserName}
+	case message.ForwardSenderName != "":
+		// The sender hides their account
+		return &storage.TaskSource{SenderName: message.ForwardSenderName}
+	case message.ForwardDate != 0:
+		return &storage.TaskSource{}
+	}
+	return nil
+}
+
+// sourceLabel formats a task source for display, e.g. "from Jane (@jane)"
+func sourceLabel(p *i18n.Printer, source *storage.TaskSource) string {
+	if source == nil {
+		return ""
+	}
+
+	label := senderLabel(p, source)
+	if source.Link != "" {
+		label += " " + source.Link
+	}
+	return p.T("capture.from", label)
+}
+
+// senderLabel names the sender of a forwarded message, e.g. "Jane (@jane)"
+func senderLabel(p *i18n.Printer, source *storage.TaskSource) string {
+	label := source.SenderName
+	if source.Username != "" {
+		if label == "" {
+			label = "@" + source.Username
+		} else {
+			label += " (@" + source.Username + ")"
+		}
+	}
+	if label == "" {
+		label = p.T("capture.forwarded_message")
+	}
+	return label
+}
+
+// captureRoom is the room captureText keeps for the line about the tasks left out
+const captureRoom = 32
+
+// sourceHTML is sourceLabel in HTML, with the sender linked to the original message
+func sourceHTML(p *i18n.Printer, source *storage.TaskSource) safeHTML {
+	if source == nil || source.Link == "" {
+		return safeHTML(escapeHTML(sourceLabel(p, source)))
+	}
+	return safeHTML(formatHTML(p.T("capture.from"), link(source.Link, senderLabel(p, source))))
+}
+
+// captureText confirms the tasks added from a message in HTML. Long lists are cut short
+// to fit in one message.
+func captureText(p *i18n.Printer, descriptions []string, source *storage.TaskSource) string {
+	var footer string
+	if source != nil {
+		footer = formatHTML("\n📨 %s", sourceHTML(p, source))
+	}
+
+	if len(descriptions) == 1 {
+		limit := messageTextLimit - htmlLength(escapeHTML(p.T("capture.added_one", ""))+footer)
+		return escapeHTML(p.T("capture.added_one", truncate(descriptions[0], limit))) + footer
+	}
+	if footer != "" {
+		footer = "\n" + footer
+	}
+
+	var text strings.Builder
+	text.WriteString(escapeHTML(p.N("capture.added", len(descriptions))))
+	width := htmlLength(text.String()) + htmlLength(footer)
+	for i, description := range descriptions {
+		line := formatHTML("\n• %s", truncate(description, listDescriptionLimit))
+		if width+htmlLength(line) > messageTextLimit-captureRoom {
+			text.WriteString("\n" + escapeHTML(p.T("capture.more", len(descriptions)-i)))
+			break
+		}
+		text.WriteString(line)
+		width += htmlLength(line)
+	}
+	return text.String() + footer
+}
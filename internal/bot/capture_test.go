@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestCaptureLines(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "Single line", text: "Buy milk", want: []string{"Buy milk"}},
+		{name: "Several lines", text: "Buy milk\n\n  Call mom \nPay rent", want: []string{"Buy milk", "Call mom", "Pay rent"}},
+		{name: "List markers", text: "- Buy milk\n* Call mom\n• Pay rent", want: []string{"Buy milk", "Call mom", "Pay rent"}},
+		{name: "Only blanks", text: " \n - \n", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := captureLines(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("captureLines(%q) = %q; want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *tgbotapi.Message
+		want    *storage.TaskSource
+	}{
+		{
+			name:    "Not forwarded",
+			message: &tgbotapi.Message{Text: "Buy milk"},
+			want:    nil,
+		},
+		{
+			name:    "From user",
+			message: &tgbotapi.Message{ForwardDate: 1, ForwardFrom: &tgbotapi.User{FirstName: "Jane", LastName: "Doe", UserName: "jane"}},
+			want:    &storage.TaskSource{SenderName: "Jane Doe", Username: "jane"},
+		},
+		{
+			name:    "From hidden user",
+			message: &tgbotapi.Message{ForwardDate: 1, ForwardSenderName: "Jane"},
+			want:    &storage.TaskSource{SenderName: "Jane"},
+		},
+		{
+			name: "From public channel",
+			message: &tgbotapi.Message{ForwardDate: 1, ForwardFromMessageID: 42,
+				ForwardFromChat: &tgbotapi.Chat{ID: -1001234, Title: "News", UserName: "news"}},
+			want: &storage.TaskSource{SenderName: "News", Username: "news", Link: "https://t.me/news/42"},
+		},
+		{
+			name: "From private channel",
+			message: &tgbotapi.Message{ForwardDate: 1, ForwardFromMessageID: 7,
+				ForwardFromChat: &tgbotapi.Chat{ID: -1001234, Title: "Team"}},
+			want: &storage.TaskSource{SenderName: "Team", Link: "https://t.me/c/1234/7"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := forwardSource(tt.message); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("forwardSource() = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaptureText(t *testing.T) {
+	p := i18n.For("en")
+	source := &storage.TaskSource{SenderName: "News <daily>", Link: "https://t.me/news/42"}
+	if got, want := captureText(p, []string{"Buy milk & eggs"}, source), `✅ Task added: Buy milk &amp; eggs`+"\n"+`📨 from <a href="https://t.me/news/42">News &lt;daily&gt;</a>`; got != want {
+		t.Errorf("captureText() = %q; want %q", got, want)
+	}
+	if got, want := captureText(p, []string{"Buy milk", "Call mom"}, nil), "✅ Added 2 tasks:\n• Buy milk\n• Call mom"; got != want {
+		t.Errorf("captureText() = %q; want %q", got, want)
+	}
+
+	long := strings.Repeat("<&>", 2000)
+	many := make([]string, 2000)
+	for i := range many {
+		many[i] = "x"
+	}
+	for _, descriptions := range [][]string{{long}, {long, long, long}, many} {
+		text := captureText(p, descriptions, source)
+		if n, err := checkHTML(text); err != nil || n > messageTextLimit {
+			t.Errorf("captureText() of %d tasks is %d characters (error %v); want at most %d", len(descriptions), n, err, messageTextLimit)
+		}
+		if !strings.HasSuffix(text, "</a>") {
+			t.Errorf("captureText() of %d tasks lost the source", len(descriptions))
+		}
+	}
+	if text := captureText(p, many, nil); !strings.Contains(text, "\n…and ") {
+		t.Errorf("captureText() of %d tasks doesn't say how many were left out", len(many))
+	}
+}
@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// command is a registered slash command
+type command struct {
+	name      string
+	usage     string // Arguments shown in /help, e.g. "<task>"
+	adminOnly bool
+	handler   func(ctx context.Context, message *tgbotapi.Message)
+}
+
+// Where each feature's commands appear in /help
+const (
+	helpTasks = iota
+	helpGroups
+	helpDigest
+	helpRotations
+	helpSettings
+	helpLanguage
+	helpTemplates
+	helpShares
+	helpPermissions
+	helpCapture
+	helpConversations
+	helpBasic
+	helpOutbox
+)
+
+// commandFeature is how a feature adds its commands to the bot
+type commandFeature struct {
+	order    int // Position of the commands in /help
+	commands func(b *Bot) []*command
+}
+
+// commandFeatures are the features registered by the init functions of their files
+var commandFeatures []commandFeature
+
+// registerCommands adds a feature's commands, listed in /help at order. Each feature
+// calls it from an init function in its own file.
+func registerCommands(order int, commands func(b *Bot) []*command) {
+	commandFeatures = append(commandFeatures, commandFeature{order: order, commands: commands})
+}
+
+// commandList collects the commands of every registered feature in the order /help lists
+// them. Their descriptions are the "command.<name>" messages of the i18n catalogs.
+func (b *Bot) commandList() []*command {
+	features := slices.Clone(commandFeatures)
+	slices.SortStableFunc(features, func(x, y commandFeature) int {
+		return x.order - y.order
+	})
+
+	var commands []*command
+	for _, feature := range features {
+		commands = append(commands, feature.commands(b)...)
+	}
+	return commands
+}
+
+func init() {
+	registerCommands(helpBasic, (*Bot).basicCommands)
+}
+
+// basicCommands are the commands every bot has
+func (b *Bot) basicCommands() []*command {
+	return []*command{
+		{name: "start", handler: b.handleStart},
+		{name: "help", handler: b.handleHelp},
+		{name: "stats", adminOnly: true, handler: b.handleStats},
+	}
+}
+
+// handleRequest is the end of the middleware chain: it runs the update's handler
+func (b *Bot) handleRequest(ctx context.Context, req *request) {
+	switch {
+	case req.update.CallbackQuery != nil:
+		b.handleCallbackQuery(ctx, req.update.CallbackQuery)
+	case req.update.Message != nil:
+		message := req.update.Message
+		// Messages sent on behalf of a channel have no user to reply to
+		if message.From == nil {
+			return
+		}
+		if !message.IsCommand() {
+			b.handleMessage(ctx, message)
+			return
+		}
+
+		// Any other command abandons a pending question
+		if req.command == nil || req.command.name != "cancel" {
+			b.endConversation(ctx, message.Chat.ID, message.From.ID)
+		}
+
+		if req.command == nil {
+			b.sendMessage(message.Chat.ID, b.printer(ctx, message.Chat.ID, message.From).T("command.unknown"))
+			return
+		}
+		req.command.handler(ctx, message)
+	}
+}
+
+func (b *Bot) handleHelp(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+
+	var text strings.Builder
+	text.WriteString(p.T("help.title") + "\n\n")
+	b.writeCommands(&text, p, false)
+	text.WriteString("\n" + p.T("help.body"))
+
+	if b.isAdmin(message.From) {
+		text.WriteString("\n\n" + p.T("help.admin") + "\n\n")
+		b.writeCommands(&text, p, true)
+	}
+
+	b.sendMessage(message.Chat.ID, text.String())
+}
+
+func (b *Bot) writeCommands(text *strings.Builder, p *i18n.Printer, adminOnly bool) {
+	for _, cmd := range b.commandList() {
+		if cmd.adminOnly != adminOnly {
+			continue
+		}
+		text.WriteString("/" + cmd.name)
+		if cmd.usage != "" {
+			text.WriteString(" " + cmd.usage)
+		}
+		text.WriteString(" - " + p.T("command."+cmd.name) + "\n")
+	}
+}
+
+func (b *Bot) handleStats(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	report := b.metrics.report(p)
+	if report == "" {
+		report = p.T("stats.none")
+	}
+	b.sendMessage(message.Chat.ID, p.T("stats.header")+"\n\n"+report)
+}
+
+// dispatch passes an update through the middleware chain
+func (b *Bot) dispatch(ctx context.Context, update tgbotapi.Update) {
+	if update.Message == nil && update.CallbackQuery == nil {
+		return
+	}
+	b.handler(ctx, b.newRequest(update))
+}
+
+// buildHandler assembles the middleware chain. Metrics and logging wrap panic recovery,
+// so they see requests that panicked.
+func (b *Bot) buildHandler() handlerFunc {
+	commands := make(map[string]*command)
+	for _, cmd := range b.commandList() {
+		if _, dup := commands[cmd.name]; dup {
+			log.Panicf("command /%s registered twice", cmd.name)
+		}
+		commands[cmd.name] = cmd
+	}
+	b.commands = commands
+
+	return chain(b.handleRequest,
+		b.logRequests,
+		b.metrics.middleware,
+		b.recoverPanics,
+		b.limitRate,
+		b.resolvePrinter,
+		b.trackMembers,
+		b.authorize,
+	)
+}
@@ -0,0 +1,20 @@
+package bot
+
+import "testing"
+
+func TestCommandFeaturesRegistered(t *testing.T) {
+	registered := make(map[int]int)
+	for _, feature := range commandFeatures {
+		registered[feature.order]++
+	}
+	for order := helpTasks; order <= helpOutbox; order++ {
+		if registered[order] != 1 {
+			t.Errorf("help position %d has %d features registered; want 1", order, registered[order])
+		}
+	}
+
+	commands := (&Bot{}).commandList()
+	if first, last := commands[0].name, commands[len(commands)-1].name; first != "add" || last != "replay" {
+		t.Errorf("commands run from /%s to /%s; want /add to /replay", first, last)
+	}
+}
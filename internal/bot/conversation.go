@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// conversationTimeout is how long the bot waits for an answer before forgetting the question
+const conversationTimeout = 10 * time.Minute
+
+// Conversation flows
+const (
+	flowAdd         = "add"
+	flowEdit        = "edit"
+	flowSetReminder = "setreminder"
+	flowOnboarding  = "onboarding"
+)
+
+// startConversation remembers that the next message from the member answers step of flow
+func (b *Bot) startConversation(ctx context.Context, chatID, userID int64, flow, step string, data map[string]string) error {
+	conv := &storage.Conversation{
+		ChatID:    chatID,
+		UserID:    userID,
+		Flow:      flow,
+		Step:      step,
+		Data:      data,
+		ExpiresAt: time.Now().Add(conversationTimeout),
+	}
+	return b.storage.SetConversation(ctx, conv)
+}
+
+// prompt asks the member a question and continues flow with their reply.
+// ForceReply opens the reply field; in groups it is shown only to that member.
+func (b *Bot) prompt(ctx context.Context, message *tgbotapi.Message, flow, step string, data map[string]string, text, placeholder string) {
+	b.promptUser(ctx, message.Chat.ID, message.From, message.MessageID, flow, step, data, text, placeholder)
+}
+
+// promptUser is prompt for questions that don't answer a member's message, e.g. from a button
+func (b *Bot) promptUser(ctx context.Context, chatID int64, from *tgbotapi.User, replyTo int, flow, step string, data map[string]string, text, placeholder string) {
+	userID := from.ID
+	p := b.printer(ctx, chatID, from)
+	if err := b.startConversation(ctx, chatID, userID, flow, step, data); err != nil {
+		log.Printf("Error starting conversation: %v", err)
+		b.sendMessage(chatID, p.T("error.generic"))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, escapeHTML(text+"\n\n"+p.T("conversation.cancel_hint")))
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.ReplyToMessageID = replyTo
+	msg.ReplyMarkup = tgbotapi.ForceReply{
+		ForceReply:            true,
+		Selective:             true,
+		InputFieldPlaceholder: placeholder,
+	}
+	sent, err := b.sender.send(ctx, chatID, priorityInteractive, msg)
+	if err != nil {
+		log.Printf("Error sending prompt: %v", err)
+		return
+	}
+	if isGroupChat(chatID) {
+		// In groups only replies to the question answer it
+		if err := b.storage.SetConversationPrompt(ctx, chatID, userID, sent.MessageID); err != nil {
+			log.Printf("Error saving prompt: %v", err)
+		}
+	}
+}
+
+// endConversation forgets the member's pending question, if any
+func (b *Bot) endConversation(ctx context.Context, chatID, userID int64) bool {
+	ended, err := b.storage.DeleteConversation(ctx, chatID, userID)
+	if err != nil {
+		log.Printf("Error ending conversation: %v", err)
+	}
+	return ended
+}
+
+// handleConversationMessage passes a plain message to the member's pending conversation.
+// It returns true if the message was consumed.
+func (b *Bot) handleConversationMessage(ctx context.Context, message *tgbotapi.Message) bool {
+	if message.From == nil {
+		return false
+	}
+
+	conv, err := b.storage.GetConversation(ctx, message.Chat.ID, message.From.ID)
+	if err != nil {
+		log.Printf("Error getting conversation: %v", err)
+		return false
+	}
+	if conv == nil || !answersPrompt(message, conv, b.api.Self.ID) {
+		return false
+	}
+
+	switch conv.Flow {
+	case flowAdd:
+		b.continueAdd(ctx, message, conv)
+	case flowEdit:
+		b.continueEdit(ctx, message, conv)
+	case flowSetReminder:
+		b.continueSetReminder(ctx, message, conv)
+	case flowOnboarding:
+		b.continueOnboarding(ctx, message, conv)
+	default:
+		log.Printf("Unknown conversation flow: %s", conv.Flow)
+		b.endConversation(ctx, message.Chat.ID, message.From.ID)
+		return false
+	}
+	return true
+}
+
+// answersPrompt reports whether a message answers the conversation's question. In groups
+// members chat about other things too, so only replies to the question count; the reply
+// field opened by the question makes that the natural way to answer.
+func answersPrompt(message *tgbotapi.Message, conv *storage.Conversation, botID int64) bool {
+	if !isGroup(message.Chat) {
+		return true
+	}
+	reply := message.ReplyToMessage
+	if reply == nil {
+		return false
+	}
+	if conv.PromptID != 0 {
+		return reply.MessageID == conv.PromptID
+	}
+	// The question was asked, but its message wasn't remembered
+	return reply.From != nil && reply.From.ID == botID
+}
+
+func init() {
+	registerCommands(helpConversations, (*Bot).conversationCommands)
+}
+
+// conversationCommands are the commands for the bot's questions
+func (b *Bot) conversationCommands() []*command {
+	return []*command{
+		{name: "cancel", handler: b.handleCancel},
+	}
+}
+
+func (b *Bot) handleCancel(ctx context.Context, message *tgbotapi.Message) {
+	if !b.endConversation(ctx, message.Chat.ID, message.From.ID) {
+		b.sendMessage(message.Chat.ID, b.printer(ctx, message.Chat.ID, message.From).T("conversation.nothing_to_cancel"))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, b.printer(ctx, message.Chat.ID, message.From).T("conversation.cancelled"))
+	msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
+	if _, err := b.sender.send(ctx, message.Chat.ID, priorityInteractive, msg); err != nil {
+		log.Printf("Error sending message: %v", err)
+	}
+}
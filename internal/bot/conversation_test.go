@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestAnswersPrompt(t *testing.T) {
+	const botID = 99
+	group := &tgbotapi.Chat{ID: -100, Type: "supergroup"}
+	private := &tgbotapi.Chat{ID: 1, Type: "private"}
+	prompt := &tgbotapi.Message{MessageID: 10, From: &tgbotapi.User{ID: botID}}
+	other := &tgbotapi.Message{MessageID: 11, From: &tgbotapi.User{ID: 2}}
+
+	tests := []struct {
+		name    string
+		message *tgbotapi.Message
+		conv    *storage.Conversation
+		want    bool
+	}{
+		{"private chat", &tgbotapi.Message{Chat: private}, &storage.Conversation{PromptID: 10}, true},
+		{"group reply to the prompt", &tgbotapi.Message{Chat: group, ReplyToMessage: prompt}, &storage.Conversation{PromptID: 10}, true},
+		{"group chatter", &tgbotapi.Message{Chat: group}, &storage.Conversation{PromptID: 10}, false},
+		{"group reply to someone else", &tgbotapi.Message{Chat: group, ReplyToMessage: other}, &storage.Conversation{PromptID: 10}, false},
+		{"group reply to an older bot message", &tgbotapi.Message{Chat: group, ReplyToMessage: &tgbotapi.Message{MessageID: 5, From: &tgbotapi.User{ID: botID}}}, &storage.Conversation{PromptID: 10}, false},
+		{"prompt not remembered, reply to the bot", &tgbotapi.Message{Chat: group, ReplyToMessage: prompt}, &storage.Conversation{}, true},
+		{"prompt not remembered, reply to a member", &tgbotapi.Message{Chat: group, ReplyToMessage: other}, &storage.Conversation{}, false},
+	}
+
+	for _, tt := range tests {
+		if got := answersPrompt(tt.message, tt.conv, botID); got != tt.want {
+			t.Errorf("%s: answersPrompt() = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// digestButtonLimit caps the tasks and buttons of a digest; Telegram allows 100 buttons
+// per message
+const digestButtonLimit = 30
+
+// Digest views, passed in callbacks so a redraw keeps the same tasks
+const (
+	digestViewAll   = "a" // /mytasks: all tasks
+	digestViewDaily = "d" // The daily digest: snoozed tasks are left out
+)
+
+// digestChat is one chat's part of a user's digest
+type digestChat struct {
+	chatID  int64
+	title   string
+	tasks   []storage.Task
+	members []storage.ChatMember
+}
+
+func init() {
+	registerCommands(helpDigest, (*Bot).digestCommands)
+}
+
+// digestCommands are the commands for tasks from all chats
+func (b *Bot) digestCommands() []*command {
+	return []*command{
+		{name: "mytasks", handler: b.handleMyTasks},
+	}
+}
+
+// handleMyTasks lists the sender's tasks from all chats: the ones they created or are assigned to
+func (b *Bot) handleMyTasks(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	if isGroup(message.Chat) {
+		b.sendMessage(message.Chat.ID, p.T("digest.private_only"))
+		return
+	}
+
+	text, keyboard, err := b.digestView(ctx, p, message.From.ID, digestViewAll)
+	if err != nil {
+		log.Printf("Error getting tasks: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("tasks.get_failed"))
+		return
+	}
+
+	if _, err := b.sendHTMLMarkup(ctx, message.Chat.ID, text, b.signedKeyboard(message.Chat.ID, keyboard)); err != nil {
+		log.Printf("Error sending task digest: %v", err)
+		b.handleSendError(ctx, message.Chat.ID, err)
+	}
+}
+
+// SendDigest queues the daily digest of a user's tasks from all chats to their private chat
+func (b *Bot) SendDigest(ctx context.Context, userID int64) error {
+	text, keyboard, err := b.digestView(ctx, b.printer(ctx, userID, nil), userID, digestViewDaily)
+	if err != nil {
+		return err
+	}
+	if keyboard == nil {
+		// Nothing to do today
+		return nil
+	}
+
+	signed := b.callbacks.signKeyboard(userID, *keyboard)
+	return b.enqueueHTML(ctx, userID, outboxKindDigest, text, &signed)
+}
+
+// digestView renders a user's tasks grouped by chat in HTML. The keyboard is nil if there
+// are no tasks.
+func (b *Bot) digestView(ctx context.Context, p *i18n.Printer, userID int64, view string) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	tasks, err := b.storage.GetTasksForUser(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if view == digestViewDaily {
+		now := time.Now()
+		active := tasks[:0]
+		for _, task := range tasks {
+			if !task.IsSnoozed(now) {
+				active = append(active, task)
+			}
+		}
+		tasks = active
+	}
+
+	chats := b.digestChats(ctx, p, tasks)
+	if len(chats) == 0 {
+		return escapeHTML(p.T("digest.empty")), nil, nil
+	}
+
+	header := formatHTML("📋 %s", bold(p.T("digest.header", len(tasks))))
+	if view == digestViewDaily {
+		header = formatHTML("🔔 %s %s", bold(p.T("digest.daily_title")),
+			p.T("digest.daily_summary", p.N("digest.tasks", len(tasks)), p.N("digest.chats", len(chats))))
+	}
+	text, shown := digestText(p, header, chats, userID)
+	return text, digestKeyboard(chats, userID, view, shown), nil
+}
+
+// digestChats groups tasks sorted by chat, the user's private chat first
+func (b *Bot) digestChats(ctx context.Context, p *i18n.Printer, tasks []storage.Task) []digestChat {
+	var chats []digestChat
+	var chatIDs []int64
+	for _, task := range tasks {
+		if len(chats) == 0 || chats[len(chats)-1].chatID != task.ChatID {
+			chats = append(chats, digestChat{chatID: task.ChatID})
+			chatIDs = append(chatIDs, task.ChatID)
+		}
+		last := &chats[len(chats)-1]
+		last.tasks = append(last.tasks, task)
+	}
+	if len(chats) == 0 {
+		return nil
+	}
+
+	titles := b.chatTitles(ctx, p, chatIDs)
+	var private, groups []digestChat
+	for _, chat := range chats {
+		if chat.chatID > 0 {
+			chat.title = p.T("digest.private_chat")
+			private = append(private, chat)
+			continue
+		}
+		chat.title = titles[chat.chatID]
+		chat.members = b.chatMembers(ctx, chat.chatID)
+		groups = append(groups, chat)
+	}
+	return append(private, groups...)
+}
+
+// chatTitles returns the titles of group chats, asking Telegram for the ones the bot
+// hasn't stored since their last reminder
+func (b *Bot) chatTitles(ctx context.Context, p *i18n.Printer, chatIDs []int64) map[int64]string {
+	titles := make(map[int64]string, len(chatIDs))
+
+	chats, err := b.storage.GetChats(ctx, chatIDs)
+	if err != nil {
+		log.Printf("Error getting chats: %v", err)
+	}
+	for _, chatID := range chatIDs {
+		if chatID > 0 {
+			continue
+		}
+		if chat, ok := chats[chatID]; ok && chat.Title != "" {
+			titles[chatID] = chat.Title
+			continue
+		}
+		chat, err := b.api.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: chatID}})
+		if err != nil || chat.Title == "" {
+			titles[chatID] = p.T("digest.group_chat")
+			continue
+		}
+		titles[chatID] = chat.Title
+	}
+	return titles
+}
+
+// digestFooterRoom is the room digestText keeps for the line about the tasks left out
+const digestFooterRoom = 64
+
+// digestText lists the tasks of each chat in HTML, numbered across chats like the buttons.
+// header is HTML. It lists at most digestButtonLimit tasks and stops early if the
+// message would get too long, and returns how many tasks it listed.
+func digestText(p *i18n.Printer, header string, chats []digestChat, userID int64) (string, int) {
+	var text strings.Builder
+	text.WriteString(header)
+	width := htmlLength(header)
+
+	total := 0
+	for _, chat := range chats {
+		total += len(chat.tasks)
+	}
+
+	n := 0
+chats:
+	for _, chat := range chats {
+		icon := "👥"
+		if chat.chatID > 0 {
+			icon = "💬"
+		}
+		heading := formatHTML("\n\n%s %s", icon, bold(chat.title))
+		for i, task := range chat.tasks {
+			description := truncate(task.Description, listDescriptionLimit)
+			var line string
+			if doneFor(task, userID) {
+				line = formatHTML("\n%d. %s%s ✅", n+1, priorityMark(task.Priority), strike(description))
+			} else {
+				line = formatHTML("\n%d. %s%s", n+1, priorityMark(task.Priority), description)
+			}
+			if others := otherAssignees(task, userID); len(others) > 0 {
+				line += " → " + escapeHTML(memberNames(p, others, chat.members))
+			}
+			if i == 0 {
+				line = heading + line
+			}
+
+			lineWidth := htmlLength(line)
+			if n == digestButtonLimit || width+lineWidth > messageTextLimit-digestFooterRoom {
+				break chats
+			}
+			text.WriteString(line)
+			width += lineWidth
+			n++
+		}
+	}
+
+	if n < total {
+		text.WriteString("\n\n" + escapeHTML(p.N("digest.more", total-n)))
+	}
+	return text.String(), n
+}
+
+// digestKeyboard has a button per task to complete it in its own chat, for the first
+// limit tasks
+func digestKeyboard(chats []digestChat, userID int64, view string, limit int) *tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	n := 0
+	for _, chat := range chats {
+		for _, task := range chat.tasks {
+			n++
+			if n > limit {
+				break
+			}
+			statusEmoji := "⬜"
+			if doneFor(task, userID) {
+				statusEmoji = "✅"
+			}
+			buttonText := fmt.Sprintf("%s %d. %s", statusEmoji, n, truncate(task.Description, buttonTextLimit))
+			buttonData := callbackData(callbackDigest, task.ID.Hex(), strconv.FormatInt(chat.chatID, 10), view)
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(buttonText, buttonData)))
+		}
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// doneFor reports whether the task is done as far as the user is concerned
+func doneFor(task storage.Task, userID int64) bool {
+	return task.Status == storage.TaskStatusCompletedToday || (task.PerMember && task.IsCompletedBy(userID))
+}
+
+// otherAssignees returns the assignees of a task the user created for someone else
+func otherAssignees(task storage.Task, userID int64) []int64 {
+	if len(task.Assignees) == 0 || task.IsAssignedTo(userID) {
+		return nil
+	}
+	return task.Assignees
+}
+
+// handleDigestCallback toggles a task from a digest in its own chat and redraws the
+// digest. Args: task ID, chat ID, digest view.
+func (b *Bot) handleDigestCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) callbackAnswer {
+	if len(args) != 3 {
+		return answerStale
+	}
+	taskID, err := primitive.ObjectIDFromHex(args[0])
+	if err != nil {
+		return answerStale
+	}
+	chatID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return answerStale
+	}
+	userID := query.From.ID
+
+	task, err := b.storage.GetTaskForChat(ctx, chatID, taskID)
+	if err != nil {
+		log.Printf("Error getting task: %v", err)
+		return answerError
+	}
+
+	p := b.printer(ctx, userID, query.From)
+	answer := callbackAnswer{key: "task.gone"}
+	switch {
+	case task == nil:
+	case task.UserID != userID && !task.IsAssignedTo(userID):
+		answer = callbackAnswer{key: "digest.not_yours"}
+	case isGroupChat(chatID) && !b.isChatMember(ctx, chatID, userID):
+		answer = callbackAnswer{key: "digest.not_member"}
+	default:
+		if ok, denied := b.allowed(ctx, p, chatID, userID, permComplete, task); !ok {
+			return callbackAnswer{text: denied, alert: true}
+		}
+		answer, err = b.toggleTask(ctx, p, task, userID)
+		if err != nil {
+			log.Printf("Error updating task: %v", err)
+			return answerError
+		}
+	}
+
+	text, keyboard, err := b.digestView(ctx, p, userID, args[2])
+	if err != nil {
+		log.Printf("Error getting tasks: %v", err)
+		return answer
+	}
+	digestID := query.Message.Chat.ID
+	if err := b.editHTML(ctx, digestID, query.Message.MessageID, text, keyboard); err != nil {
+		log.Printf("Error updating task digest: %v", err)
+	}
+	return answer
+}
@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDigestText(t *testing.T) {
+	const me = 1
+	chats := []digestChat{
+		{
+			chatID: me,
+			title:  "Private chat",
+			tasks:  []storage.Task{{Description: "Buy milk", Status: storage.TaskStatusActive}},
+		},
+		{
+			chatID:  -100,
+			title:   "Team",
+			members: []storage.ChatMember{{UserID: 2, FirstName: "Bob"}},
+			tasks: []storage.Task{
+				{Description: "Fix CI", Assignees: []int64{me}, Status: storage.TaskStatusCompletedToday},
+				{Description: "Review", UserID: me, Assignees: []int64{2}, Status: storage.TaskStatusActive},
+				{Description: "Timesheet", PerMember: true, Assignees: []int64{me, 2}, CompletedBy: []int64{me}, Status: storage.TaskStatusActive},
+			},
+		},
+	}
+
+	got, shown := digestText(i18n.For("en"), "Header", chats, me)
+	if shown != 4 {
+		t.Errorf("digestText() listed %d tasks; want 4", shown)
+	}
+	want := "Header\n\n💬 <b>Private chat</b>\n1. Buy milk\n\n👥 <b>Team</b>\n2. <s>Fix CI</s> ✅\n3. Review → Bob\n4. <s>Timesheet</s> ✅"
+	if got != want {
+		t.Errorf("digestText() = %q; want %q", got, want)
+	}
+
+	keyboard := digestKeyboard(chats, me, digestViewDaily, shown)
+	if len(keyboard.InlineKeyboard) != 4 {
+		t.Fatalf("got %d buttons; want 4", len(keyboard.InlineKeyboard))
+	}
+	button := keyboard.InlineKeyboard[3][0]
+	if button.Text != "✅ 4. Timesheet" {
+		t.Errorf("button text = %q; want %q", button.Text, "✅ 4. Timesheet")
+	}
+	if data := *button.CallbackData; !strings.HasSuffix(data, "|-100|"+digestViewDaily) {
+		t.Errorf("button data = %q; want the task's chat and the view", data)
+	}
+}
+
+func TestDigestLimit(t *testing.T) {
+	tasks := make([]storage.Task, digestButtonLimit+5)
+	text, shown := digestText(i18n.For("en"), "Header", []digestChat{{chatID: 1, title: "Private chat", tasks: tasks}}, 1)
+	if shown != digestButtonLimit {
+		t.Errorf("digestText() listed %d tasks; want %d", shown, digestButtonLimit)
+	}
+	if !strings.HasSuffix(text, "…and 5 more tasks.") {
+		t.Errorf("digestText() = %q; want a note about the 5 tasks left out", text)
+	}
+	keyboard := digestKeyboard([]digestChat{{chatID: 1, tasks: tasks}}, 1, digestViewAll, shown)
+	if len(keyboard.InlineKeyboard) != digestButtonLimit {
+		t.Errorf("got %d buttons; want %d", len(keyboard.InlineKeyboard), digestButtonLimit)
+	}
+}
+
+func TestDigestTextLength(t *testing.T) {
+	members := []storage.ChatMember{{UserID: 2, FirstName: strings.Repeat("Bob", 20)}}
+	var chats []digestChat
+	for i := 0; i < 10; i++ {
+		chat := digestChat{chatID: int64(-100 - i), title: strings.Repeat("<Team>", 20), members: members}
+		for j := 0; j < 10; j++ {
+			chat.tasks = append(chat.tasks, storage.Task{
+				Description: strings.Repeat("a & b ", 100),
+				Assignees:   []int64{2},
+			})
+		}
+		chats = append(chats, chat)
+	}
+
+	text, shown := digestText(i18n.For("en"), "Header", chats, 1)
+	if n, err := checkHTML(text); err != nil || n > messageTextLimit {
+		t.Errorf("digestText() is %d characters (error %v); want at most %d", n, err, messageTextLimit)
+	}
+	if shown == 0 || shown >= digestButtonLimit {
+		t.Errorf("digestText() listed %d tasks; want fewer than %d", shown, digestButtonLimit)
+	}
+	if !strings.Contains(text, fmt.Sprintf("\n%d. ", shown)) || strings.Contains(text, fmt.Sprintf("\n%d. ", shown+1)) {
+		t.Errorf("digestText() doesn't end at task %d", shown)
+	}
+	if want := fmt.Sprintf("…and %d more tasks.", 100-shown); !strings.HasSuffix(text, want) {
+		t.Errorf("digestText() ends with %q; want %q", text[len(text)-40:], want)
+	}
+}
+
+// telegramStub answers getMe and reports the members of a group by user ID as
+// getChatMember statuses; unknown users make getChatMember fail
+func telegramStub(t *testing.T, statuses map[string]string) *tgbotapi.BotAPI {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path.Base(r.URL.Path) {
+		case "getMe":
+			fmt.Fprint(w, `{"ok":true,"result":{"id":1,"is_bot":true,"username":"nagger_bot"}}`)
+		case "getChatMember":
+			userID := r.FormValue("user_id")
+			status, ok := statuses[userID]
+			if !ok {
+				fmt.Fprint(w, `{"ok":false,"error_code":400,"description":"Bad Request: user not found"}`)
+				return
+			}
+			fmt.Fprintf(w, `{"ok":true,"result":{"user":{"id":%s,"first_name":"User"},"status":%q}}`, userID, status)
+		default:
+			t.Errorf("unexpected Telegram call %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	api, err := tgbotapi.NewBotAPIWithClient("token", srv.URL+"/bot%s/%s", srv.Client())
+	if err != nil {
+		t.Fatalf("NewBotAPIWithClient() error = %v", err)
+	}
+	return api
+}
+
+func TestIsChatMember(t *testing.T) {
+	const group = -100
+	tests := []struct {
+		name       string
+		userID     int64
+		record     bool // The bot has a member record
+		want       bool
+		wantRecord bool
+	}{
+		{"Member", 1, true, true, true},
+		{"Member without a record", 2, false, true, true},
+		{"Left", 3, true, false, false},
+		{"Kicked", 4, true, false, false},
+		{"Restricted outside the chat", 5, true, false, false},
+		{"Telegram fails, on record", 6, true, true, true},
+		{"Telegram fails, no record", 7, false, false, false},
+	}
+
+	b, db, _ := newTestBot(t)
+	b.api = telegramStub(t, map[string]string{"1": "member", "2": "member", "3": "left", "4": "kicked", "5": "restricted"})
+	ctx := context.Background()
+	for _, tt := range tests {
+		if tt.record {
+			db.members[[2]int64{group, tt.userID}] = storage.ChatMember{ChatID: group, UserID: tt.userID}
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.isChatMember(ctx, group, tt.userID); got != tt.want {
+				t.Errorf("isChatMember() = %v; want %v", got, tt.want)
+			}
+			if _, ok := db.members[[2]int64{group, tt.userID}]; ok != tt.wantRecord {
+				t.Errorf("member record kept = %v; want %v", ok, tt.wantRecord)
+			}
+		})
+	}
+}
+
+func TestDigestCallbackAfterLeavingChat(t *testing.T) {
+	const me, group = 1, -100
+	b, db, api := newTestBot(t)
+	b.api = telegramStub(t, map[string]string{"1": "left"})
+	ctx := context.Background()
+
+	taskID := primitive.NewObjectID()
+	db.tasks = []storage.Task{{ID: taskID, ChatID: group, UserID: me, Description: "Fix CI", Status: storage.TaskStatusActive}}
+	db.members[[2]int64{group, me}] = storage.ChatMember{ChatID: group, UserID: me}
+
+	query := &tgbotapi.CallbackQuery{
+		ID:      "1",
+		From:    &tgbotapi.User{ID: me},
+		Message: &tgbotapi.Message{MessageID: 1, Chat: &tgbotapi.Chat{ID: me, Type: "private"}},
+	}
+	answer := b.handleDigestCallback(ctx, query, []string{taskID.Hex(), fmt.Sprint(group), digestViewAll})
+	if answer.key != "digest.not_member" {
+		t.Errorf("answer = %+v; want digest.not_member", answer)
+	}
+	if db.tasks[0].Status != storage.TaskStatusActive {
+		t.Errorf("task status = %q; want it untouched", db.tasks[0].Status)
+	}
+	if want := i18n.For("en").T("digest.empty"); api.lastText() != want {
+		t.Errorf("redrawn digest = %q; want %q", api.lastText(), want)
+	}
+}
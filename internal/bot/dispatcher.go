@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Dispatcher defaults
+const (
+	defaultUpdateWorkers   = 16
+	defaultUpdateQueueSize = 64
+	drainTimeout           = 10 * time.Second
+)
+
+// dispatcher handles updates concurrently. Updates from one chat always go to the
+// same worker, so they are handled one at a time and in the order they arrived.
+type dispatcher struct {
+	handle func(ctx context.Context, update tgbotapi.Update)
+	queues []chan tgbotapi.Update
+	wg     sync.WaitGroup
+}
+
+func newDispatcher(workers, queueSize int, handle func(ctx context.Context, update tgbotapi.Update)) *dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	d := &dispatcher{
+		handle: handle,
+		queues: make([]chan tgbotapi.Update, workers),
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan tgbotapi.Update, queueSize)
+	}
+	return d
+}
+
+// start launches the workers. Handlers get ctx, which should outlive the update
+// source so queued updates can still be handled while draining.
+func (d *dispatcher) start(ctx context.Context) {
+	for _, queue := range d.queues {
+		d.wg.Add(1)
+		go func(queue <-chan tgbotapi.Update) {
+			defer d.wg.Done()
+			for update := range queue {
+				d.handle(ctx, update)
+			}
+		}(queue)
+	}
+}
+
+// submit queues an update for its chat's worker. When that worker's queue is full it
+// blocks until there is room, which stops the caller from fetching more updates.
+// It returns ctx's error if ctx is done first.
+func (d *dispatcher) submit(ctx context.Context, update tgbotapi.Update) error {
+	queue := d.queues[d.worker(updateChatID(update))]
+
+	select {
+	case queue <- update:
+		return nil
+	default:
+	}
+
+	log.Printf("Update queue for chat %d is full, waiting", updateChatID(update))
+	select {
+	case queue <- update:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drain stops accepting updates and waits for the queued ones to be handled.
+// It returns false if they weren't all handled within timeout.
+func (d *dispatcher) drain(timeout time.Duration) bool {
+	for _, queue := range d.queues {
+		close(queue)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// worker picks the worker for a chat
+func (d *dispatcher) worker(chatID int64) int {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(chatID))
+	h := fnv.New32a()
+	h.Write(buf[:])
+	return int(h.Sum32() % uint32(len(d.queues)))
+}
+
+// updateChatID returns the chat an update belongs to, or the user for updates without a chat
+func updateChatID(update tgbotapi.Update) int64 {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat.ID
+	case update.CallbackQuery != nil:
+		if update.CallbackQuery.Message != nil {
+			return update.CallbackQuery.Message.Chat.ID
+		}
+		return update.CallbackQuery.From.ID
+	}
+	return 0
+}
@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func chatUpdate(id int, chatID int64) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: id, Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: chatID}}}
+}
+
+func TestDispatcherKeepsOrderWithinChat(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[int64][]int)
+
+	d := newDispatcher(4, 8, func(ctx context.Context, update tgbotapi.Update) {
+		mu.Lock()
+		defer mu.Unlock()
+		chatID := updateChatID(update)
+		seen[chatID] = append(seen[chatID], update.UpdateID)
+	})
+	d.start(context.Background())
+
+	const perChat = 50
+	chats := []int64{1, 2, 3, -100123, 42}
+	id := 0
+	for i := 0; i < perChat; i++ {
+		for _, chatID := range chats {
+			id++
+			if err := d.submit(context.Background(), chatUpdate(id, chatID)); err != nil {
+				t.Fatalf("submit() = %v", err)
+			}
+		}
+	}
+	if !d.drain(time.Second) {
+		t.Fatal("drain timed out")
+	}
+
+	for _, chatID := range chats {
+		ids := seen[chatID]
+		if len(ids) != perChat {
+			t.Fatalf("chat %d: handled %d updates, want %d", chatID, len(ids), perChat)
+		}
+		for i := 1; i < len(ids); i++ {
+			if ids[i] < ids[i-1] {
+				t.Fatalf("chat %d: update %d handled after %d", chatID, ids[i], ids[i-1])
+			}
+		}
+	}
+}
+
+func TestDispatcherSlowChatDoesNotBlockOthers(t *testing.T) {
+	d := newDispatcher(2, 4, nil)
+	slowChat := int64(1)
+	otherChat := int64(2)
+	for d.worker(otherChat) == d.worker(slowChat) {
+		otherChat++
+	}
+
+	release := make(chan struct{})
+	handled := make(chan int64, 1)
+	d.handle = func(ctx context.Context, update tgbotapi.Update) {
+		if updateChatID(update) == slowChat {
+			<-release
+			return
+		}
+		handled <- updateChatID(update)
+	}
+	d.start(context.Background())
+	defer d.drain(time.Second)
+	defer close(release)
+
+	if err := d.submit(context.Background(), chatUpdate(1, slowChat)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.submit(context.Background(), chatUpdate(2, otherChat)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case chatID := <-handled:
+		if chatID != otherChat {
+			t.Errorf("handled chat %d, want %d", chatID, otherChat)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("update for another chat waited for the slow chat")
+	}
+}
+
+func TestDispatcherBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	d := newDispatcher(1, 1, func(ctx context.Context, update tgbotapi.Update) {
+		<-release
+	})
+	d.start(context.Background())
+
+	// One update is being handled and one fills the queue
+	if err := d.submit(context.Background(), chatUpdate(1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := d.submit(context.Background(), chatUpdate(2, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.submit(ctx, chatUpdate(3, 1)); err != context.DeadlineExceeded {
+		t.Errorf("submit() to a full queue = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	if !d.drain(time.Second) {
+		t.Error("drain timed out")
+	}
+}
+
+func TestDispatcherDrainHandlesQueuedUpdates(t *testing.T) {
+	var mu sync.Mutex
+	count := 0
+	d := newDispatcher(2, 16, func(ctx context.Context, update tgbotapi.Update) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		count++
+		mu.Unlock()
+	})
+	d.start(context.Background())
+
+	for i := 0; i < 20; i++ {
+		if err := d.submit(context.Background(), chatUpdate(i, int64(i%3))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !d.drain(time.Second) {
+		t.Fatal("drain timed out")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if count != 20 {
+		t.Errorf("handled %d updates, want 20", count)
+	}
+}
+
+func TestDispatcherDrainTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	d := newDispatcher(1, 1, func(ctx context.Context, update tgbotapi.Update) {
+		<-release
+	})
+	d.start(context.Background())
+
+	if err := d.submit(context.Background(), chatUpdate(1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if d.drain(10 * time.Millisecond) {
+		t.Error("drain() reported success while a handler was still running")
+	}
+}
@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// unreachableChatErrors maps Telegram error descriptions to the reason the chat is unreachable
+var unreachableChatErrors = []struct {
+	code    int
+	message string
+	reason  string
+}{
+	{http.StatusForbidden, "bot was blocked by the user", storage.ChatInactiveBlocked},
+	{http.StatusForbidden, "user is deactivated", storage.ChatInactiveDeactivated},
+	{http.StatusForbidden, "bot was kicked", storage.ChatInactiveKicked},
+	{http.StatusForbidden, "bot is not a member", storage.ChatInactiveKicked},
+	{http.StatusForbidden, "group chat was deleted", storage.ChatInactiveNotFound},
+	{http.StatusBadRequest, "chat not found", storage.ChatInactiveNotFound},
+}
+
+// chatUnreachableReason reports whether err means the bot can no longer message the chat
+func chatUnreachableReason(err error) (string, bool) {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return "", false
+	}
+
+	message := strings.ToLower(apiErr.Message)
+	for _, e := range unreachableChatErrors {
+		if apiErr.Code == e.code && strings.Contains(message, e.message) {
+			return e.reason, true
+		}
+	}
+	return "", false
+}
+
+// handleSendError marks the chat inactive if err shows it is no longer reachable.
+// It returns true if the chat was deactivated.
+func (b *Bot) handleSendError(ctx context.Context, chatID int64, err error) bool {
+	reason, unreachable := chatUnreachableReason(err)
+	if !unreachable {
+		return false
+	}
+
+	if err := b.storage.DeactivateChat(ctx, chatID, reason); err != nil {
+		log.Printf("Error deactivating chat %d: %v", chatID, err)
+		return false
+	}
+
+	log.Printf("Chat %d is unreachable (%s), reminders are paused until it sends /start", chatID, reason)
+	return true
+}
+
+// editErrorContains reports whether err is Telegram rejecting a message edit with the message
+func editErrorContains(err error, message string) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest &&
+		strings.Contains(strings.ToLower(apiErr.Message), message)
+}
+
+// isNotModified reports whether an edit failed because it wouldn't change the message
+func isNotModified(err error) bool {
+	return editErrorContains(err, "message is not modified")
+}
+
+// isMessageGone reports whether an edit failed because the message was deleted
+func isMessageGone(err error) bool {
+	return editErrorContains(err, "message to edit not found")
+}
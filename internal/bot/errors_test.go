@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestChatUnreachableReason(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantReason string
+		wantOK     bool
+	}{
+		{
+			name:       "Blocked by user",
+			err:        &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"},
+			wantReason: storage.ChatInactiveBlocked,
+			wantOK:     true,
+		},
+		{
+			name:       "User deactivated",
+			err:        &tgbotapi.Error{Code: 403, Message: "Forbidden: user is deactivated"},
+			wantReason: storage.ChatInactiveDeactivated,
+			wantOK:     true,
+		},
+		{
+			name:       "Kicked from group",
+			err:        &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was kicked from the supergroup chat"},
+			wantReason: storage.ChatInactiveKicked,
+			wantOK:     true,
+		},
+		{
+			name:       "Chat not found",
+			err:        &tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"},
+			wantReason: storage.ChatInactiveNotFound,
+			wantOK:     true,
+		},
+		{
+			name:       "Wrapped error",
+			err:        fmt.Errorf("send failed: %w", &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}),
+			wantReason: storage.ChatInactiveBlocked,
+			wantOK:     true,
+		},
+		{
+			name:   "Other bad request",
+			err:    &tgbotapi.Error{Code: 400, Message: "Bad Request: message text is empty"},
+			wantOK: false,
+		},
+		{
+			name:   "Flood control",
+			err:    &tgbotapi.Error{Code: 429, Message: "Too Many Requests: retry after 5"},
+			wantOK: false,
+		},
+		{
+			name:   "Network error",
+			err:    errors.New("connection reset by peer"),
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, ok := chatUnreachableReason(tt.err)
+			if ok != tt.wantOK || reason != tt.wantReason {
+				t.Errorf("chatUnreachableReason() = %q, %v; want %q, %v", reason, ok, tt.wantReason, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestEditErrors(t *testing.T) {
+	notModified := &tgbotapi.Error{Code: 400, Message: "Bad Request: message is not modified: specified new message content and reply markup are exactly the same"}
+	gone := fmt.Errorf("edit failed: %w", &tgbotapi.Error{Code: 400, Message: "Bad Request: message to edit not found"})
+	other := &tgbotapi.Error{Code: 400, Message: "Bad Request: message text is empty"}
+
+	if !isNotModified(notModified) || isNotModified(gone) || isNotModified(other) || isNotModified(errors.New("timeout")) {
+		t.Error("isNotModified() should only match unchanged edits")
+	}
+	if !isMessageGone(gone) || isMessageGone(notModified) || isMessageGone(other) {
+		t.Error("isMessageGone() should only match deleted messages")
+	}
+}
@@ -0,0 +1,581 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// memberRefresh is how often an unchanged member is saved again
+const memberRefresh = 24 * time.Hour
+
+// memberCache remembers which members were saved recently, so the bot doesn't write
+// to the database on every group message
+type memberCache struct {
+	mu   sync.Mutex
+	seen map[[2]int64]memberSeen
+}
+
+type memberSeen struct {
+	member storage.ChatMember
+	at     time.Time
+}
+
+func newMemberCache() *memberCache {
+	return &memberCache{seen: make(map[[2]int64]memberSeen)}
+}
+
+// stale reports whether the member should be saved, and if so marks it as saved at now
+func (c *memberCache) stale(member storage.ChatMember, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := [2]int64{member.ChatID, member.UserID}
+	if seen, ok := c.seen[key]; ok && seen.member == member && now.Sub(seen.at) < memberRefresh {
+		return false
+	}
+	c.seen[key] = memberSeen{member: member, at: now}
+	return true
+}
+
+func (c *memberCache) forget(chatID, userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.seen, [2]int64{chatID, userID})
+}
+
+// trackMembers records the group members the bot sees, so tasks can be assigned to them
+func (b *Bot) trackMembers(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) {
+		if message := req.update.Message; message != nil && isGroup(message.Chat) {
+			b.saveMember(ctx, message.Chat.ID, message.From)
+			for i := range message.NewChatMembers {
+				b.saveMember(ctx, message.Chat.ID, &message.NewChatMembers[i])
+			}
+			if left := message.LeftChatMember; left != nil {
+				b.members.forget(message.Chat.ID, left.ID)
+				if err := b.storage.RemoveChatMember(ctx, message.Chat.ID, left.ID); err != nil {
+					log.Printf("Error removing chat member: %v", err)
+				}
+			}
+		}
+		if query := req.update.CallbackQuery; query != nil && query.Message != nil && isGroup(query.Message.Chat) {
+			b.saveMember(ctx, query.Message.Chat.ID, query.From)
+		}
+
+		next(ctx, req)
+	}
+}
+
+func (b *Bot) saveMember(ctx context.Context, chatID int64, user *tgbotapi.User) {
+	if user == nil || user.IsBot {
+		return
+	}
+	member := newChatMember(chatID, user)
+	if !b.members.stale(member, time.Now()) {
+		return
+	}
+	if err := b.storage.UpsertChatMember(ctx, &member); err != nil {
+		log.Printf("Error saving chat member: %v", err)
+		b.members.forget(chatID, user.ID)
+	}
+}
+
+func newChatMember(chatID int64, user *tgbotapi.User) storage.ChatMember {
+	return storage.ChatMember{
+		ChatID:    chatID,
+		UserID:    user.ID,
+		Username:  strings.ToLower(user.UserName),
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	}
+}
+
+func isGroup(chat *tgbotapi.Chat) bool {
+	return chat != nil && (chat.IsGroup() || chat.IsSuperGroup())
+}
+
+// chatMembers returns the known members of a group chat, or nil for private chats
+func (b *Bot) chatMembers(ctx context.Context, chatID int64) []storage.ChatMember {
+	// Only group chats have negative IDs
+	if chatID > 0 {
+		return nil
+	}
+	members, err := b.storage.GetChatMembers(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting chat members: %v", err)
+	}
+	return members
+}
+
+// isChatMember reports whether the user is still in the group chat. Telegram has the
+// final say: a member it reports as gone loses their record, and one without a record
+// gets it back. If Telegram can't be asked, the records decide.
+func (b *Bot) isChatMember(ctx context.Context, chatID, userID int64) bool {
+	member, err := b.api.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+	if err != nil {
+		log.Printf("Error getting chat member: %v", err)
+		for _, known := range b.chatMembers(ctx, chatID) {
+			if known.UserID == userID {
+				return true
+			}
+		}
+		return false
+	}
+
+	if member.HasLeft() || member.WasKicked() || (member.Status == "restricted" && !member.IsMember) {
+		b.members.forget(chatID, userID)
+		if err := b.storage.RemoveChatMember(ctx, chatID, userID); err != nil {
+			log.Printf("Error removing chat member: %v", err)
+		}
+		return false
+	}
+	b.saveMember(ctx, chatID, member.User)
+	return true
+}
+
+func init() {
+	registerCommands(helpGroups, (*Bot).groupCommands)
+}
+
+// groupCommands are the commands for tasks shared by group members
+func (b *Bot) groupCommands() []*command {
+	return []*command{
+		{name: "addeach", usage: "[@user...] <task>", handler: b.handleAddEach},
+		{name: "assign", usage: "<task> [@user...]", handler: b.handleAssign},
+	}
+}
+
+// handleAddEach adds a task that every member, or every mentioned member, completes individually
+func (b *Bot) handleAddEach(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	if !isGroup(message.Chat) {
+		b.sendMessage(message.Chat.ID, p.T("group.addeach_groups_only"))
+		return
+	}
+
+	assignees, description, ok := b.parseAssignees(ctx, message, 0)
+	if !ok {
+		return
+	}
+	if description == "" {
+		data := map[string]string{"per_member": "1", "assignees": formatIDs(assignees)}
+		b.prompt(ctx, message, flowAdd, "description", data, p.T("group.addeach_prompt"), p.T("add.placeholder"))
+		return
+	}
+
+	b.addTask(ctx, message, &storage.Task{Description: description, Assignees: assignees, PerMember: true})
+}
+
+// handleAssign replaces the assignees of a task: /assign <task> [@user...].
+// Without mentions it unassigns the task.
+func (b *Bot) handleAssign(ctx context.Context, message *tgbotapi.Message) {
+	const usage = "/assign <task> @user..."
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	if !isGroup(message.Chat) {
+		b.sendMessage(message.Chat.ID, p.T("group.assign_groups_only"))
+		return
+	}
+
+	fields := strings.Fields(message.CommandArguments())
+	if len(fields) == 0 {
+		b.sendMessage(message.Chat.ID, p.T("task.ref_usage", usage))
+		return
+	}
+
+	assignees, rest, ok := b.parseAssignees(ctx, message, 1)
+	if !ok {
+		return
+	}
+	if rest != "" {
+		b.sendMessage(message.Chat.ID, p.T("group.assign_mentions", usage))
+		return
+	}
+
+	task := b.findTask(ctx, message, fields[0], usage)
+	if task == nil {
+		return
+	}
+	if task.ChatID != message.Chat.ID {
+		b.sendMessage(message.Chat.ID, p.T("group.assign_shared"))
+		return
+	}
+	if task.Rotation != nil {
+		b.sendMessage(message.Chat.ID, p.T("group.assign_rotation", task.Description))
+		return
+	}
+	if !b.permit(ctx, message, permEdit, task) {
+		return
+	}
+
+	if err := b.storage.SetTaskAssignees(ctx, task.ID, assignees); err != nil {
+		log.Printf("Error assigning task: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("group.assign_failed"))
+		return
+	}
+	b.remindersChanged(ctx, task.ChatID)
+
+	if len(assignees) == 0 {
+		b.sendMessage(message.Chat.ID, p.T("group.unassigned", task.Description))
+		return
+	}
+	members := b.chatMembers(ctx, message.Chat.ID)
+	b.sendMessage(message.Chat.ID, p.T("group.assigned", task.Description, memberNames(p, assignees, members)))
+}
+
+// parseAssignees resolves the @mentions at the start of a message's command arguments,
+// after skipping the given number of words, and returns the text after them. In private
+// chats the whole text is returned. If a mentioned user is unknown it tells the user
+// and returns false.
+func (b *Bot) parseAssignees(ctx context.Context, message *tgbotapi.Message, skipWords int) ([]int64, string, bool) {
+	if !isGroup(message.Chat) {
+		text := message.Text
+		if message.IsCommand() {
+			text = message.CommandArguments()
+		}
+		return nil, strings.TrimSpace(text), true
+	}
+
+	mentions, rest := leadingMentions(message, skipWords)
+	assignees, ok := b.resolveMentions(ctx, message, mentions)
+	if !ok {
+		return nil, "", false
+	}
+	return assignees, rest, true
+}
+
+// resolveMentions returns the user IDs of mentioned members. If a mentioned user is
+// unknown it tells the user and returns false.
+func (b *Bot) resolveMentions(ctx context.Context, message *tgbotapi.Message, mentions []mention) ([]int64, bool) {
+	var ids []int64
+	var unknown []string
+	for _, m := range mentions {
+		if m.user != nil {
+			b.saveMember(ctx, message.Chat.ID, m.user)
+			ids = appendID(ids, m.user.ID)
+			continue
+		}
+
+		member, err := b.storage.GetChatMemberByUsername(ctx, message.Chat.ID, strings.ToLower(m.username))
+		if err != nil {
+			log.Printf("Error getting chat member: %v", err)
+			b.sendMessage(message.Chat.ID, b.printer(ctx, message.Chat.ID, message.From).T("group.lookup_failed"))
+			return nil, false
+		}
+		if member == nil {
+			unknown = append(unknown, "@"+m.username)
+			continue
+		}
+		ids = appendID(ids, member.UserID)
+	}
+
+	if len(unknown) > 0 {
+		b.sendMessage(message.Chat.ID, b.printer(ctx, message.Chat.ID, message.From).T("group.unknown_members", strings.Join(unknown, ", ")))
+		return nil, false
+	}
+	return ids, true
+}
+
+// mention is an @username mention, or a mention of a user without a username
+type mention struct {
+	username string
+	user     *tgbotapi.User
+}
+
+// leadingMentions returns the mentions at the start of a message's arguments and the text
+// after them. Arguments follow the command, if any; skipWords words are skipped first.
+// Entity offsets are in UTF-16 code units.
+func leadingMentions(message *tgbotapi.Message, skipWords int) ([]mention, string) {
+	text := utf16.Encode([]rune(message.Text))
+	pos := argumentsStart(message)
+
+	for i := 0; i < skipWords; i++ {
+		pos = skipSpace(text, pos)
+		for pos < len(text) && !isSpaceUnit(text[pos]) {
+			pos++
+		}
+	}
+
+	mentions, pos := collectMentions(text, message.Entities, pos)
+	return mentions, strings.TrimSpace(string(utf16.Decode(text[pos:])))
+}
+
+// splitAtMentions splits a message's arguments into the text before the first mention,
+// the run of mentions starting there and the text after them
+func splitAtMentions(message *tgbotapi.Message) (before string, mentions []mention, after string) {
+	text := utf16.Encode([]rune(message.Text))
+	start := argumentsStart(message)
+
+	pos := len(text)
+	for _, e := range message.Entities {
+		if e.Offset >= start && e.Offset < pos && entityAt(message.Entities, e.Offset) != nil {
+			pos = e.Offset
+		}
+	}
+
+	before = strings.TrimSpace(string(utf16.Decode(text[start:pos])))
+	mentions, pos = collectMentions(text, message.Entities, pos)
+	after = strings.TrimSpace(string(utf16.Decode(text[pos:])))
+	return before, mentions, after
+}
+
+// argumentsStart returns the offset after the command, or 0 for messages without one
+func argumentsStart(message *tgbotapi.Message) int {
+	if message.IsCommand() {
+		return message.Entities[0].Length
+	}
+	return 0
+}
+
+// collectMentions reads consecutive mentions from pos on and returns the offset after them
+func collectMentions(text []uint16, entities []tgbotapi.MessageEntity, pos int) ([]mention, int) {
+	var mentions []mention
+	for {
+		next := skipSpace(text, pos)
+		entity := entityAt(entities, next)
+		if entity == nil || entity.Offset+entity.Length > len(text) {
+			return mentions, pos
+		}
+		switch entity.Type {
+		case "mention":
+			// The entity includes the @
+			name := string(utf16.Decode(text[next+1 : next+entity.Length]))
+			mentions = append(mentions, mention{username: name})
+		case "text_mention":
+			mentions = append(mentions, mention{user: entity.User})
+		}
+		pos = next + entity.Length
+	}
+}
+
+func skipSpace(text []uint16, pos int) int {
+	for pos < len(text) && isSpaceUnit(text[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// entityAt returns the mention entity starting at offset, if any
+func entityAt(entities []tgbotapi.MessageEntity, offset int) *tgbotapi.MessageEntity {
+	for i := range entities {
+		e := &entities[i]
+		if e.Offset != offset || e.Length == 0 {
+			continue
+		}
+		if e.Type == "mention" || (e.Type == "text_mention" && e.User != nil) {
+			return e
+		}
+	}
+	return nil
+}
+
+func isSpaceUnit(u uint16) bool {
+	return u == ' ' || u == '\n' || u == '\t' || u == '\r'
+}
+
+// completeForMember records that a member completed a per-member task. The task is
+// done for today once all its members have completed it. It returns the progress.
+func (b *Bot) completeForMember(ctx context.Context, task *storage.Task, userID int64) (done, total int, err error) {
+	updated, err := b.storage.CompleteTaskForMember(ctx, task.ID, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	done, total = memberProgress(*updated, b.chatMembers(ctx, task.ChatID))
+	if done >= total && updated.Status != storage.TaskStatusCompletedToday {
+		if err := b.storage.CompleteTask(ctx, task.ID); err != nil {
+			return 0, 0, err
+		}
+	}
+	b.remindersChanged(ctx, task.ChatID)
+	b.recordStreak(ctx, task.ChatID)
+	return done, total, nil
+}
+
+// taskMemberIDs returns the members who must complete a per-member task: its assignees,
+// or all known members if it has none
+func taskMemberIDs(task storage.Task, members []storage.ChatMember) []int64 {
+	if len(task.Assignees) > 0 {
+		return task.Assignees
+	}
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.UserID)
+	}
+	return ids
+}
+
+// memberProgress counts how many of a per-member task's members completed it
+func memberProgress(task storage.Task, members []storage.ChatMember) (done, total int) {
+	ids := taskMemberIDs(task, members)
+	for _, id := range ids {
+		if task.IsCompletedBy(id) {
+			done++
+		}
+	}
+	return done, len(ids)
+}
+
+// progressMark shows the progress of a per-member task, e.g. "👥2/5 "
+func progressMark(task storage.Task, members []storage.ChatMember) string {
+	if !task.PerMember {
+		return ""
+	}
+	done, total := memberProgress(task, members)
+	return fmt.Sprintf("👥%d/%d ", done, total)
+}
+
+func findMember(members []storage.ChatMember, userID int64) *storage.ChatMember {
+	for i := range members {
+		if members[i].UserID == userID {
+			return &members[i]
+		}
+	}
+	return nil
+}
+
+// memberName is how the bot refers to a member without mentioning them
+func memberName(m storage.ChatMember) string {
+	name := strings.TrimSpace(m.FirstName + " " + m.LastName)
+	if name == "" {
+		name = "@" + m.Username
+	}
+	return name
+}
+
+// memberNames lists the names of the given members; members who left are skipped
+func memberNames(p *i18n.Printer, ids []int64, members []storage.ChatMember) string {
+	var names []string
+	for _, id := range ids {
+		if m := findMember(members, id); m != nil {
+			names = append(names, memberName(*m))
+		}
+	}
+	if len(names) == 0 {
+		return p.T("group.former_members")
+	}
+	return strings.Join(names, ", ")
+}
+
+// pendingAssignees returns the assignees a reminder should mention: all assignees of an
+// active task, or those who haven't completed a per-member task
+func pendingAssignees(task storage.Task) []int64 {
+	if task.Status == storage.TaskStatusCompletedToday {
+		return nil
+	}
+	if !task.PerMember {
+		return task.Assignees
+	}
+	var pending []int64
+	for _, id := range task.Assignees {
+		if !task.IsCompletedBy(id) {
+			pending = append(pending, id)
+		}
+	}
+	return pending
+}
+
+// writeAssignments adds a line per task that waits for its assignees, mentioning them
+func writeAssignments(p *i18n.Printer, text *entityText, tasks []storage.Task, members []storage.ChatMember) {
+	header := false
+	for _, task := range tasks {
+		var pending []storage.ChatMember
+		for _, id := range pendingAssignees(task) {
+			if m := findMember(members, id); m != nil {
+				pending = append(pending, *m)
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		if !header {
+			text.WriteString("\n\n" + p.T("reminder.waiting_for"))
+			header = true
+		}
+		text.WriteString("\n• " + truncate(task.Description, buttonTextLimit) + " — ")
+		for i, m := range pending {
+			if i > 0 {
+				text.WriteString(", ")
+			}
+			text.writeMention(m)
+		}
+		if task.Rotation != nil {
+			text.WriteString(" (" + strings.ToLower(turnLabel(p, task.Rotation.Period, time.Time{}, 0)) + ")")
+		}
+	}
+}
+
+// entityText builds a message text along with its entities. Telegram counts entity
+// offsets in UTF-16 code units.
+type entityText struct {
+	text     strings.Builder
+	length   int
+	entities []tgbotapi.MessageEntity
+}
+
+func (t *entityText) WriteString(s string) {
+	t.text.WriteString(s)
+	t.length += len(utf16.Encode([]rune(s)))
+}
+
+// writeMention mentions a member by @username, or by name for members without one
+func (t *entityText) writeMention(m storage.ChatMember) {
+	offset := t.length
+	if m.Username != "" {
+		t.WriteString("@" + m.Username)
+		t.entities = append(t.entities, tgbotapi.MessageEntity{Type: "mention", Offset: offset, Length: t.length - offset})
+		return
+	}
+	t.WriteString(memberName(m))
+	t.entities = append(t.entities, tgbotapi.MessageEntity{
+		Type:   "text_mention",
+		Offset: offset,
+		Length: t.length - offset,
+		User:   &tgbotapi.User{ID: m.UserID, FirstName: m.FirstName, LastName: m.LastName},
+	})
+}
+
+func (t *entityText) String() string {
+	return t.text.String()
+}
+
+// formatIDs and parseIDs keep user IDs in conversation data
+func formatIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+func parseIDs(s string) []int64 {
+	var ids []int64
+	for _, part := range strings.Split(s, ",") {
+		if id, err := strconv.ParseInt(part, 10, 64); err == nil {
+			ids = appendID(ids, id)
+		}
+	}
+	return ids
+}
+
+// appendID adds id unless it is already there
+func appendID(ids []int64, id int64) []int64 {
+	for _, v := range ids {
+		if v == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
@@ -0,0 +1,191 @@
+package bot
+
+import (
+	"strconv"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func commandMessage(text string, entities ...tgbotapi.MessageEntity) *tgbotapi.Message {
+	end := len(text)
+	for i, r := range text {
+		if r == ' ' {
+			end = i
+			break
+		}
+	}
+	command := tgbotapi.MessageEntity{Type: "bot_command", Offset: 0, Length: end}
+	return &tgbotapi.Message{Text: text, Entities: append([]tgbotapi.MessageEntity{command}, entities...)}
+}
+
+func TestLeadingMentions(t *testing.T) {
+	bob := &tgbotapi.User{ID: 7, FirstName: "Bob"}
+
+	tests := []struct {
+		name      string
+		message   *tgbotapi.Message
+		skipWords int
+		wantNames []string // username, or "#<id>" for text mentions
+		wantRest  string
+	}{
+		{
+			name:     "No mentions",
+			message:  commandMessage("/add fix CI"),
+			wantRest: "fix CI",
+		},
+		{
+			name: "Username and text mention",
+			message: commandMessage("/add @alice Bob fix CI",
+				tgbotapi.MessageEntity{Type: "mention", Offset: 5, Length: 6},
+				tgbotapi.MessageEntity{Type: "text_mention", Offset: 12, Length: 3, User: bob}),
+			wantNames: []string{"alice", "#7"},
+			wantRest:  "fix CI",
+		},
+		{
+			name: "Mentions later in the text stay in the description",
+			message: commandMessage("/add call @alice",
+				tgbotapi.MessageEntity{Type: "mention", Offset: 10, Length: 6}),
+			wantRest: "call @alice",
+		},
+		{
+			name: "Skipped task number",
+			message: commandMessage("/assign 3 @alice",
+				tgbotapi.MessageEntity{Type: "mention", Offset: 10, Length: 6}),
+			skipWords: 1,
+			wantNames: []string{"alice"},
+		},
+		{
+			// The emoji takes two UTF-16 code units
+			name: "Offsets in UTF-16 after an emoji",
+			message: &tgbotapi.Message{
+				Text:     "🧹 @alice sweep",
+				Entities: []tgbotapi.MessageEntity{{Type: "mention", Offset: 3, Length: 6}},
+			},
+			skipWords: 1,
+			wantNames: []string{"alice"},
+			wantRest:  "sweep",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mentions, rest := leadingMentions(tt.message, tt.skipWords)
+
+			var names []string
+			for _, m := range mentions {
+				if m.user != nil {
+					names = append(names, "#"+strconv.FormatInt(m.user.ID, 10))
+				} else {
+					names = append(names, m.username)
+				}
+			}
+			if len(names) != len(tt.wantNames) {
+				t.Fatalf("mentions = %v; want %v", names, tt.wantNames)
+			}
+			for i := range names {
+				if names[i] != tt.wantNames[i] {
+					t.Errorf("mention %d = %q; want %q", i, names[i], tt.wantNames[i])
+				}
+			}
+			if rest != tt.wantRest {
+				t.Errorf("rest = %q; want %q", rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestMemberProgress(t *testing.T) {
+	members := []storage.ChatMember{{UserID: 1}, {UserID: 2}, {UserID: 3}}
+
+	tests := []struct {
+		name      string
+		task      storage.Task
+		wantDone  int
+		wantTotal int
+	}{
+		{name: "All members", task: storage.Task{PerMember: true, CompletedBy: []int64{1, 3}}, wantDone: 2, wantTotal: 3},
+		{name: "Assignees only", task: storage.Task{PerMember: true, Assignees: []int64{2, 3}, CompletedBy: []int64{1, 3}}, wantDone: 1, wantTotal: 2},
+		{name: "Member who left is not counted", task: storage.Task{PerMember: true, CompletedBy: []int64{9}}, wantDone: 0, wantTotal: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done, total := memberProgress(tt.task, members)
+			if done != tt.wantDone || total != tt.wantTotal {
+				t.Errorf("memberProgress() = %d/%d; want %d/%d", done, total, tt.wantDone, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestWriteAssignments(t *testing.T) {
+	members := []storage.ChatMember{
+		{UserID: 1, Username: "alice", FirstName: "Alice"},
+		{UserID: 2, FirstName: "Бob"},
+	}
+	tasks := []storage.Task{
+		{Description: "Fix CI", Assignees: []int64{1, 2}, Status: storage.TaskStatusActive},
+		{Description: "Done already", Assignees: []int64{1}, Status: storage.TaskStatusCompletedToday},
+		{Description: "Water plants", Assignees: []int64{1, 2}, PerMember: true, CompletedBy: []int64{1}, Status: storage.TaskStatusActive},
+		{Description: "Nobody's", Status: storage.TaskStatusActive},
+	}
+
+	var text entityText
+	text.WriteString("🔔 Daily Reminder!")
+	writeAssignments(i18n.For("en"), &text, tasks, members)
+
+	want := "🔔 Daily Reminder!\n\n👤 Waiting for:\n• Fix CI — @alice, Бob\n• Water plants — Бob"
+	if text.String() != want {
+		t.Fatalf("text = %q; want %q", text.String(), want)
+	}
+
+	units := utf16.Encode([]rune(text.String()))
+	wantEntities := []struct {
+		typ    string
+		text   string
+		userID int64
+	}{
+		{"mention", "@alice", 0},
+		{"text_mention", "Бob", 2},
+		{"text_mention", "Бob", 2},
+	}
+	if len(text.entities) != len(wantEntities) {
+		t.Fatalf("got %d entities; want %d", len(text.entities), len(wantEntities))
+	}
+	for i, e := range text.entities {
+		got := string(utf16.Decode(units[e.Offset : e.Offset+e.Length]))
+		if e.Type != wantEntities[i].typ || got != wantEntities[i].text {
+			t.Errorf("entity %d = %s %q; want %s %q", i, e.Type, got, wantEntities[i].typ, wantEntities[i].text)
+		}
+		if e.Type == "text_mention" && (e.User == nil || e.User.ID != wantEntities[i].userID) {
+			t.Errorf("entity %d does not mention user %d", i, wantEntities[i].userID)
+		}
+	}
+}
+
+func TestMemberCacheStale(t *testing.T) {
+	c := newMemberCache()
+	now := time.Now()
+	alice := storage.ChatMember{ChatID: -1, UserID: 1, FirstName: "Alice"}
+
+	if !c.stale(alice, now) {
+		t.Error("new member should be saved")
+	}
+	if c.stale(alice, now.Add(time.Minute)) {
+		t.Error("unchanged member was saved again")
+	}
+
+	renamed := alice
+	renamed.Username = "alice"
+	if !c.stale(renamed, now.Add(2*time.Minute)) {
+		t.Error("renamed member should be saved")
+	}
+	if !c.stale(renamed, now.Add(memberRefresh+2*time.Minute)) {
+		t.Error("member should be saved again after the refresh interval")
+	}
+}
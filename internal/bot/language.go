@@ -0,0 +1,231 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// languageAuto clears the language override, so replies follow the user's Telegram app
+const languageAuto = "auto"
+
+// printerKey is the context key of the update's updatePrinter
+type printerKey struct{}
+
+// updatePrinter is the printer for the chat and user of the update being handled, looked
+// up once by the resolvePrinter middleware instead of on every reply
+type updatePrinter struct {
+	chatID int64
+	userID int64
+	p      *i18n.Printer
+}
+
+// withPrinter returns a copy of ctx that carries the printer for replying in the chat to from
+func (b *Bot) withPrinter(ctx context.Context, chatID int64, from *tgbotapi.User) context.Context {
+	return context.WithValue(ctx, printerKey{}, &updatePrinter{chatID: chatID, userID: from.ID, p: b.lookupPrinter(ctx, chatID, from)})
+}
+
+// replacePrinter swaps the printer ctx carries for the chat and user, once the language
+// they get replies in has changed
+func replacePrinter(ctx context.Context, chatID int64, from *tgbotapi.User, p *i18n.Printer) {
+	if up, ok := ctx.Value(printerKey{}).(*updatePrinter); ok && from != nil && up.chatID == chatID && up.userID == from.ID {
+		up.p = p
+	}
+}
+
+// printer returns the translations for replying in the chat to a user: the chat's
+// /language, else the user's own from their private chat, else the language of their
+// Telegram app. from may be nil for messages nobody asked for, like reminders.
+// The printer for the update's own chat and user comes from ctx.
+func (b *Bot) printer(ctx context.Context, chatID int64, from *tgbotapi.User) *i18n.Printer {
+	if up, ok := ctx.Value(printerKey{}).(*updatePrinter); ok && from != nil && up.chatID == chatID && up.userID == from.ID {
+		return up.p
+	}
+	return b.lookupPrinter(ctx, chatID, from)
+}
+
+// lookupPrinter reads the chat's and the user's settings to pick the printer
+func (b *Bot) lookupPrinter(ctx context.Context, chatID int64, from *tgbotapi.User) *i18n.Printer {
+	settings, err := b.storage.GetUserSettings(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+	}
+	if settings != nil && settings.Language != "" {
+		return i18n.For(settings.Language)
+	}
+	if from == nil {
+		return i18n.For(i18n.Default)
+	}
+
+	if from.ID != chatID {
+		own, err := b.storage.GetUserSettings(ctx, from.ID)
+		if err != nil {
+			log.Printf("Error getting user settings: %v", err)
+		}
+		if own != nil && own.Language != "" {
+			return i18n.For(own.Language)
+		}
+	}
+	return i18n.For(from.LanguageCode)
+}
+
+func init() {
+	registerCommands(helpLanguage, (*Bot).languageCommands)
+}
+
+// languageCommands are the commands for the chat's language
+func (b *Bot) languageCommands() []*command {
+	return []*command{
+		{name: "language", usage: "[en|ru|auto]", handler: b.handleLanguage},
+	}
+}
+
+// handleLanguage shows or changes the chat's language: /language [en|ru|auto]
+func (b *Bot) handleLanguage(ctx context.Context, message *tgbotapi.Message) {
+	arg := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	if arg == "" {
+		b.sendLanguages(ctx, message)
+		return
+	}
+
+	code := i18n.Match(arg)
+	if code == "" && arg != languageAuto {
+		p := b.printer(ctx, message.Chat.ID, message.From)
+		b.sendMessage(message.Chat.ID, p.T("language.unknown", arg, strings.Join(languageCodes(), ", ")))
+		return
+	}
+	if !b.permit(ctx, message, permSettings, nil) {
+		return
+	}
+
+	p, ok := b.setLanguage(ctx, message.Chat.ID, message.From, code)
+	if !ok {
+		b.sendMessage(message.Chat.ID, p.T("language.failed"))
+		return
+	}
+	b.sendMessage(message.Chat.ID, languageSetText(p, code))
+}
+
+func (b *Bot) sendLanguages(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+
+	current := i18n.Label(p.Language())
+	if settings, err := b.storage.GetUserSettings(ctx, message.Chat.ID); err == nil && (settings == nil || settings.Language == "") {
+		current = p.T("language.from_app", current)
+	}
+
+	keyboard := b.callbacks.signKeyboard(message.Chat.ID, languageKeyboard(p))
+	if _, err := b.sendHTMLMarkup(ctx, message.Chat.ID, escapeHTML(p.T("language.choose", current)), keyboard); err != nil {
+		log.Printf("Error sending languages: %v", err)
+		b.handleSendError(ctx, message.Chat.ID, err)
+	}
+}
+
+// handleLanguageCallback applies a language button. Args: language code or "auto".
+func (b *Bot) handleLanguageCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) callbackAnswer {
+	if len(args) != 1 {
+		return answerStale
+	}
+	code := i18n.Match(args[0])
+	if code == "" && args[0] != languageAuto {
+		return answerStale
+	}
+	if denied, ok := b.permitCallback(ctx, query, permSettings, nil); !ok {
+		return denied
+	}
+
+	chatID := query.Message.Chat.ID
+	p, ok := b.setLanguage(ctx, chatID, query.From, code)
+	if !ok {
+		return callbackAnswer{text: p.T("language.failed")}
+	}
+
+	text := languageSetText(p, code)
+	if err := b.editHTML(ctx, chatID, query.Message.MessageID, escapeHTML(text), nil); err != nil {
+		log.Printf("Error updating language message: %v", err)
+	}
+	return callbackAnswer{text: text}
+}
+
+// setLanguage stores the chat's language, or clears it if code is empty. It returns the
+// printer for the new language and whether the change was saved.
+func (b *Bot) setLanguage(ctx context.Context, chatID int64, from *tgbotapi.User, code string) (*i18n.Printer, bool) {
+	settings, err := b.getSettings(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		return b.printer(ctx, chatID, from), false
+	}
+
+	settings.Language = code
+	if settings.UserID == 0 {
+		settings.UserID = from.ID
+	}
+	if err := b.storage.SetUserSettings(ctx, settings); err != nil {
+		log.Printf("Error setting user settings: %v", err)
+		return b.printer(ctx, chatID, from), false
+	}
+	p := b.lookupPrinter(ctx, chatID, from)
+	replacePrinter(ctx, chatID, from, p)
+	return p, true
+}
+
+func languageSetText(p *i18n.Printer, code string) string {
+	if code == "" {
+		return p.T("language.set_auto")
+	}
+	return p.T("language.set", i18n.Label(code))
+}
+
+func languageKeyboard(p *i18n.Printer) tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	for _, lang := range i18n.Languages() {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(lang.Label, callbackData(callbackLanguage, lang.Code)))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(p.T("language.auto_button"), callbackData(callbackLanguage, languageAuto)),
+	))
+}
+
+func languageCodes() []string {
+	var codes []string
+	for _, lang := range i18n.Languages() {
+		codes = append(codes, lang.Code)
+	}
+	return codes
+}
+
+// menuCommands lists the commands for Telegram's command menu in a language.
+// Admin commands are left out, as everyone sees the menu.
+func menuCommands(commands []*command, p *i18n.Printer) []tgbotapi.BotCommand {
+	var menu []tgbotapi.BotCommand
+	for _, cmd := range commands {
+		if cmd.adminOnly {
+			continue
+		}
+		menu = append(menu, tgbotapi.BotCommand{Command: cmd.name, Description: p.T("command." + cmd.name)})
+	}
+	return menu
+}
+
+// setCommandMenus sets the command menu for every language, and the default menu for
+// users whose language isn't supported
+func (b *Bot) setCommandMenus() {
+	scope := tgbotapi.BotCommandScope{Type: "default"}
+	commands := b.commandList()
+
+	configs := []tgbotapi.SetMyCommandsConfig{
+		tgbotapi.NewSetMyCommandsWithScope(scope, menuCommands(commands, i18n.For(i18n.Default))...),
+	}
+	for _, lang := range i18n.Languages() {
+		configs = append(configs, tgbotapi.NewSetMyCommandsWithScopeAndLanguage(scope, lang.Code, menuCommands(commands, i18n.For(lang.Code))...))
+	}
+
+	for _, config := range configs {
+		if _, err := b.api.Request(config); err != nil {
+			log.Printf("Error setting command menu %q: %v", config.LanguageCode, err)
+		}
+	}
+}
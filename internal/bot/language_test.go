@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestMenuCommands(t *testing.T) {
+	b := &Bot{}
+	commands := b.commandList()
+
+	for _, lang := range i18n.Languages() {
+		menu := menuCommands(commands, i18n.For(lang.Code))
+		if len(menu) == 0 {
+			t.Fatalf("%s: empty menu", lang.Code)
+		}
+		for _, cmd := range menu {
+			if cmd.Description == "" || cmd.Description == "command."+cmd.Command {
+				t.Errorf("%s: /%s has no description", lang.Code, cmd.Command)
+			}
+			if n := len([]rune(cmd.Description)); n > 256 {
+				t.Errorf("%s: /%s description is %d characters; Telegram allows 256", lang.Code, cmd.Command, n)
+			}
+		}
+		for _, cmd := range commands {
+			if !cmd.adminOnly {
+				continue
+			}
+			for _, item := range menu {
+				if item.Command == cmd.name {
+					t.Errorf("%s: admin command /%s is in the menu", lang.Code, cmd.name)
+				}
+			}
+		}
+	}
+}
+
+func TestLanguageSetText(t *testing.T) {
+	tests := []struct {
+		lang string
+		code string
+		want string
+	}{
+		{"en", "ru", "✅ Language set to 🇷🇺 Русский."},
+		{"ru", "ru", "✅ Язык: 🇷🇺 Русский."},
+		{"en", "", "✅ I'll speak the language of your Telegram app."},
+	}
+
+	for _, tt := range tests {
+		if got := languageSetText(i18n.For(tt.lang), tt.code); got != tt.want {
+			t.Errorf("languageSetText(%s, %q) = %q; want %q", tt.lang, tt.code, got, tt.want)
+		}
+	}
+}
+
+// countingStore counts the settings lookups
+type countingStore struct {
+	*memoryStore
+	lookups atomic.Int32
+}
+
+func (c *countingStore) GetUserSettings(ctx context.Context, chatID int64) (*storage.UserSettings, error) {
+	c.lookups.Add(1)
+	return c.memoryStore.GetUserSettings(ctx, chatID)
+}
+
+func TestResolvePrinterOncePerUpdate(t *testing.T) {
+	b, db, _ := newTestBot(t)
+	counting := &countingStore{memoryStore: db}
+	b.storage = counting
+
+	from := &tgbotapi.User{ID: 7, LanguageCode: "en"}
+	chat := &tgbotapi.Chat{ID: -100, Type: "group"}
+	db.settings[chat.ID] = storage.UserSettings{ChatID: chat.ID, UserID: from.ID, Language: "ru"}
+	update := tgbotapi.Update{Message: &tgbotapi.Message{From: from, Chat: chat, Text: "hi"}}
+
+	var languages []string
+	handler := b.resolvePrinter(func(ctx context.Context, req *request) {
+		for i := 0; i < 3; i++ {
+			languages = append(languages, b.printer(ctx, chat.ID, from).Language())
+		}
+		lookups := counting.lookups.Load()
+		if lookups != 1 {
+			t.Errorf("settings looked up %d times for three replies; want once", lookups)
+		}
+
+		// Other chats still get their own language
+		if got := b.printer(ctx, from.ID, from).Language(); got != "en" {
+			t.Errorf("printer for the private chat = %s; want en", got)
+		}
+
+		if _, ok := b.setLanguage(ctx, chat.ID, from, "en"); !ok {
+			t.Fatal("setLanguage() failed")
+		}
+		languages = append(languages, b.printer(ctx, chat.ID, from).Language())
+	})
+	handler(context.Background(), b.newRequest(update))
+
+	want := []string{"ru", "ru", "ru", "en"}
+	if len(languages) != len(want) {
+		t.Fatalf("languages = %v; want %v", languages, want)
+	}
+	for i := range want {
+		if languages[i] != want[i] {
+			t.Errorf("languages = %v; want %v", languages, want)
+			break
+		}
+	}
+}
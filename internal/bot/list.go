@@ -0,0 +1,541 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	"github.com/dm-popov-sdg/nagger/internal/timezone"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	listPageSize         = 8
+	reminderPageSize     = 10
+	listDescriptionLimit = 200 // Runes of a description shown in the list text
+	buttonTextLimit      = 40  // Runes of a description shown on a button
+)
+
+// snoozeOptions are the snooze choices: days until the reminders resume
+var snoozeOptions = []struct {
+	label string // Message key
+	days  int
+}{
+	{"list.snooze_tomorrow", 1},
+	{"list.snooze_3_days", 3},
+	{"list.snooze_week", 7},
+}
+
+// listView is the state of a /list message, encoded in its callback data
+type listView struct {
+	page   int
+	openID string // Task whose action row is shown
+	snooze bool   // Show snooze choices instead of actions
+}
+
+func (b *Bot) handleList(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	tasks, shares, err := b.chatTasks(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting tasks: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("tasks.get_failed"))
+		return
+	}
+
+	if len(tasks) == 0 {
+		b.sendMessage(message.Chat.ID, p.T("list.empty"))
+		return
+	}
+
+	loc := b.chatLocation(ctx, message.Chat.ID)
+	members := b.chatMembers(ctx, message.Chat.ID)
+	b.refreshRotations(ctx, message.Chat.ID, tasks, members)
+	msg := tgbotapi.NewMessage(message.Chat.ID, listText(p, tasks, 0, loc, members, shareNames(shares)))
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = b.signedKeyboard(message.Chat.ID, listKeyboard(p, tasks, listView{}, members))
+	if _, err := b.sender.send(ctx, message.Chat.ID, priorityInteractive, msg); err != nil {
+		log.Printf("Error sending task list: %v", err)
+		b.handleSendError(ctx, message.Chat.ID, err)
+	}
+}
+
+// handleListCallback applies a /list button action and redraws the list in place.
+// Args: action, task ID, page; or "p", page for navigation.
+func (b *Bot) handleListCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) callbackAnswer {
+	if len(args) < 2 {
+		return answerStale
+	}
+	chatID := query.Message.Chat.ID
+	action := args[0]
+
+	page, err := strconv.Atoi(args[len(args)-1])
+	if err != nil || page < 0 {
+		return answerStale
+	}
+
+	view := listView{page: page}
+	answer := answerNone
+	if action != "p" {
+		if len(args) != 3 {
+			return answerStale
+		}
+
+		var task *storage.Task
+		task, answer = b.taskForCallback(ctx, query, args[1])
+		if task != nil {
+			var redraw bool
+			answer, redraw = b.applyListAction(ctx, query, task, action, &view)
+			if !redraw {
+				return answer
+			}
+		}
+		// A missing task was closed or deleted elsewhere, just redraw
+	}
+
+	tasks, shares, err := b.chatTasks(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting tasks: %v", err)
+		return answerError
+	}
+
+	p := b.printer(ctx, chatID, query.From)
+	var edit tgbotapi.EditMessageTextConfig
+	if len(tasks) == 0 {
+		edit = tgbotapi.NewEditMessageText(chatID, query.Message.MessageID, escapeHTML(p.T("list.empty")))
+	} else {
+		view.page = clampPage(view.page, len(tasks), listPageSize)
+		loc := b.chatLocation(ctx, chatID)
+		members := b.chatMembers(ctx, chatID)
+		b.refreshRotations(ctx, chatID, tasks, members)
+		keyboard := b.callbacks.signKeyboard(chatID, *listKeyboard(p, tasks, view, members))
+		edit = tgbotapi.NewEditMessageTextAndMarkup(chatID, query.Message.MessageID, listText(p, tasks, view.page, loc, members, shareNames(shares)), keyboard)
+	}
+	edit.ParseMode = tgbotapi.ModeHTML
+	edit.DisableWebPagePreview = true
+	if _, err := b.sender.send(ctx, chatID, priorityInteractive, edit); err != nil {
+		log.Printf("Error updating task list: %v", err)
+	}
+	return answer
+}
+
+// applyListAction performs a task action from the list and updates the view.
+// It returns the answer to show and whether the list should be redrawn.
+func (b *Bot) applyListAction(ctx context.Context, query *tgbotapi.CallbackQuery, task *storage.Task, action string, view *listView) (callbackAnswer, bool) {
+	chatID := query.Message.Chat.ID
+	id := task.ID.Hex()
+	p := b.printer(ctx, chatID, query.From)
+
+	if perm, ok := listActionPermission(action); ok {
+		if denied, ok := b.permitCallback(ctx, query, perm, task); !ok {
+			return denied, false
+		}
+	}
+
+	switch {
+	case action == "o":
+		// Tapping the open task again folds it
+		if !isOpen(query.Message, id) {
+			view.openID = id
+			// The ID to use in commands
+			return callbackAnswer{text: "#" + shortID(*task)}, true
+		}
+		return answerNone, true
+
+	case action == "d":
+		answer, err := b.toggleTask(ctx, p, task, query.From.ID)
+		if err != nil {
+			log.Printf("Error updating task: %v", err)
+			return answerError, false
+		}
+		view.openID = id
+		return answer, true
+
+	case action == "e":
+		data := map[string]string{"task_id": id}
+		b.promptUser(ctx, chatID, query.From, query.Message.MessageID, flowEdit, "description", data,
+			p.T("list.edit_prompt", task.Description), truncate(task.Description, buttonTextLimit))
+		return callbackAnswer{key: "list.edit_answer"}, false
+
+	case action == "s":
+		view.openID = id
+		view.snooze = true
+		return answerNone, true
+
+	case strings.HasPrefix(action, "z"):
+		days, err := strconv.Atoi(strings.TrimPrefix(action, "z"))
+		if err != nil || days < 0 {
+			return answerStale, false
+		}
+		loc := b.chatLocation(ctx, chatID)
+		var until *time.Time
+		answer := callbackAnswer{key: "list.resumed"}
+		if days > 0 {
+			t := startOfDay(time.Now().In(loc), days)
+			until = &t
+			answer = callbackAnswer{text: p.T("list.snoozed", p.Date(t))}
+		}
+		if err := b.storage.SnoozeTask(ctx, task.ID, until); err != nil {
+			log.Printf("Error snoozing task: %v", err)
+			return answerError, false
+		}
+		b.remindersChanged(ctx, task.ChatID)
+		view.openID = id
+		return answer, true
+
+	case action == "r":
+		priority := nextPriority(task.Priority)
+		if err := b.storage.SetTaskPriority(ctx, task.ID, priority); err != nil {
+			log.Printf("Error updating task priority: %v", err)
+			return answerError, false
+		}
+		b.remindersChanged(ctx, task.ChatID)
+		view.openID = id
+		return callbackAnswer{text: p.T("list.priority", priorityLabel(p, priority))}, true
+
+	case action == "c":
+		if err := b.storage.CloseTask(ctx, task.ID); err != nil {
+			log.Printf("Error closing task: %v", err)
+			return answerError, false
+		}
+		b.remindersChanged(ctx, task.ChatID)
+		return callbackAnswer{key: "list.closed"}, true
+	}
+
+	log.Printf("Unknown list callback: %s", query.Data)
+	return answerStale, false
+}
+
+// listActionPermission returns the permission a list action needs, if any
+func listActionPermission(action string) (permission, bool) {
+	switch {
+	case action == "d":
+		return permComplete, true
+	case action == "e", action == "s", action == "r", strings.HasPrefix(action, "z"):
+		return permEdit, true
+	case action == "c":
+		return permClose, true
+	}
+	return "", false
+}
+
+// toggleTask marks an active task as done for today, or a done task as active again.
+// Per-member tasks are toggled for the user only. It returns the answer to show.
+func (b *Bot) toggleTask(ctx context.Context, p *i18n.Printer, task *storage.Task, userID int64) (callbackAnswer, error) {
+	if task.Rotation != nil && task.Rotation.Period == storage.RotationOnCompletion {
+		next, err := b.completeTurn(ctx, task)
+		if err != nil {
+			return answerError, err
+		}
+		return callbackAnswer{text: p.T("list.done_next", memberNames(p, []int64{next}, b.chatMembers(ctx, task.ChatID)))}, nil
+	}
+	if !task.PerMember {
+		answer := callbackAnswer{key: "list.done_today"}
+		update := b.storage.CompleteTask
+		if task.Status == storage.TaskStatusCompletedToday {
+			answer = callbackAnswer{key: "list.undone"}
+			update = b.storage.ReactivateTask
+		}
+		if err := update(ctx, task.ID); err != nil {
+			return answerError, err
+		}
+		b.remindersChanged(ctx, task.ChatID)
+		if task.Status != storage.TaskStatusCompletedToday {
+			b.recordStreak(ctx, task.ChatID)
+		}
+		return answer, nil
+	}
+
+	if len(task.Assignees) > 0 && !task.IsAssignedTo(userID) {
+		return callbackAnswer{key: "list.not_assigned"}, nil
+	}
+	if task.IsCompletedBy(userID) {
+		if err := b.storage.ReactivateTaskForMember(ctx, task.ID, userID); err != nil {
+			return answerError, err
+		}
+		b.remindersChanged(ctx, task.ChatID)
+		return callbackAnswer{key: "list.undone"}, nil
+	}
+
+	done, total, err := b.completeForMember(ctx, task, userID)
+	if err != nil {
+		return answerError, err
+	}
+	return callbackAnswer{text: p.T("list.done_member", done, total)}, nil
+}
+
+// chatLocation returns the chat's timezone, or UTC if it can't be loaded
+func (b *Bot) chatLocation(ctx context.Context, chatID int64) *time.Location {
+	settings, err := b.getSettings(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		return time.UTC
+	}
+	loc, err := timezone.Load(settings.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// listText renders a page of the list in HTML. shared names the lists other chats shared
+// with this one.
+func listText(p *i18n.Printer, tasks []storage.Task, page int, loc *time.Location, members []storage.ChatMember, shared map[int64]string) string {
+	start, end := pageBounds(page, len(tasks), listPageSize)
+	now := time.Now()
+
+	var text strings.Builder
+	text.WriteString(formatHTML("📋 %s\n\n", bold(p.T("list.header", len(tasks)))))
+	for i := start; i < end; i++ {
+		task := tasks[i]
+		text.WriteString(formatHTML("%d. %s%s", i+1, priorityMark(task.Priority), taskHTML(task, listDescriptionLimit)))
+		if task.Status == storage.TaskStatusCompletedToday {
+			text.WriteString(" ✅")
+		} else if task.PerMember {
+			done, total := memberProgress(task, members)
+			text.WriteString(" " + escapeHTML(p.T("list.progress", done, total)))
+		}
+		if task.IsSnoozed(now) {
+			text.WriteString(" " + escapeHTML(p.T("list.snoozed_until", p.Date(task.SnoozedUntil.In(loc)))))
+		}
+		text.WriteString("\n")
+		if task.Source != nil {
+			text.WriteString(formatHTML("   📨 %s\n", sourceHTML(p, task.Source)))
+		}
+		if name, ok := shared[task.ChatID]; ok {
+			// Assignees of shared tasks are members of another chat
+			text.WriteString(formatHTML("   🔗 %s\n", name))
+		} else if task.Rotation != nil {
+			text.WriteString(formatHTML("   %s\n", rotationLine(p, *task.Rotation, members)))
+		} else if len(task.Assignees) > 0 {
+			text.WriteString(formatHTML("   👤 %s\n", memberNames(p, task.Assignees, members)))
+		}
+	}
+	text.WriteString("\n" + escapeHTML(p.T("list.hint")))
+	return text.String()
+}
+
+// taskHTML renders a task's description, struck through if it is done for today
+func taskHTML(task storage.Task, limit int) safeHTML {
+	description := truncate(task.Description, limit)
+	if task.Status == storage.TaskStatusCompletedToday {
+		return strike(description)
+	}
+	return safeHTML(escapeHTML(description))
+}
+
+func listKeyboard(p *i18n.Printer, tasks []storage.Task, view listView, members []storage.ChatMember) *tgbotapi.InlineKeyboardMarkup {
+	start, end := pageBounds(view.page, len(tasks), listPageSize)
+	page := strconv.Itoa(view.page)
+	now := time.Now()
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := start; i < end; i++ {
+		task := tasks[i]
+		id := task.ID.Hex()
+		status := "⬜"
+		if task.Status == storage.TaskStatusCompletedToday {
+			status = "✅"
+		}
+		label := fmt.Sprintf("%d. %s %s%s%s", i+1, status, progressMark(task, members), priorityMark(task.Priority), truncate(task.Description, buttonTextLimit))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, callbackData(callbackList, "o", id, page)),
+		))
+
+		if id != view.openID {
+			continue
+		}
+
+		if view.snooze {
+			var row []tgbotapi.InlineKeyboardButton
+			for _, option := range snoozeOptions {
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData(p.T(option.label), callbackData(callbackList, "z"+strconv.Itoa(option.days), id, page)))
+			}
+			if task.IsSnoozed(now) {
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData(p.T("list.wake_button"), callbackData(callbackList, "z0", id, page)))
+			}
+			rows = append(rows, row, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(p.T("list.back_button"), callbackData(callbackList, "o", id, page)),
+			))
+			continue
+		}
+
+		done := p.T("list.done_button")
+		if task.PerMember {
+			// Each member toggles their own completion
+			done = p.T("list.my_part_button")
+		} else if task.Status == storage.TaskStatusCompletedToday {
+			done = p.T("list.undo_button")
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(done, callbackData(callbackList, "d", id, page)),
+			tgbotapi.NewInlineKeyboardButtonData("✏️", callbackData(callbackList, "e", id, page)),
+			tgbotapi.NewInlineKeyboardButtonData("😴", callbackData(callbackList, "s", id, page)),
+			tgbotapi.NewInlineKeyboardButtonData(priorityLabel(p, nextPriority(task.Priority)), callbackData(callbackList, "r", id, page)),
+			tgbotapi.NewInlineKeyboardButtonData("🗑", callbackData(callbackList, "c", id, page)),
+		))
+	}
+
+	if nav := pageNavRow(view.page, len(tasks), listPageSize, func(n int) string {
+		return callbackData(callbackList, "p", strconv.Itoa(n))
+	}); nav != nil {
+		rows = append(rows, nav)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// reminderKeyboard lists one page of a daily reminder's tasks as completion toggles
+func reminderKeyboard(tasks []storage.Task, page int, members []storage.ChatMember) tgbotapi.InlineKeyboardMarkup {
+	start, end := pageBounds(page, len(tasks), reminderPageSize)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, task := range tasks[start:end] {
+		statusEmoji := "⬜"
+		if task.Status == storage.TaskStatusCompletedToday {
+			statusEmoji = "✅"
+		}
+		buttonText := fmt.Sprintf("%s %s%s", statusEmoji, progressMark(task, members), truncate(task.Description, buttonTextLimit))
+		buttonData := callbackData(callbackComplete, task.ID.Hex(), strconv.Itoa(page))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(buttonText, buttonData)))
+	}
+
+	if nav := pageNavRow(page, len(tasks), reminderPageSize, func(p int) string {
+		return callbackData(callbackReminderPage, strconv.Itoa(p))
+	}); nav != nil {
+		rows = append(rows, nav)
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// pageNavRow returns the ◀ n/m ▶ row, or nil if everything fits on one page.
+// pageData builds the callback data that opens a page.
+func pageNavRow(page, total, size int, pageData func(page int) string) []tgbotapi.InlineKeyboardButton {
+	pages := pageCount(total, size)
+	if pages <= 1 {
+		return nil
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("◀️", pageData(page-1)))
+	}
+	row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d", page+1, pages), callbackData(callbackNoop)))
+	if page < pages-1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("▶️", pageData(page+1)))
+	}
+	return row
+}
+
+func pageCount(total, size int) int {
+	if total == 0 {
+		return 1
+	}
+	return (total + size - 1) / size
+}
+
+// clampPage keeps page in range after the list shrank
+func clampPage(page, total, size int) int {
+	if last := pageCount(total, size) - 1; page > last {
+		return last
+	}
+	if page < 0 {
+		return 0
+	}
+	return page
+}
+
+// pageBounds returns the slice bounds of a page
+func pageBounds(page, total, size int) (int, int) {
+	page = clampPage(page, total, size)
+	start := page * size
+	end := start + size
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+// isOpen reports whether the list message currently shows the action row of the task
+func isOpen(message *tgbotapi.Message, id string) bool {
+	if message.ReplyMarkup == nil {
+		return false
+	}
+	for _, row := range message.ReplyMarkup.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData != nil && strings.HasPrefix(*button.CallbackData, callbackData(callbackList, "d", id)+callbackSeparator) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func findTask(tasks []storage.Task, idHex string) *storage.Task {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return nil
+	}
+	for i := range tasks {
+		if tasks[i].ID == id {
+			return &tasks[i]
+		}
+	}
+	return nil
+}
+
+func nextPriority(p storage.TaskPriority) storage.TaskPriority {
+	switch p {
+	case storage.TaskPriorityNormal:
+		return storage.TaskPriorityHigh
+	case storage.TaskPriorityHigh:
+		return storage.TaskPriorityLow
+	default:
+		return storage.TaskPriorityNormal
+	}
+}
+
+func priorityMark(p storage.TaskPriority) string {
+	switch p {
+	case storage.TaskPriorityHigh:
+		return "🔺 "
+	case storage.TaskPriorityLow:
+		return "🔻 "
+	default:
+		return ""
+	}
+}
+
+// priorityLabel names the button that switches a task to priority p
+func priorityLabel(p *i18n.Printer, priority storage.TaskPriority) string {
+	switch priority {
+	case storage.TaskPriorityHigh:
+		return p.T("list.priority_high")
+	case storage.TaskPriorityLow:
+		return p.T("list.priority_low")
+	default:
+		return p.T("list.priority_normal")
+	}
+}
+
+// startOfDay returns midnight days after t's day, in t's location
+func startOfDay(t time.Time, days int) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, 0, t.Location())
+}
+
+// truncate shortens s to at most limit runes, marking the cut with an ellipsis
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestPageBounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		page      int
+		total     int
+		wantStart int
+		wantEnd   int
+	}{
+		{name: "First page", page: 0, total: 20, wantStart: 0, wantEnd: 8},
+		{name: "Middle page", page: 1, total: 20, wantStart: 8, wantEnd: 16},
+		{name: "Last partial page", page: 2, total: 20, wantStart: 16, wantEnd: 20},
+		{name: "Page past the end after tasks were closed", page: 5, total: 9, wantStart: 8, wantEnd: 9},
+		{name: "Empty list", page: 0, total: 0, wantStart: 0, wantEnd: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := pageBounds(tt.page, tt.total, listPageSize)
+			if start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("pageBounds(%d, %d) = %d, %d; want %d, %d", tt.page, tt.total, start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestPageNavRow(t *testing.T) {
+	if row := pageNavRow(0, listPageSize, listPageSize, strconv.Itoa); row != nil {
+		t.Errorf("single page should have no navigation, got %d buttons", len(row))
+	}
+
+	tests := []struct {
+		page      int
+		wantTexts []string
+	}{
+		{page: 0, wantTexts: []string{"1/3", "▶️"}},
+		{page: 1, wantTexts: []string{"◀️", "2/3", "▶️"}},
+		{page: 2, wantTexts: []string{"◀️", "3/3"}},
+	}
+	for _, tt := range tests {
+		row := pageNavRow(tt.page, 20, listPageSize, strconv.Itoa)
+		if len(row) != len(tt.wantTexts) {
+			t.Fatalf("page %d: got %d buttons, want %d", tt.page, len(row), len(tt.wantTexts))
+		}
+		for i, button := range row {
+			if button.Text != tt.wantTexts[i] {
+				t.Errorf("page %d button %d = %q, want %q", tt.page, i, button.Text, tt.wantTexts[i])
+			}
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("Buy milk", 40); got != "Buy milk" {
+		t.Errorf("short text changed: %q", got)
+	}
+	if got := truncate("Купить молоко", 6); got != "Купит…" {
+		t.Errorf("truncate by runes = %q, want %q", got, "Купит…")
+	}
+}
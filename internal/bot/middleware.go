@@ -0,0 +1,325 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// User rate limit: a burst of userBurst updates, then one every userRefill
+const (
+	userBurst  = 20
+	userRefill = 2 * time.Second
+)
+
+// request is one incoming update on its way through the middleware chain
+type request struct {
+	update  tgbotapi.Update
+	route   string   // e.g. "/add", "message", "callback:l"; used for logs and metrics
+	command *command // Set for known commands
+	chatID  int64
+	userID  int64
+
+	panicked bool
+	limited  bool
+}
+
+// handlerFunc handles a request
+type handlerFunc func(ctx context.Context, req *request)
+
+// middleware wraps a handler with cross-cutting behaviour
+type middleware func(next handlerFunc) handlerFunc
+
+// chain wraps h so that the first middleware runs first
+func chain(h handlerFunc, middlewares ...middleware) handlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// newRequest works out who sent an update and which route handles it
+func (b *Bot) newRequest(update tgbotapi.Update) *request {
+	req := &request{update: update, route: "other"}
+
+	switch {
+	case update.Message != nil:
+		message := update.Message
+		req.chatID = message.Chat.ID
+		if message.From != nil {
+			req.userID = message.From.ID
+		}
+
+		switch {
+		case message.IsCommand():
+			if cmd, ok := b.commands[strings.ToLower(message.Command())]; ok {
+				req.command = cmd
+				req.route = "/" + cmd.name
+			} else {
+				req.route = "/unknown"
+			}
+		case message.Location != nil:
+			req.route = "location"
+		default:
+			req.route = "message"
+		}
+
+	case update.CallbackQuery != nil:
+		query := update.CallbackQuery
+		req.userID = query.From.ID
+		if query.Message != nil {
+			req.chatID = query.Message.Chat.ID
+		}
+		action := callbackAction(query.Data)
+		if _, ok := b.routes[action]; !ok && action != "legacy" {
+			// Keep arbitrary client data out of metric names
+			action = "unknown"
+		}
+		req.route = "callback:" + action
+	}
+
+	return req
+}
+
+// callbackAction extracts the action from callback data for logs, without verifying it
+func callbackAction(data string) string {
+	if strings.HasPrefix(data, legacyCompletePrefix) {
+		return "legacy"
+	}
+	parts := strings.SplitN(data, callbackSeparator, 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// recoverPanics keeps a panicking handler from taking the whole bot down
+func (b *Bot) recoverPanics(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) {
+		defer func() {
+			if r := recover(); r != nil {
+				req.panicked = true
+				log.Printf("Panic handling %s in chat %d: %v\n%s", req.route, req.chatID, r, debug.Stack())
+				if message := req.update.Message; message != nil {
+					b.sendMessage(req.chatID, b.printer(ctx, req.chatID, message.From).T("error.generic"))
+				}
+			}
+		}()
+		next(ctx, req)
+	}
+}
+
+// logRequests writes one log line per update
+func (b *Bot) logRequests(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) {
+		start := time.Now()
+		next(ctx, req)
+
+		log.Printf("Update %d: %s in chat %d from user %d took %s (panicked: %t, rate limited: %t)",
+			req.update.UpdateID, req.route, req.chatID, req.userID, time.Since(start).Round(time.Microsecond), req.panicked, req.limited)
+	}
+}
+
+// resolvePrinter looks up the language of the update's chat and user once, for all the
+// replies its handler sends
+func (b *Bot) resolvePrinter(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) {
+		var from *tgbotapi.User
+		switch {
+		case req.update.Message != nil:
+			from = req.update.Message.From
+		case req.update.CallbackQuery != nil:
+			from = req.update.CallbackQuery.From
+		}
+		if from != nil && req.chatID != 0 {
+			ctx = b.withPrinter(ctx, req.chatID, from)
+		}
+		next(ctx, req)
+	}
+}
+
+// authorize rejects admin commands from other users
+func (b *Bot) authorize(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) {
+		if req.command != nil && req.command.adminOnly && !b.isAdmin(req.update.Message.From) {
+			b.sendMessage(req.chatID, b.printer(ctx, req.chatID, req.update.Message.From).T("admin.only"))
+			return
+		}
+		next(ctx, req)
+	}
+}
+
+// routeStats are the counters of one route
+type routeStats struct {
+	count   int64
+	panics  int64
+	limited int64
+	total   time.Duration
+	max     time.Duration
+}
+
+// metrics records per-route timings
+type metrics struct {
+	mu     sync.Mutex
+	routes map[string]*routeStats
+}
+
+func newMetrics() *metrics {
+	return &metrics{routes: make(map[string]*routeStats)}
+}
+
+func (m *metrics) middleware(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) {
+		start := time.Now()
+		next(ctx, req)
+		m.record(req, time.Since(start))
+	}
+}
+
+func (m *metrics) record(req *request, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.routes[req.route]
+	if !ok {
+		stats = &routeStats{}
+		m.routes[req.route] = stats
+	}
+	stats.count++
+	stats.total += elapsed
+	if elapsed > stats.max {
+		stats.max = elapsed
+	}
+	if req.panicked {
+		stats.panics++
+	}
+	if req.limited {
+		stats.limited++
+	}
+}
+
+// report formats the counters, busiest route first
+func (m *metrics) report(p *i18n.Printer) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	routes := make([]string, 0, len(m.routes))
+	for route := range m.routes {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return m.routes[routes[i]].count > m.routes[routes[j]].count
+	})
+
+	var text strings.Builder
+	for _, route := range routes {
+		stats := m.routes[route]
+		avg := stats.total / time.Duration(stats.count)
+		text.WriteString(p.T("stats.route", route, stats.count, avg.Round(time.Millisecond), stats.max.Round(time.Millisecond)))
+		if stats.panics > 0 {
+			text.WriteString(p.N("stats.panics", int(stats.panics)))
+		}
+		if stats.limited > 0 {
+			text.WriteString(p.N("stats.limited", int(stats.limited)))
+		}
+		text.WriteString("\n")
+	}
+	return text.String()
+}
+
+// rateLimiter allows each user a burst of updates, then a steady trickle
+type rateLimiter struct {
+	mu        sync.Mutex
+	clock     clock
+	capacity  float64
+	rate      float64
+	users     map[int64]*tokenBucket
+	warned    map[int64]bool
+	lastPrune time.Time
+}
+
+func newRateLimiter(clk clock, capacity int, refill time.Duration) *rateLimiter {
+	return &rateLimiter{
+		clock:     clk,
+		capacity:  float64(capacity),
+		rate:      1 / refill.Seconds(),
+		users:     make(map[int64]*tokenBucket),
+		warned:    make(map[int64]bool),
+		lastPrune: clk.Now(),
+	}
+}
+
+// allow takes a token for the user. warn is true the first time a user is limited,
+// so they are told once instead of on every dropped update.
+func (rl *rateLimiter) allow(userID int64) (ok, warn bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.clock.Now()
+	rl.prune(now)
+
+	tb, exists := rl.users[userID]
+	if !exists {
+		tb = newTokenBucket(rl.capacity, rl.rate, now)
+		rl.users[userID] = tb
+	}
+	if tb.wait(now) > 0 {
+		warn = !rl.warned[userID]
+		rl.warned[userID] = true
+		return false, warn
+	}
+	tb.take(now)
+	delete(rl.warned, userID)
+	return true, false
+}
+
+// prune forgets users whose buckets are full again
+func (rl *rateLimiter) prune(now time.Time) {
+	if now.Sub(rl.lastPrune) < bucketIdleTimeout {
+		return
+	}
+	rl.lastPrune = now
+
+	for userID, tb := range rl.users {
+		if tb.full(now) {
+			delete(rl.users, userID)
+			delete(rl.warned, userID)
+		}
+	}
+}
+
+// limitRate drops updates from users who send too many. Admins are not limited.
+func (b *Bot) limitRate(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) {
+		if req.userID == 0 || b.isAdminID(req.userID) {
+			next(ctx, req)
+			return
+		}
+
+		ok, warn := b.limiter.allow(req.userID)
+		if ok {
+			next(ctx, req)
+			return
+		}
+
+		req.limited = true
+		if !warn {
+			return
+		}
+		if query := req.update.CallbackQuery; query != nil {
+			callback := tgbotapi.NewCallbackWithAlert(query.ID, b.printer(ctx, req.chatID, query.From).T("rate.limited"))
+			if _, err := b.api.Request(callback); err != nil {
+				log.Printf("Error answering callback: %v", err)
+			}
+		} else if message := req.update.Message; message != nil {
+			b.sendMessage(req.chatID, b.printer(ctx, req.chatID, message.From).T("rate.limited"))
+		}
+	}
+}
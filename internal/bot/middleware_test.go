@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) middleware {
+		return func(next handlerFunc) handlerFunc {
+			return func(ctx context.Context, req *request) {
+				calls = append(calls, name+" before")
+				next(ctx, req)
+				calls = append(calls, name+" after")
+			}
+		}
+	}
+
+	h := chain(func(context.Context, *request) { calls = append(calls, "handler") }, trace("outer"), trace("inner"))
+	h(context.Background(), &request{})
+
+	want := "outer before,inner before,handler,inner after,outer after"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("calls = %s; want %s", got, want)
+	}
+}
+
+func TestRecoverPanicsIsRecordedByMetrics(t *testing.T) {
+	b := &Bot{metrics: newMetrics()}
+	h := chain(func(context.Context, *request) { panic("boom") }, b.metrics.middleware, b.recoverPanics)
+
+	// A callback update, so the recovery doesn't try to send a message
+	req := &request{route: "callback:l", update: tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{}}}
+	h(context.Background(), req)
+
+	if !req.panicked {
+		t.Error("request was not marked as panicked")
+	}
+	if report := b.metrics.report(i18n.For("en")); !strings.Contains(report, "callback:l: 1,") || !strings.Contains(report, "1 panic") {
+		t.Errorf("report = %q; want one request with one panic", report)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	clk := newFakeClock()
+	rl := newRateLimiter(clk, 3, time.Second)
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := rl.allow(1); !ok {
+			t.Fatalf("update %d within the burst was limited", i+1)
+		}
+	}
+
+	if ok, warn := rl.allow(1); ok || !warn {
+		t.Errorf("first update over the limit = %v, %v; want limited with a warning", ok, warn)
+	}
+	if ok, warn := rl.allow(1); ok || warn {
+		t.Errorf("second update over the limit = %v, %v; want limited without a warning", ok, warn)
+	}
+	if ok, _ := rl.allow(2); !ok {
+		t.Error("another user was limited")
+	}
+
+	clk.Advance(time.Second)
+	if ok, _ := rl.allow(1); !ok {
+		t.Error("update after refill was limited")
+	}
+	if ok, warn := rl.allow(1); ok || !warn {
+		t.Errorf("limit after refill = %v, %v; want limited with a new warning", ok, warn)
+	}
+}
+
+func TestCallbackAction(t *testing.T) {
+	tests := map[string]string{
+		callbackData(callbackList, "d", "id", "0"): callbackList,
+		settingsCallbackPrefix + "main":            callbackSettings,
+		"complete_65a1b2c3d4e5f60718293a4b":        "legacy",
+		"garbage":                                  "",
+	}
+	for data, want := range tests {
+		if got := callbackAction(data); got != want {
+			t.Errorf("callbackAction(%q) = %q; want %q", data, got, want)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	"github.com/dm-popov-sdg/nagger/internal/timezone"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// startOnboarding begins the /start wizard, or resumes it where the chat left off
+func (b *Bot) startOnboarding(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	existing, err := b.storage.GetUserSettings(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("onboarding.start_failed"))
+		return
+	}
+
+	// Chats configured before the wizard existed have no step and count as set up
+	if existing != nil && (existing.Onboarding == storage.OnboardingDone || existing.Onboarding == "") {
+		b.sendMessage(message.Chat.ID, p.T("onboarding.welcome_back"))
+		return
+	}
+
+	settings, err := b.getSettings(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("onboarding.start_failed"))
+		return
+	}
+
+	if existing == nil {
+		settings.UserID = message.From.ID
+		settings.Onboarding = storage.OnboardingLanguage
+		if err := b.storage.SetUserSettings(ctx, settings); err != nil {
+			log.Printf("Error setting user settings: %v", err)
+			b.sendMessage(message.Chat.ID, p.T("onboarding.start_failed"))
+			return
+		}
+		b.sendMessage(message.Chat.ID, p.T("onboarding.welcome"))
+	} else {
+		b.sendMessage(message.Chat.ID, p.T("onboarding.resume"))
+	}
+
+	b.sendOnboardingStep(ctx, message.Chat, message.From, settings)
+}
+
+// sendOnboardingStep prompts for the chat's current wizard step
+func (b *Bot) sendOnboardingStep(ctx context.Context, chat *tgbotapi.Chat, from *tgbotapi.User, settings *storage.UserSettings) {
+	p := b.printer(ctx, chat.ID, from)
+	var msg tgbotapi.MessageConfig
+
+	switch settings.Onboarding {
+	case storage.OnboardingLanguage:
+		msg = tgbotapi.NewMessage(chat.ID, escapeHTML(p.T("onboarding.language")))
+		var row []tgbotapi.InlineKeyboardButton
+		for _, lang := range i18n.Languages() {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(lang.Label, onboardingCallbackPrefix+"lang_"+lang.Code))
+		}
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(row)
+
+	case storage.OnboardingTimezone:
+		if chat.IsPrivate() {
+			// Location requests only work in private chats and need a reply keyboard,
+			// which cannot share a message with the inline keyboard below
+			prompt := tgbotapi.NewMessage(chat.ID, escapeHTML(p.T("onboarding.location")))
+			keyboard := tgbotapi.NewReplyKeyboard(tgbotapi.NewKeyboardButtonRow(
+				tgbotapi.NewKeyboardButtonLocation(p.T("onboarding.location_button")),
+			))
+			keyboard.OneTimeKeyboard = true
+			keyboard.ResizeKeyboard = true
+			prompt.ReplyMarkup = keyboard
+			b.sendOnboardingMessage(ctx, prompt)
+
+			msg = tgbotapi.NewMessage(chat.ID, escapeHTML(p.T("onboarding.timezone_or_list")))
+		} else {
+			msg = tgbotapi.NewMessage(chat.ID, escapeHTML(p.T("onboarding.timezone")))
+		}
+		msg.ReplyMarkup = onboardingTimezoneKeyboard(p, settings)
+
+	case storage.OnboardingTime:
+		msg = tgbotapi.NewMessage(chat.ID, escapeHTML(p.T("onboarding.hour", settings.Timezone)))
+		msg.ReplyMarkup = hourPickerKeyboard(p, onboardingCallbackPrefix, onboardingCallbackPrefix+"tz")
+
+	case storage.OnboardingTask:
+		msg = tgbotapi.NewMessage(chat.ID, escapeHTML(p.T("onboarding.task")))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(p.T("onboarding.skip_button"), onboardingCallbackPrefix+"skip"),
+		))
+
+	default:
+		return
+	}
+
+	// The timezone and task steps accept typed answers
+	if settings.Onboarding == storage.OnboardingTimezone || settings.Onboarding == storage.OnboardingTask {
+		if err := b.startConversation(ctx, chat.ID, settings.UserID, flowOnboarding, string(settings.Onboarding), nil); err != nil {
+			log.Printf("Error starting conversation: %v", err)
+		}
+	}
+
+	b.sendOnboardingMessage(ctx, msg)
+}
+
+// sendOnboardingMessage sends a wizard message, its text in HTML
+func (b *Bot) sendOnboardingMessage(ctx context.Context, msg tgbotapi.MessageConfig) {
+	msg.ParseMode = tgbotapi.ModeHTML
+	switch keyboard := msg.ReplyMarkup.(type) {
+	case tgbotapi.InlineKeyboardMarkup:
+		msg.ReplyMarkup = b.callbacks.signKeyboard(msg.ChatID, keyboard)
+	case *tgbotapi.InlineKeyboardMarkup:
+		msg.ReplyMarkup = b.signedKeyboard(msg.ChatID, keyboard)
+	}
+	if _, err := b.sender.send(ctx, msg.ChatID, priorityInteractive, msg); err != nil {
+		log.Printf("Error sending onboarding message: %v", err)
+	}
+}
+
+func onboardingTimezoneKeyboard(p *i18n.Printer, settings *storage.UserSettings) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(p.T("onboarding.list_button"), onboardingCallbackPrefix+"tzlist"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(p.T("onboarding.keep_button", settings.Timezone), onboardingCallbackPrefix+"tzz_"+settings.Timezone),
+		),
+	)
+}
+
+// answerStepDone is shown for buttons of wizard steps the chat has moved past
+var answerStepDone = callbackAnswer{key: "onboarding.step_done"}
+
+// handleOnboardingCallback handles the wizard's inline buttons. Args: the wizard action.
+func (b *Bot) handleOnboardingCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) callbackAnswer {
+	if len(args) != 1 {
+		return answerStale
+	}
+	chat := query.Message.Chat
+	action := args[0]
+
+	if denied, ok := b.permitCallback(ctx, query, permSettings, nil); !ok {
+		return denied
+	}
+
+	settings, err := b.getSettings(ctx, chat.ID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		return answerError
+	}
+	p := b.printer(ctx, chat.ID, query.From)
+
+	switch {
+	case strings.HasPrefix(action, "lang_"):
+		if settings.Onboarding != storage.OnboardingLanguage {
+			return answerStepDone
+		}
+		settings.Language = i18n.Match(strings.TrimPrefix(action, "lang_"))
+		p = i18n.For(settings.Language)
+		replacePrinter(ctx, chat.ID, query.From, p)
+		b.editOnboardingMessage(ctx, query, escapeHTML(p.T("onboarding.language_done", i18n.Label(settings.Language))), nil)
+		b.advanceOnboarding(ctx, chat, query.From, settings, storage.OnboardingTimezone)
+
+	case action == "tz":
+		// Back from the hour picker to the timezone step
+		if settings.Onboarding != storage.OnboardingTime {
+			return answerStepDone
+		}
+		keyboard := onboardingTimezoneKeyboard(p, settings)
+		b.editOnboardingMessage(ctx, query, escapeHTML(p.T("onboarding.timezone_retry")), &keyboard)
+		settings.Onboarding = storage.OnboardingTimezone
+		if b.saveOnboarding(ctx, settings) {
+			if err := b.startConversation(ctx, chat.ID, settings.UserID, flowOnboarding, string(settings.Onboarding), nil); err != nil {
+				log.Printf("Error starting conversation: %v", err)
+			}
+		}
+
+	case action == "tzlist":
+		b.editOnboardingMessage(ctx, query, escapeHTML(p.T("onboarding.region")), regionKeyboard(p, onboardingCallbackPrefix, onboardingCallbackPrefix+"tzback"))
+
+	case action == "tzback":
+		keyboard := onboardingTimezoneKeyboard(p, settings)
+		b.editOnboardingMessage(ctx, query, escapeHTML(p.T("onboarding.timezone_retry")), &keyboard)
+
+	case strings.HasPrefix(action, "tzr_"):
+		region, page := parseRegionPage(strings.TrimPrefix(action, "tzr_"))
+		b.editOnboardingMessage(ctx, query, escapeHTML(p.T("onboarding.city", region)), zoneKeyboard(p, onboardingCallbackPrefix, region, page, onboardingCallbackPrefix+"tzlist"))
+
+	case strings.HasPrefix(action, "tzz_"):
+		if settings.Onboarding != storage.OnboardingTimezone {
+			return answerStepDone
+		}
+		tzName := strings.TrimPrefix(action, "tzz_")
+		if _, err := timezone.Load(tzName); err != nil {
+			log.Printf("Invalid timezone in onboarding callback: %s", tzName)
+			return answerStale
+		}
+		settings.Timezone = tzName
+		b.editOnboardingMessage(ctx, query, escapeHTML(p.T("onboarding.timezone_done", tzName)), nil)
+		b.advanceOnboarding(ctx, chat, query.From, settings, storage.OnboardingTime)
+
+	case strings.HasPrefix(action, "h_"):
+		hour, err := strconv.Atoi(strings.TrimPrefix(action, "h_"))
+		if err != nil || hour < 0 || hour > 23 {
+			return answerStale
+		}
+		if settings.Onboarding != storage.OnboardingTime {
+			return answerStepDone
+		}
+		settings.ReminderTime = fmt.Sprintf("%02d:00", hour)
+		b.saveOnboarding(ctx, settings)
+		b.editOnboardingMessage(ctx, query, escapeHTML(p.T("onboarding.minute", hour)),
+			minutePickerKeyboard(p, onboardingCallbackPrefix, onboardingCallbackPrefix+"hour"))
+
+	case action == "hour":
+		b.editOnboardingMessage(ctx, query, escapeHTML(p.T("onboarding.hour", settings.Timezone)),
+			hourPickerKeyboard(p, onboardingCallbackPrefix, onboardingCallbackPrefix+"tz"))
+
+	case strings.HasPrefix(action, "m_"):
+		minute, err := strconv.Atoi(strings.TrimPrefix(action, "m_"))
+		if err != nil || minute < 0 || minute > 59 {
+			return answerStale
+		}
+		if settings.Onboarding != storage.OnboardingTime {
+			return answerStepDone
+		}
+		settings.ReminderTime = fmt.Sprintf("%s:%02d", reminderHour(settings.ReminderTime), minute)
+		b.editOnboardingMessage(ctx, query, escapeHTML(p.T("onboarding.time_done", settings.ReminderTime, settings.Timezone)), nil)
+		b.advanceOnboarding(ctx, chat, query.From, settings, storage.OnboardingTask)
+
+	case action == "skip":
+		if settings.Onboarding != storage.OnboardingTask {
+			return answerStepDone
+		}
+		b.editOnboardingMessage(ctx, query, escapeHTML(p.T("onboarding.task_skipped")), nil)
+		b.finishOnboarding(ctx, chat.ID, query.From, settings)
+
+	default:
+		log.Printf("Unknown onboarding callback: %s", query.Data)
+		return answerStale
+	}
+	return answerNone
+}
+
+// continueOnboarding handles a message typed at the wizard's timezone or task step
+func (b *Bot) continueOnboarding(ctx context.Context, message *tgbotapi.Message, conv *storage.Conversation) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	settings, err := b.getSettings(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("onboarding.failed_continue"))
+		return
+	}
+
+	text := strings.TrimSpace(message.Text)
+	if text == "" || string(settings.Onboarding) != conv.Step {
+		b.sendMessage(message.Chat.ID, p.T("onboarding.text_only"))
+		return
+	}
+
+	switch settings.Onboarding {
+	case storage.OnboardingTimezone:
+		tzName, err := timezone.Parse(text)
+		if err != nil {
+			b.sendMessage(message.Chat.ID, invalidTimezoneText(p, text, err))
+			return
+		}
+		settings.Timezone = tzName
+		b.confirmOnboardingTimezone(ctx, p, message.Chat.ID, tzName)
+		b.advanceOnboarding(ctx, message.Chat, message.From, settings, storage.OnboardingTime)
+
+	case storage.OnboardingTask:
+		if !b.addTask(ctx, message, &storage.Task{Description: text}) {
+			return
+		}
+		b.finishOnboarding(ctx, message.Chat.ID, message.From, settings)
+	}
+}
+
+// handleOnboardingLocation continues the wizard after a location was shared at the timezone step
+func (b *Bot) handleOnboardingLocation(ctx context.Context, chat *tgbotapi.Chat, from *tgbotapi.User, settings *storage.UserSettings) bool {
+	if settings.Onboarding != storage.OnboardingTimezone {
+		return false
+	}
+
+	b.confirmOnboardingTimezone(ctx, b.printer(ctx, chat.ID, from), chat.ID, settings.Timezone)
+	b.advanceOnboarding(ctx, chat, from, settings, storage.OnboardingTime)
+	return true
+}
+
+// confirmOnboardingTimezone acknowledges the timezone and removes the location keyboard
+func (b *Bot) confirmOnboardingTimezone(ctx context.Context, p *i18n.Printer, chatID int64, tzName string) {
+	msg := tgbotapi.NewMessage(chatID, escapeHTML(p.T("onboarding.timezone_set", tzName)))
+	msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(false)
+	b.sendOnboardingMessage(ctx, msg)
+}
+
+func (b *Bot) advanceOnboarding(ctx context.Context, chat *tgbotapi.Chat, from *tgbotapi.User, settings *storage.UserSettings, step storage.OnboardingStep) {
+	b.endConversation(ctx, chat.ID, settings.UserID)
+	settings.Onboarding = step
+	if !b.saveOnboarding(ctx, settings) {
+		b.sendMessage(chat.ID, b.printer(ctx, chat.ID, from).T("onboarding.save_failed"))
+		return
+	}
+	b.sendOnboardingStep(ctx, chat, from, settings)
+}
+
+func (b *Bot) finishOnboarding(ctx context.Context, chatID int64, from *tgbotapi.User, settings *storage.UserSettings) {
+	b.endConversation(ctx, chatID, settings.UserID)
+	settings.Onboarding = storage.OnboardingDone
+	p := b.printer(ctx, chatID, from)
+	if !b.saveOnboarding(ctx, settings) {
+		b.sendMessage(chatID, p.T("onboarding.save_failed"))
+		return
+	}
+
+	b.sendMessage(chatID, p.T("onboarding.done", settings.ReminderTime, settings.Timezone))
+}
+
+func (b *Bot) saveOnboarding(ctx context.Context, settings *storage.UserSettings) bool {
+	if err := b.storage.SetUserSettings(ctx, settings); err != nil {
+		log.Printf("Error setting user settings: %v", err)
+		return false
+	}
+	return true
+}
+
+// editOnboardingMessage replaces a wizard message with HTML
+func (b *Bot) editOnboardingMessage(ctx context.Context, query *tgbotapi.CallbackQuery, text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	if err := b.editHTML(ctx, query.Message.Chat.ID, query.Message.MessageID, text, keyboard); err != nil {
+		log.Printf("Error updating onboarding message: %v", err)
+	}
+}
@@ -0,0 +1,314 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryStore keeps the settings, conversations, tasks and group members the tests touch.
+// Calls to other storage methods panic on the nil embedded store.
+type memoryStore struct {
+	store
+
+	mu       sync.Mutex
+	settings map[int64]storage.UserSettings
+	convs    map[[2]int64]storage.Conversation
+	tasks    []storage.Task
+	members  map[[2]int64]storage.ChatMember
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		settings: make(map[int64]storage.UserSettings),
+		convs:    make(map[[2]int64]storage.Conversation),
+		members:  make(map[[2]int64]storage.ChatMember),
+	}
+}
+
+func (m *memoryStore) GetUserSettings(ctx context.Context, chatID int64) (*storage.UserSettings, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	settings, ok := m.settings[chatID]
+	if !ok {
+		return nil, nil
+	}
+	return &settings, nil
+}
+
+func (m *memoryStore) SetUserSettings(ctx context.Context, settings *storage.UserSettings) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.settings[settings.ChatID] = *settings
+	return nil
+}
+
+func (m *memoryStore) GetConversation(ctx context.Context, chatID, userID int64) (*storage.Conversation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conv, ok := m.convs[[2]int64{chatID, userID}]
+	if !ok {
+		return nil, nil
+	}
+	return &conv, nil
+}
+
+func (m *memoryStore) SetConversation(ctx context.Context, conv *storage.Conversation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.convs[[2]int64{conv.ChatID, conv.UserID}] = *conv
+	return nil
+}
+
+func (m *memoryStore) DeleteConversation(ctx context.Context, chatID, userID int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := [2]int64{chatID, userID}
+	_, ok := m.convs[key]
+	delete(m.convs, key)
+	return ok, nil
+}
+
+func (m *memoryStore) AddTask(ctx context.Context, task *storage.Task) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tasks = append(m.tasks, *task)
+	return nil
+}
+
+func (m *memoryStore) GetTaskForChat(ctx context.Context, chatID int64, taskID primitive.ObjectID) (*storage.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, task := range m.tasks {
+		if task.ChatID == chatID && task.ID == taskID {
+			return &task, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetTasksForUser leaves out the groups the user has no member record in, like the real query
+func (m *memoryStore) GetTasksForUser(ctx context.Context, userID int64) ([]storage.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var tasks []storage.Task
+	for _, task := range m.tasks {
+		if task.UserID != userID && !task.IsAssignedTo(userID) {
+			continue
+		}
+		if _, ok := m.members[[2]int64{task.ChatID, userID}]; isGroupChat(task.ChatID) && !ok {
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func (m *memoryStore) GetChatMembers(ctx context.Context, chatID int64) ([]storage.ChatMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var members []storage.ChatMember
+	for key, member := range m.members {
+		if key[0] == chatID {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (m *memoryStore) UpsertChatMember(ctx context.Context, member *storage.ChatMember) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.members[[2]int64{member.ChatID, member.UserID}] = *member
+	return nil
+}
+
+func (m *memoryStore) RemoveChatMember(ctx context.Context, chatID, userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.members, [2]int64{chatID, userID})
+	return nil
+}
+
+func (m *memoryStore) GetListSubscribers(ctx context.Context, ownerChatID int64) ([]storage.ListShare, error) {
+	return nil, nil
+}
+
+// recordingAPI remembers the text and inline keyboard of every message sent or edited
+type recordingAPI struct {
+	mu       sync.Mutex
+	texts    []string
+	keyboard *tgbotapi.InlineKeyboardMarkup // Of the last message that had one
+}
+
+func (a *recordingAPI) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch msg := c.(type) {
+	case tgbotapi.MessageConfig:
+		a.texts = append(a.texts, msg.Text)
+		switch keyboard := msg.ReplyMarkup.(type) {
+		case tgbotapi.InlineKeyboardMarkup:
+			a.keyboard = &keyboard
+		case *tgbotapi.InlineKeyboardMarkup:
+			a.keyboard = keyboard
+		}
+	case tgbotapi.EditMessageTextConfig:
+		a.texts = append(a.texts, msg.Text)
+		a.keyboard = msg.ReplyMarkup
+	}
+	return tgbotapi.Message{MessageID: 1}, nil
+}
+
+func (a *recordingAPI) lastText() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.texts) == 0 {
+		return ""
+	}
+	return a.texts[len(a.texts)-1]
+}
+
+// button returns the callback data of the last keyboard's button whose action is action
+func (a *recordingAPI) button(t *testing.T, action string) string {
+	t.Helper()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.keyboard != nil {
+		for _, row := range a.keyboard.InlineKeyboard {
+			for _, button := range row {
+				if button.CallbackData != nil && *button.CallbackData == onboardingCallbackPrefix+action {
+					return *button.CallbackData
+				}
+			}
+		}
+	}
+	t.Fatalf("no %q button on the last keyboard %+v", action, a.keyboard)
+	return ""
+}
+
+func newTestBot(t *testing.T) (*Bot, *memoryStore, *recordingAPI) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	db := newMemoryStore()
+	api := &recordingAPI{}
+	b := &Bot{
+		storage:         db,
+		sender:          newSender(api, realClock{}, sendLimits{GlobalPerSecond: 1000, ChatPerSecond: 1000, GroupPerMinute: 1000}),
+		defaultTimezone: "UTC",
+		members:         newMemberCache(),
+		reminders:       newReminderRefresher(time.Hour, func(int64) {}),
+	}
+	b.routes = b.callbackRoutes()
+	go b.sender.run(ctx)
+	return b, db, api
+}
+
+func TestOnboardingWizard(t *testing.T) {
+	b, db, api := newTestBot(t)
+	ctx := context.Background()
+	en := i18n.For("en")
+
+	user := &tgbotapi.User{ID: 42, FirstName: "Ann"}
+	chat := &tgbotapi.Chat{ID: 42, Type: "private"}
+	db.settings[chat.ID] = storage.UserSettings{ChatID: chat.ID, UserID: user.ID, ReminderTime: "09:00", Timezone: "UTC", Onboarding: storage.OnboardingLanguage}
+
+	press := func(data string) {
+		t.Helper()
+		query := &tgbotapi.CallbackQuery{ID: "1", From: user, Data: data, Message: &tgbotapi.Message{MessageID: 1, Chat: chat}}
+		if answer := b.routeCallback(ctx, query); answer != answerNone {
+			t.Fatalf("pressing %q answered %+v", strings.TrimPrefix(data, onboardingCallbackPrefix), answer)
+		}
+	}
+	step := func() storage.OnboardingStep {
+		return db.settings[chat.ID].Onboarding
+	}
+
+	press(onboardingCallbackPrefix + "lang_en")
+	if step() != storage.OnboardingTimezone || db.settings[chat.ID].Language != "en" {
+		t.Fatalf("after the language: settings = %+v", db.settings[chat.ID])
+	}
+
+	press(api.button(t, "tzlist"))
+	press(api.button(t, "tzr_Europe"))
+	press(api.button(t, "tzr_Europe_1"))
+	if want := en.T("onboarding.city", "Europe"); api.lastText() != want {
+		t.Fatalf("city page text = %q; want %q", api.lastText(), want)
+	}
+
+	// Back from the city list returns to the regions while the timezone step is current
+	press(api.button(t, "tzlist"))
+	if want := en.T("onboarding.region"); api.lastText() != want {
+		t.Fatalf("after Back: text = %q; want %q", api.lastText(), want)
+	}
+	if step() != storage.OnboardingTimezone {
+		t.Fatalf("after Back: step = %q; want %q", step(), storage.OnboardingTimezone)
+	}
+
+	press(api.button(t, "tzr_Europe"))
+	press(api.button(t, "tzz_Europe/Berlin"))
+	if step() != storage.OnboardingTime || db.settings[chat.ID].Timezone != "Europe/Berlin" {
+		t.Fatalf("after the city: settings = %+v", db.settings[chat.ID])
+	}
+
+	press(api.button(t, "h_07"))
+	press(api.button(t, "m_30"))
+	if step() != storage.OnboardingTask || db.settings[chat.ID].ReminderTime != "07:30" {
+		t.Fatalf("after the time: settings = %+v", db.settings[chat.ID])
+	}
+
+	conv, _ := db.GetConversation(ctx, chat.ID, user.ID)
+	if conv == nil || conv.Flow != flowOnboarding || conv.Step != string(storage.OnboardingTask) {
+		t.Fatalf("conversation at the task step = %+v", conv)
+	}
+	b.continueOnboarding(ctx, &tgbotapi.Message{MessageID: 2, From: user, Chat: chat, Text: "Water the plants"}, conv)
+
+	if step() != storage.OnboardingDone {
+		t.Errorf("after the task: step = %q; want %q", step(), storage.OnboardingDone)
+	}
+	if len(db.tasks) != 1 || db.tasks[0].Description != "Water the plants" {
+		t.Errorf("tasks = %+v; want the first task", db.tasks)
+	}
+	if want := en.T("onboarding.done", "07:30", "Europe/Berlin"); api.lastText() != escapeHTML(want) {
+		t.Errorf("last message = %q; want %q", api.lastText(), escapeHTML(want))
+	}
+	if conv, _ := db.GetConversation(ctx, chat.ID, user.ID); conv != nil {
+		t.Errorf("conversation after finishing = %+v; want none", conv)
+	}
+}
+
+func TestOnboardingLocationGuess(t *testing.T) {
+	b, db, api := newTestBot(t)
+	ctx := context.Background()
+
+	user := &tgbotapi.User{ID: 42, FirstName: "Ann"}
+	chat := &tgbotapi.Chat{ID: 42, Type: "private"}
+	db.settings[chat.ID] = storage.UserSettings{ChatID: chat.ID, UserID: user.ID, ReminderTime: "09:00", Timezone: "UTC", Language: "en", Onboarding: storage.OnboardingTimezone}
+
+	// Oral is outside the boundary map, so its timezone is only a guess
+	b.handleLocation(ctx, &tgbotapi.Message{MessageID: 2, From: user, Chat: chat, Location: &tgbotapi.Location{Latitude: 51.2, Longitude: 51.4}})
+	if settings := db.settings[chat.ID]; settings.Onboarding != storage.OnboardingTimezone || settings.Timezone != "UTC" {
+		t.Fatalf("after sharing the location: settings = %+v; want them unchanged until confirmed", settings)
+	}
+	if text := api.lastText(); !strings.Contains(text, "Europe/Samara") {
+		t.Fatalf("guess text = %q; want it to name Europe/Samara", text)
+	}
+	api.button(t, "tzlist")
+
+	query := &tgbotapi.CallbackQuery{ID: "1", From: user, Data: api.button(t, "tzz_Europe/Samara"), Message: &tgbotapi.Message{MessageID: 3, Chat: chat}}
+	if answer := b.routeCallback(ctx, query); answer != answerNone {
+		t.Fatalf("confirming the guess answered %+v", answer)
+	}
+	if settings := db.settings[chat.ID]; settings.Onboarding != storage.OnboardingTime || settings.Timezone != "Europe/Samara" {
+		t.Errorf("after confirming: settings = %+v", settings)
+	}
+}
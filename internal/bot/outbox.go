@@ -0,0 +1,317 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// outboxPollInterval is how often the delivery worker looks for due messages
+	outboxPollInterval = time.Second
+	// outboxLockDuration is how long a claimed message stays locked to one worker
+	outboxLockDuration = 2 * time.Minute
+	// outboxSendTimeout is how long a claimed message waits for the sender, short enough
+	// for the message to be settled before its lock runs out
+	outboxSendTimeout = time.Minute
+	// outboxConcurrency is how many messages are handed to the sender at once
+	outboxConcurrency = 16
+	// outboxMaxAttempts is the number of failed deliveries after which a message is dead-lettered
+	outboxMaxAttempts = 8
+	outboxBaseBackoff = 5 * time.Second
+	outboxMaxBackoff  = time.Hour
+)
+
+// Outbox message kinds
+const (
+	outboxKindReminder = "reminder"
+	outboxKindDigest   = "digest"
+)
+
+// enqueueMessage stores a message in the outbox for delivery by the worker
+func (b *Bot) enqueueMessage(ctx context.Context, chatID int64, kind, text string, entities []tgbotapi.MessageEntity, markup *tgbotapi.InlineKeyboardMarkup) error {
+	return b.enqueue(ctx, &storage.OutboxMessage{ChatID: chatID, Kind: kind, Text: text}, entities, markup)
+}
+
+// enqueueHTML queues a message formatted with Telegram's HTML for delivery to a chat
+func (b *Bot) enqueueHTML(ctx context.Context, chatID int64, kind, html string, markup *tgbotapi.InlineKeyboardMarkup) error {
+	msg := &storage.OutboxMessage{ChatID: chatID, Kind: kind, Text: html, ParseMode: tgbotapi.ModeHTML}
+	return b.enqueue(ctx, msg, nil, markup)
+}
+
+func (b *Bot) enqueue(ctx context.Context, msg *storage.OutboxMessage, entities []tgbotapi.MessageEntity, markup *tgbotapi.InlineKeyboardMarkup) error {
+	if len(entities) > 0 {
+		data, err := json.Marshal(entities)
+		if err != nil {
+			return fmt.Errorf("failed to encode message entities: %w", err)
+		}
+		msg.Entities = string(data)
+	}
+
+	if markup != nil {
+		data, err := json.Marshal(markup)
+		if err != nil {
+			return fmt.Errorf("failed to encode reply markup: %w", err)
+		}
+		msg.ReplyMarkup = string(data)
+	}
+
+	return b.storage.EnqueueOutboxMessage(ctx, msg)
+}
+
+// runOutbox delivers queued messages until ctx is done
+func (b *Bot) runOutbox(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.drainOutbox(ctx)
+		}
+	}
+}
+
+func (b *Bot) drainOutbox(ctx context.Context) {
+	// Several messages are in flight at once so the sender can interleave
+	// chats instead of waiting out each chat's rate limit in turn
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	slots := make(chan struct{}, outboxConcurrency)
+
+	for ctx.Err() == nil {
+		slots <- struct{}{}
+
+		msg, err := b.storage.ClaimOutboxMessage(ctx, outboxLockDuration)
+		if err != nil {
+			log.Printf("Error claiming outbox message: %v", err)
+			return
+		}
+		if msg == nil {
+			return
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			b.deliverOutboxMessage(ctx, msg)
+		}()
+	}
+}
+
+// decodeOutboxMessage builds the Telegram message for a stored outbox message
+func decodeOutboxMessage(msg *storage.OutboxMessage) (tgbotapi.MessageConfig, error) {
+	out := tgbotapi.NewMessage(msg.ChatID, msg.Text)
+	out.ParseMode = msg.ParseMode
+	if msg.Entities != "" {
+		if err := json.Unmarshal([]byte(msg.Entities), &out.Entities); err != nil {
+			return out, fmt.Errorf("invalid message entities: %w", err)
+		}
+	}
+	if msg.ReplyMarkup != "" {
+		var markup tgbotapi.InlineKeyboardMarkup
+		if err := json.Unmarshal([]byte(msg.ReplyMarkup), &markup); err != nil {
+			return out, fmt.Errorf("invalid reply markup: %w", err)
+		}
+		out.ReplyMarkup = markup
+	}
+	return out, nil
+}
+
+// deliverOutboxMessage sends a claimed message and settles it: marks it sent, schedules
+// a retry or dead-letters it. It settles the message even after ctx is cancelled, so a
+// shutdown doesn't leave the message locked for outboxLockDuration.
+func (b *Bot) deliverOutboxMessage(ctx context.Context, msg *storage.OutboxMessage) {
+	ctx = context.WithoutCancel(ctx)
+	out, err := decodeOutboxMessage(msg)
+	if err != nil {
+		log.Printf("Invalid outbox message %s: %v", msg.ID.Hex(), err)
+		if err := b.storage.DeadLetterOutboxMessage(ctx, msg.ID, err.Error()); err != nil {
+			log.Printf("Error dead-lettering outbox message %s: %v", msg.ID.Hex(), err)
+		}
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, outboxSendTimeout)
+	sent, sendErr := b.sender.send(sendCtx, msg.ChatID, priorityBulk, out)
+	cancel()
+	if errors.Is(sendErr, context.DeadlineExceeded) || errors.Is(sendErr, errSenderStopped) {
+		// The message never got its turn; hand it straight back without using up an attempt
+		if err := b.storage.RetryOutboxMessage(ctx, msg.ID, time.Now(), sendErr.Error(), false); err != nil {
+			log.Printf("Error releasing outbox message %s: %v", msg.ID.Hex(), err)
+		}
+		return
+	}
+	if sendErr == nil {
+		if err := b.storage.MarkOutboxMessageSent(ctx, msg.ID); err != nil {
+			log.Printf("Error marking outbox message %s as sent: %v", msg.ID.Hex(), err)
+		}
+		if msg.Kind == outboxKindReminder {
+			b.trackReminder(ctx, msg.ChatID, sent)
+		}
+		return
+	}
+
+	delay, retry := outboxRetryDelay(sendErr, msg.Attempts)
+	if !retry {
+		log.Printf("Dead-lettering outbox message %s for chat %d after %d attempt(s): %v", msg.ID.Hex(), msg.ChatID, msg.Attempts, sendErr)
+		if err := b.storage.DeadLetterOutboxMessage(ctx, msg.ID, sendErr.Error()); err != nil {
+			log.Printf("Error dead-lettering outbox message %s: %v", msg.ID.Hex(), err)
+		}
+		b.handleSendError(ctx, msg.ChatID, sendErr)
+		return
+	}
+
+	log.Printf("Delivery of outbox message %s to chat %d failed, retrying in %s: %v", msg.ID.Hex(), msg.ChatID, delay, sendErr)
+	// Claiming the message counted an attempt; flood control gives it back
+	counted := !isFloodControl(sendErr)
+	if err := b.storage.RetryOutboxMessage(ctx, msg.ID, time.Now().Add(delay), sendErr.Error(), counted); err != nil {
+		log.Printf("Error rescheduling outbox message %s: %v", msg.ID.Hex(), err)
+	}
+}
+
+// isFloodControl reports whether Telegram refused a message because too many were sent.
+// That is not the message's fault, so it does not count against the attempts.
+func isFloodControl(err error) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests
+}
+
+// outboxRetryDelay decides when a failed delivery should be retried.
+// It returns false if the failure is permanent or the attempts are exhausted.
+func outboxRetryDelay(err error, attempts int) (time.Duration, bool) {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == http.StatusTooManyRequests:
+			if apiErr.RetryAfter > 0 {
+				return time.Duration(apiErr.RetryAfter) * time.Second, true
+			}
+			return outboxBackoff(attempts), true
+		case apiErr.Code >= 400 && apiErr.Code < 500:
+			// Bad request, blocked by the user, chat not found and so on
+			return 0, false
+		}
+	}
+
+	// Network errors and Telegram 5xx responses are transient
+	if attempts >= outboxMaxAttempts {
+		return 0, false
+	}
+	return outboxBackoff(attempts), true
+}
+
+// outboxBackoff returns the exponential backoff for the given attempt number
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return delay
+}
+
+func init() {
+	registerCommands(helpOutbox, (*Bot).outboxCommands)
+}
+
+// outboxCommands are the admin commands for undelivered messages
+func (b *Bot) outboxCommands() []*command {
+	return []*command{
+		{name: "outbox", adminOnly: true, handler: b.handleOutbox},
+		{name: "replay", usage: "<id|all>", adminOnly: true, handler: b.handleReplay},
+	}
+}
+
+func (b *Bot) handleOutbox(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	total, err := b.storage.CountDeadOutboxMessages(ctx)
+	if err != nil {
+		log.Printf("Error counting outbox messages: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("outbox.get_failed"))
+		return
+	}
+
+	if total == 0 {
+		b.sendMessage(message.Chat.ID, p.T("outbox.empty"))
+		return
+	}
+
+	messages, err := b.storage.GetDeadOutboxMessages(ctx, 10)
+	if err != nil {
+		log.Printf("Error getting outbox messages: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("outbox.get_failed"))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(p.T("outbox.header", total, len(messages)) + "\n\n")
+	for _, msg := range messages {
+		text.WriteString(p.T("outbox.entry", msg.ID.Hex(), msg.ChatID, msg.Kind, p.N("outbox.attempts", msg.Attempts),
+			msg.UpdatedAt.UTC().Format("2006-01-02 15:04 UTC"), msg.LastError) + "\n\n")
+	}
+	text.WriteString(p.T("outbox.hint"))
+
+	b.sendMessage(message.Chat.ID, text.String())
+}
+
+func (b *Bot) handleReplay(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	arg := strings.TrimSpace(message.CommandArguments())
+	if arg == "" {
+		b.sendMessage(message.Chat.ID, p.T("outbox.replay_usage"))
+		return
+	}
+
+	var id *primitive.ObjectID
+	if arg != "all" {
+		objectID, err := primitive.ObjectIDFromHex(arg)
+		if err != nil {
+			b.sendMessage(message.Chat.ID, p.T("outbox.invalid_id"))
+			return
+		}
+		id = &objectID
+	}
+
+	count, err := b.storage.ReplayOutboxMessages(ctx, id)
+	if err != nil {
+		log.Printf("Error replaying outbox messages: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("outbox.replay_failed"))
+		return
+	}
+
+	if count == 0 {
+		b.sendMessage(message.Chat.ID, p.T("outbox.no_match"))
+		return
+	}
+
+	b.sendMessage(message.Chat.ID, p.N("outbox.queued", int(count)))
+}
+
+func (b *Bot) isAdmin(user *tgbotapi.User) bool {
+	return user != nil && b.isAdminID(user.ID)
+}
+
+func (b *Bot) isAdminID(userID int64) bool {
+	for _, id := range b.adminIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 5 * time.Second},
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{8, 640 * time.Second},
+		{10, 2560 * time.Second},
+		{11, time.Hour},
+		{100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := outboxBackoff(tt.attempts); got != tt.want {
+			t.Errorf("outboxBackoff(%d) = %s; want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestOutboxRetryDelay(t *testing.T) {
+	network := errors.New("connection reset")
+	flood := &tgbotapi.Error{Code: http.StatusTooManyRequests, Message: "Too Many Requests", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 30}}
+	floodNoHint := &tgbotapi.Error{Code: http.StatusTooManyRequests, Message: "Too Many Requests"}
+	blocked := &tgbotapi.Error{Code: http.StatusForbidden, Message: "Forbidden: bot was blocked by the user"}
+	serverErr := &tgbotapi.Error{Code: http.StatusBadGateway, Message: "Bad Gateway"}
+
+	tests := []struct {
+		name      string
+		err       error
+		attempts  int
+		wantDelay time.Duration
+		wantRetry bool
+	}{
+		{"network error", network, 1, 5 * time.Second, true},
+		{"wrapped network error", fmt.Errorf("failed to send: %w", network), 3, 20 * time.Second, true},
+		{"server error", serverErr, 2, 10 * time.Second, true},
+		{"last attempt", serverErr, outboxMaxAttempts - 1, outboxBackoff(outboxMaxAttempts - 1), true},
+		{"attempts exhausted", serverErr, outboxMaxAttempts, 0, false},
+		{"blocked", blocked, 1, 0, false},
+		{"flood control", flood, 1, 30 * time.Second, true},
+		{"flood control without a hint", floodNoHint, 3, 20 * time.Second, true},
+		{"flood control after many attempts", flood, outboxMaxAttempts + 5, 30 * time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, retry := outboxRetryDelay(tt.err, tt.attempts)
+			if delay != tt.wantDelay || retry != tt.wantRetry {
+				t.Errorf("outboxRetryDelay() = %s, %v; want %s, %v", delay, retry, tt.wantDelay, tt.wantRetry)
+			}
+		})
+	}
+}
+
+// TestOutboxDeadLetter follows a message's attempts the way the worker counts them:
+// claiming adds one, and flood control gives it back
+func TestOutboxDeadLetter(t *testing.T) {
+	flood := &tgbotapi.Error{Code: http.StatusTooManyRequests, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 1}}
+	serverErr := &tgbotapi.Error{Code: http.StatusInternalServerError}
+
+	deliveries := func(failures []error) (int, bool) {
+		attempts := 0
+		for i, err := range failures {
+			attempts++
+			if _, retry := outboxRetryDelay(err, attempts); !retry {
+				return i + 1, true
+			}
+			if isFloodControl(err) {
+				attempts--
+			}
+		}
+		return len(failures), false
+	}
+
+	var errs []error
+	for i := 0; i < 20; i++ {
+		errs = append(errs, serverErr)
+	}
+	if n, dead := deliveries(errs); !dead || n != outboxMaxAttempts {
+		t.Errorf("server errors: dead-lettered = %v after %d deliveries; want true after %d", dead, n, outboxMaxAttempts)
+	}
+
+	// Flood control in between doesn't use up the attempts
+	errs = nil
+	for i := 0; i < outboxMaxAttempts-1; i++ {
+		errs = append(errs, flood, flood, serverErr)
+	}
+	if _, dead := deliveries(errs); dead {
+		t.Errorf("%d server errors among flood control were dead-lettered; want %d allowed", outboxMaxAttempts-1, outboxMaxAttempts)
+	}
+
+	if !isFloodControl(fmt.Errorf("send: %w", flood)) || isFloodControl(serverErr) {
+		t.Error("isFloodControl() misclassified an error")
+	}
+}
+
+// outboxOutcomes records how the worker settles claimed messages
+type outboxOutcomes struct {
+	store
+
+	mu      sync.Mutex
+	sent    int
+	retries []bool // Whether each retry counted an attempt
+}
+
+func (o *outboxOutcomes) MarkOutboxMessageSent(ctx context.Context, id primitive.ObjectID) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	o.sent++
+	return nil
+}
+
+func (o *outboxOutcomes) RetryOutboxMessage(ctx context.Context, id primitive.ObjectID, nextAttemptAt time.Time, lastError string, counted bool) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	o.retries = append(o.retries, counted)
+	return nil
+}
+
+// TestOutboxSettlesOnShutdown checks that messages claimed before a shutdown are
+// settled rather than left locked
+func TestOutboxSettlesOnShutdown(t *testing.T) {
+	msg := &storage.OutboxMessage{ID: primitive.NewObjectID(), ChatID: 42, Kind: outboxKindDigest, Text: "Digest", Attempts: 1}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("Sender running", func(t *testing.T) {
+		outcomes := &outboxOutcomes{}
+		b := &Bot{storage: outcomes, sender: newSender(&recordingAPI{}, realClock{}, sendLimits{GlobalPerSecond: 1000, ChatPerSecond: 1000, GroupPerMinute: 1000})}
+		runCtx, stop := context.WithCancel(context.Background())
+		defer stop()
+		go b.sender.run(runCtx)
+
+		b.deliverOutboxMessage(cancelled, msg)
+		if outcomes.sent != 1 || len(outcomes.retries) != 0 {
+			t.Errorf("sent %d, retried %v; want the message sent", outcomes.sent, outcomes.retries)
+		}
+	})
+
+	t.Run("Sender stopped", func(t *testing.T) {
+		outcomes := &outboxOutcomes{}
+		b := &Bot{storage: outcomes, sender: newSender(&recordingAPI{}, realClock{}, defaultSendLimits())}
+		runCtx, stop := context.WithCancel(context.Background())
+		stop()
+		b.sender.run(runCtx)
+
+		b.deliverOutboxMessage(cancelled, msg)
+		if outcomes.sent != 0 || len(outcomes.retries) != 1 || outcomes.retries[0] {
+			t.Errorf("sent %d, retried %v; want the message released without counting an attempt", outcomes.sent, outcomes.retries)
+		}
+	})
+}
@@ -0,0 +1,426 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatAdminsRefresh is how long the admins of a chat are cached. Promotions and
+// demotions take effect after at most this long.
+const chatAdminsRefresh = 10 * time.Minute
+
+// groupAnonymousBotID is the sender of messages from anonymous group admins
+const groupAnonymousBotID = 1087968824
+
+// permission is something group members may or may not be allowed to do
+type permission string
+
+const (
+	permComplete permission = "complete"
+	permEdit     permission = "edit"
+	permClose    permission = "close"
+	permSettings permission = "settings"
+)
+
+// permissionList is the order /permissions shows the permissions in
+var permissionList = []permission{permComplete, permEdit, permClose, permSettings}
+
+// role is what a member is to a task, from least to most privileged
+type role int
+
+const (
+	roleMember role = iota
+	roleOwner       // Created the task or is assigned to it
+	roleAdmin       // Administrator of the chat
+)
+
+// policy returns the chat's policy for the permission
+func policy(p storage.Permissions, perm permission) storage.PermissionPolicy {
+	p = p.WithDefaults()
+	switch perm {
+	case permComplete:
+		return p.Complete
+	case permEdit:
+		return p.Edit
+	case permClose:
+		return p.Close
+	default:
+		return p.Settings
+	}
+}
+
+// setPolicy returns the permissions with the permission's policy changed
+func setPolicy(p storage.Permissions, perm permission, pol storage.PermissionPolicy) storage.Permissions {
+	switch perm {
+	case permComplete:
+		p.Complete = pol
+	case permEdit:
+		p.Edit = pol
+	case permClose:
+		p.Close = pol
+	case permSettings:
+		p.Settings = pol
+	}
+	return p
+}
+
+// allows reports whether a member with the role may act under the policy
+func allows(pol storage.PermissionPolicy, r role) bool {
+	switch pol {
+	case storage.PolicyEveryone:
+		return true
+	case storage.PolicyOwners:
+		return r >= roleOwner
+	default:
+		return r >= roleAdmin
+	}
+}
+
+// nextPolicy cycles through the policies for the /permissions buttons
+func nextPolicy(pol storage.PermissionPolicy) storage.PermissionPolicy {
+	switch pol {
+	case storage.PolicyEveryone:
+		return storage.PolicyOwners
+	case storage.PolicyOwners:
+		return storage.PolicyAdmins
+	default:
+		return storage.PolicyEveryone
+	}
+}
+
+func parsePolicy(s string) (storage.PermissionPolicy, bool) {
+	switch strings.ToLower(s) {
+	case "everyone", "members", "all":
+		return storage.PolicyEveryone, true
+	case "owners", "owner", "creator", "creators":
+		return storage.PolicyOwners, true
+	case "admins", "admin":
+		return storage.PolicyAdmins, true
+	}
+	return "", false
+}
+
+func policyLabel(p *i18n.Printer, pol storage.PermissionPolicy) string {
+	switch pol {
+	case storage.PolicyEveryone:
+		return p.T("permissions.everyone")
+	case storage.PolicyOwners:
+		return p.T("permissions.owners")
+	default:
+		return p.T("permissions.admins")
+	}
+}
+
+func permissionLabel(p *i18n.Printer, perm permission) string {
+	switch perm {
+	case permComplete:
+		return p.T("permissions.complete")
+	case permEdit:
+		return p.T("permissions.edit")
+	case permClose:
+		return p.T("permissions.close")
+	default:
+		return p.T("permissions.settings")
+	}
+}
+
+// deniedText explains who may perform an action the user wasn't allowed to
+func deniedText(p *i18n.Printer, perm permission, pol storage.PermissionPolicy) string {
+	var action string
+	switch perm {
+	case permComplete:
+		action = p.T("permissions.action_complete")
+	case permEdit:
+		action = p.T("permissions.action_edit")
+	case permClose:
+		action = p.T("permissions.action_close")
+	default:
+		action = p.T("permissions.action_settings")
+	}
+	if pol == storage.PolicyOwners {
+		return p.T("permissions.denied_owners", action)
+	}
+	return p.T("permissions.denied_admins", action)
+}
+
+// chatAdmins caches the administrators of group chats
+type chatAdmins struct {
+	mu    sync.Mutex
+	chats map[int64]cachedAdmins
+}
+
+type cachedAdmins struct {
+	ids     map[int64]bool
+	fetched time.Time
+}
+
+func newChatAdmins() *chatAdmins {
+	return &chatAdmins{chats: make(map[int64]cachedAdmins)}
+}
+
+// get returns the cached admins of a chat, if they are fresh
+func (c *chatAdmins) get(chatID int64, now time.Time) (map[int64]bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.chats[chatID]
+	if !ok || now.Sub(cached.fetched) >= chatAdminsRefresh {
+		return nil, false
+	}
+	return cached.ids, true
+}
+
+func (c *chatAdmins) set(chatID int64, ids map[int64]bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.chats[chatID] = cachedAdmins{ids: ids, fetched: now}
+}
+
+// isChatAdmin reports whether the user administers the group chat.
+// If the admins can't be fetched the user is treated as a member.
+func (b *Bot) isChatAdmin(chatID, userID int64) bool {
+	now := time.Now()
+	admins, ok := b.chatAdmins.get(chatID, now)
+	if !ok {
+		members, err := b.api.GetChatAdministrators(tgbotapi.ChatAdministratorsConfig{
+			ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
+		})
+		if err != nil {
+			log.Printf("Error getting chat administrators: %v", err)
+			return false
+		}
+		admins = make(map[int64]bool, len(members))
+		for _, m := range members {
+			if m.User != nil {
+				admins[m.User.ID] = true
+			}
+		}
+		b.chatAdmins.set(chatID, admins, now)
+	}
+	return admins[userID]
+}
+
+// roleOf works out the user's role for a task, or for the chat if task is nil
+func (b *Bot) roleOf(chatID, userID int64, task *storage.Task) role {
+	if b.isChatAdmin(chatID, userID) {
+		return roleAdmin
+	}
+	if task != nil && (task.UserID == userID || task.IsAssignedTo(userID)) {
+		return roleOwner
+	}
+	return roleMember
+}
+
+// allowed reports whether the user may perform the action in the chat. Tasks of shared
+// lists need edit access, and everything else is allowed in private chats. If the action
+// isn't allowed, it returns the text that explains why.
+func (b *Bot) allowed(ctx context.Context, p *i18n.Printer, chatID, userID int64, perm permission, task *storage.Task) (bool, string) {
+	if task != nil && task.ChatID != chatID {
+		access, err := b.shareAccess(ctx, chatID, task.ChatID)
+		if err != nil {
+			log.Printf("Error getting list shares: %v", err)
+			return false, p.T(answerError.key)
+		}
+		if access != storage.ShareAccessEdit {
+			return false, p.T("permissions.view_only")
+		}
+	}
+	if perm == permComplete && task != nil && task.PerMember {
+		// Every member completes their own part
+		return true, ""
+	}
+	if chatID > 0 {
+		return true, ""
+	}
+
+	settings, err := b.getSettings(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		return false, p.T(answerError.key)
+	}
+	pol := policy(settings.Permissions, perm)
+	if pol == storage.PolicyEveryone {
+		// No need to ask Telegram for the admins
+		return true, ""
+	}
+	if !allows(pol, b.roleOf(chatID, userID, task)) {
+		return false, deniedText(p, perm, pol)
+	}
+	return true, ""
+}
+
+// permit checks a permission for the sender of a message and tells them if it's denied
+func (b *Bot) permit(ctx context.Context, message *tgbotapi.Message, perm permission, task *storage.Task) bool {
+	ok, denied := b.senderAllowed(ctx, message, perm, task)
+	if !ok && denied != "" {
+		b.sendMessage(message.Chat.ID, denied)
+	}
+	return ok
+}
+
+// senderAllowed is allowed for the sender of a message. If nobody can be told why the
+// action isn't allowed, the text is empty.
+func (b *Bot) senderAllowed(ctx context.Context, message *tgbotapi.Message, perm permission, task *storage.Task) (bool, string) {
+	// Anonymous admins post as the group itself
+	if message.SenderChat != nil && message.SenderChat.ID == message.Chat.ID {
+		return true, ""
+	}
+	if message.From == nil || message.From.ID == groupAnonymousBotID {
+		return false, ""
+	}
+	return b.allowed(ctx, b.printer(ctx, message.Chat.ID, message.From), message.Chat.ID, message.From.ID, perm, task)
+}
+
+// permitCallback checks a permission for the user who pressed a button. If it's denied,
+// it returns the alert to answer with.
+func (b *Bot) permitCallback(ctx context.Context, query *tgbotapi.CallbackQuery, perm permission, task *storage.Task) (callbackAnswer, bool) {
+	p := b.printer(ctx, query.Message.Chat.ID, query.From)
+	ok, denied := b.allowed(ctx, p, query.Message.Chat.ID, query.From.ID, perm, task)
+	if !ok {
+		return callbackAnswer{text: denied, alert: true}, false
+	}
+	return answerNone, true
+}
+
+func init() {
+	registerCommands(helpPermissions, (*Bot).permissionCommands)
+}
+
+// permissionCommands are the commands for who may do what in a group
+func (b *Bot) permissionCommands() []*command {
+	return []*command{
+		{name: "permissions", usage: "[<action> <everyone|owners|admins>]", handler: b.handlePermissions},
+	}
+}
+
+// handlePermissions shows who may do what in a group, or changes it: /permissions <action> <everyone|owners|admins>
+func (b *Bot) handlePermissions(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	if !isGroup(message.Chat) {
+		b.sendMessage(message.Chat.ID, p.T("permissions.groups_only"))
+		return
+	}
+
+	settings, err := b.getSettings(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("settings.get_failed"))
+		return
+	}
+
+	fields := strings.Fields(message.CommandArguments())
+	if len(fields) > 0 {
+		if len(fields) != 2 {
+			b.sendMessage(message.Chat.ID, p.T("permissions.usage"))
+			return
+		}
+		perm := permission(strings.ToLower(fields[0]))
+		pol, ok := parsePolicy(fields[1])
+		if !ok || !knownPermission(perm) {
+			b.sendMessage(message.Chat.ID, p.T("permissions.usage"))
+			return
+		}
+		if !b.permitPermissionsChange(p, message) {
+			return
+		}
+
+		settings.Permissions = setPolicy(settings.Permissions, perm, pol)
+		if err := b.storage.SetUserSettings(ctx, settings); err != nil {
+			log.Printf("Error setting user settings: %v", err)
+			b.sendMessage(message.Chat.ID, p.T("settings.save_failed"))
+			return
+		}
+		b.sendMessage(message.Chat.ID, fmt.Sprintf("%s: %s", permissionLabel(p, perm), policyLabel(p, pol)))
+		return
+	}
+
+	if _, err := b.sendHTMLMarkup(ctx, message.Chat.ID, permissionsText(p, settings.Permissions), b.signedKeyboard(message.Chat.ID, permissionsKeyboard(p, settings.Permissions))); err != nil {
+		log.Printf("Error sending permissions: %v", err)
+		b.handleSendError(ctx, message.Chat.ID, err)
+	}
+}
+
+// permitPermissionsChange lets only chat admins change permissions, whatever the
+// settings policy says, so members can't lock the admins out
+func (b *Bot) permitPermissionsChange(p *i18n.Printer, message *tgbotapi.Message) bool {
+	if message.SenderChat != nil && message.SenderChat.ID == message.Chat.ID {
+		return true
+	}
+	if message.From != nil && b.isChatAdmin(message.Chat.ID, message.From.ID) {
+		return true
+	}
+	b.sendMessage(message.Chat.ID, p.T("permissions.admins_only"))
+	return false
+}
+
+// handlePermissionsCallback moves a permission to the next policy. Args: permission.
+func (b *Bot) handlePermissionsCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) callbackAnswer {
+	if len(args) != 1 || !knownPermission(permission(args[0])) {
+		return answerStale
+	}
+	chatID := query.Message.Chat.ID
+	perm := permission(args[0])
+
+	if !b.isChatAdmin(chatID, query.From.ID) {
+		return callbackAnswer{key: "permissions.admins_only", alert: true}
+	}
+
+	settings, err := b.getSettings(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		return answerError
+	}
+	pol := nextPolicy(policy(settings.Permissions, perm))
+	settings.Permissions = setPolicy(settings.Permissions, perm, pol)
+	if err := b.storage.SetUserSettings(ctx, settings); err != nil {
+		log.Printf("Error setting user settings: %v", err)
+		return callbackAnswer{key: "settings.save_failed", alert: true}
+	}
+
+	p := b.printer(ctx, chatID, query.From)
+	if err := b.editHTML(ctx, chatID, query.Message.MessageID, permissionsText(p, settings.Permissions), permissionsKeyboard(p, settings.Permissions)); err != nil {
+		log.Printf("Error updating permissions message: %v", err)
+	}
+	return callbackAnswer{key: "settings.saved_short"}
+}
+
+func knownPermission(perm permission) bool {
+	for _, p := range permissionList {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionsText lists the policy of each permission in HTML
+func permissionsText(p *i18n.Printer, perms storage.Permissions) string {
+	var text strings.Builder
+	text.WriteString(formatHTML("🔐 %s\n\n", bold(p.T("permissions.title"))))
+	for _, perm := range permissionList {
+		text.WriteString(formatHTML("%s: %s\n", permissionLabel(p, perm), policyLabel(p, policy(perms, perm))))
+	}
+	text.WriteString("\n" + escapeHTML(p.T("permissions.hint")))
+	return text.String()
+}
+
+func permissionsKeyboard(p *i18n.Printer, perms storage.Permissions) *tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, perm := range permissionList {
+		label := fmt.Sprintf("%s: %s", permissionLabel(p, perm), policyLabel(p, policy(perms, perm)))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, callbackData(callbackPermissions, string(perm))),
+		))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+)
+
+func TestPolicyAllows(t *testing.T) {
+	custom := storage.Permissions{Complete: storage.PolicyOwners, Close: "unknown"}
+
+	tests := []struct {
+		name        string
+		permissions storage.Permissions
+		perm        permission
+		role        role
+		want        bool
+	}{
+		{name: "Default: members complete", perm: permComplete, role: roleMember, want: true},
+		{name: "Default: members can't close", perm: permClose, role: roleMember, want: false},
+		{name: "Default: creator closes", perm: permClose, role: roleOwner, want: true},
+		{name: "Default: owners can't change settings", perm: permSettings, role: roleOwner, want: false},
+		{name: "Default: admins change settings", perm: permSettings, role: roleAdmin, want: true},
+		{name: "Custom: members can't complete", permissions: custom, perm: permComplete, role: roleMember, want: false},
+		{name: "Custom: assignee completes", permissions: custom, perm: permComplete, role: roleOwner, want: true},
+		{name: "Custom: unknown policy falls back to the default", permissions: custom, perm: permClose, role: roleOwner, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allows(policy(tt.permissions, tt.perm), tt.role); got != tt.want {
+				t.Errorf("allows() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListActionPermission(t *testing.T) {
+	tests := []struct {
+		action    string
+		want      permission
+		wantCheck bool
+	}{
+		{action: "o"},
+		{action: "d", want: permComplete, wantCheck: true},
+		{action: "e", want: permEdit, wantCheck: true},
+		{action: "z3", want: permEdit, wantCheck: true},
+		{action: "r", want: permEdit, wantCheck: true},
+		{action: "c", want: permClose, wantCheck: true},
+	}
+
+	for _, tt := range tests {
+		perm, check := listActionPermission(tt.action)
+		if check != tt.wantCheck || (check && perm != tt.want) {
+			t.Errorf("listActionPermission(%q) = %q, %v; want %q, %v", tt.action, perm, check, tt.want, tt.wantCheck)
+		}
+	}
+}
+
+func TestChatAdminsExpire(t *testing.T) {
+	c := newChatAdmins()
+	now := time.Now()
+
+	if _, ok := c.get(-1, now); ok {
+		t.Fatal("unknown chat should not be cached")
+	}
+	c.set(-1, map[int64]bool{7: true}, now)
+	if admins, ok := c.get(-1, now.Add(time.Minute)); !ok || !admins[7] {
+		t.Error("admins should be cached")
+	}
+	if _, ok := c.get(-1, now.Add(chatAdminsRefresh)); ok {
+		t.Error("admins should be fetched again after the refresh interval")
+	}
+}
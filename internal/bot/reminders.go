@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// reminderRefreshDelay is how long a chat's reminders wait for more changes before
+	// they are re-rendered, so completing several tasks in a row edits them once
+	reminderRefreshDelay = 2 * time.Second
+	// reminderRefreshTimeout bounds one re-render of a chat's reminders
+	reminderRefreshTimeout = 30 * time.Second
+)
+
+// reminderRefresher coalesces the re-renders of each chat's live reminders
+type reminderRefresher struct {
+	mu      sync.Mutex
+	pending map[int64]bool
+	delay   time.Duration
+	render  func(chatID int64)
+}
+
+func newReminderRefresher(delay time.Duration, render func(chatID int64)) *reminderRefresher {
+	return &reminderRefresher{
+		pending: make(map[int64]bool),
+		delay:   delay,
+		render:  render,
+	}
+}
+
+// schedule re-renders the chat's reminders after the delay, unless that's already planned
+func (r *reminderRefresher) schedule(chatID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pending[chatID] {
+		return
+	}
+	r.pending[chatID] = true
+	time.AfterFunc(r.delay, func() {
+		// Changes made while rendering schedule another render
+		r.mu.Lock()
+		delete(r.pending, chatID)
+		r.mu.Unlock()
+
+		r.render(chatID)
+	})
+}
+
+// remindersChanged re-renders today's reminders of the chat, and of the chats its tasks
+// are shared with, once the changes settle
+func (b *Bot) remindersChanged(ctx context.Context, chatID int64) {
+	b.reminders.schedule(chatID)
+
+	subscribers, err := b.storage.GetListSubscribers(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting list shares: %v", err)
+		return
+	}
+	for _, share := range subscribers {
+		b.reminders.schedule(share.ChatID)
+	}
+}
+
+// trackReminder remembers a daily reminder sent to a chat so it can be kept up to date
+func (b *Bot) trackReminder(ctx context.Context, chatID int64, sent tgbotapi.Message) {
+	if sent.Chat != nil && sent.Chat.Title != "" {
+		// Remembered for digests
+		if err := b.storage.SetChatTitle(ctx, chatID, sent.Chat.Title); err != nil {
+			log.Printf("Error saving title of chat %d: %v", chatID, err)
+		}
+	}
+	b.rememberChatName(ctx, sent.Chat)
+
+	reminder := &storage.ReminderMessage{
+		ChatID:    chatID,
+		MessageID: sent.MessageID,
+		Day:       reminderDay(time.Now(), b.chatLocation(ctx, chatID)),
+	}
+	if err := b.storage.AddReminderMessage(ctx, reminder); err != nil {
+		log.Printf("Error saving reminder message for chat %d: %v", chatID, err)
+	}
+}
+
+// renderReminders updates the keyboards of the chat's reminders sent today
+func (b *Bot) renderReminders(chatID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), reminderRefreshTimeout)
+	defer cancel()
+
+	day := reminderDay(time.Now(), b.chatLocation(ctx, chatID))
+	reminders, err := b.storage.GetReminderMessages(ctx, chatID, day)
+	if err != nil {
+		log.Printf("Error getting reminder messages: %v", err)
+		return
+	}
+	if len(reminders) == 0 {
+		return
+	}
+
+	tasks, members, err := b.reminderTasks(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting updated tasks: %v", err)
+		return
+	}
+	for _, reminder := range reminders {
+		b.editReminder(ctx, chatID, reminder.MessageID, reminder.Page, reminder.KeyboardHash, tasks, members)
+	}
+}
+
+// reminderTasks returns the tasks a chat's reminders show: all but the snoozed ones
+func (b *Bot) reminderTasks(ctx context.Context, chatID int64) ([]storage.Task, []storage.ChatMember, error) {
+	allTasks, _, err := b.chatTasks(ctx, chatID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	var tasks []storage.Task
+	for _, t := range allTasks {
+		if !t.IsSnoozed(now) {
+			tasks = append(tasks, t)
+		}
+	}
+
+	members := b.chatMembers(ctx, chatID)
+	b.refreshRotations(ctx, chatID, tasks, members)
+	return tasks, members, nil
+}
+
+// editReminder shows the tasks on a reminder's keyboard at the given page. The edit is
+// skipped if the reminder already shows that keyboard, as Telegram rejects edits that
+// change nothing; pass an empty shownHash to edit anyway.
+func (b *Bot) editReminder(ctx context.Context, chatID int64, messageID, page int, shownHash string, tasks []storage.Task, members []storage.ChatMember) {
+	page = clampPage(page, len(tasks), reminderPageSize)
+	keyboard := b.callbacks.signKeyboard(chatID, reminderKeyboard(tasks, page, members))
+	keyboardHash := hashKeyboard(keyboard)
+	if keyboardHash == shownHash {
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, keyboard)
+	_, err := b.sender.send(ctx, chatID, priorityInteractive, edit)
+	switch {
+	case err == nil, isNotModified(err):
+	case isMessageGone(err):
+		if err := b.storage.DeleteReminderMessage(ctx, chatID, messageID); err != nil {
+			log.Printf("Error deleting reminder message: %v", err)
+		}
+		return
+	default:
+		log.Printf("Error updating message: %v", err)
+		return
+	}
+
+	if err := b.storage.UpdateReminderMessage(ctx, chatID, messageID, page, keyboardHash); err != nil {
+		log.Printf("Error updating reminder message: %v", err)
+	}
+}
+
+// reminderDay is the day a reminder belongs to in the chat's timezone
+func reminderDay(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02")
+}
+
+// hashKeyboard fingerprints a keyboard to tell whether an edit would change it
+func hashKeyboard(keyboard tgbotapi.InlineKeyboardMarkup) string {
+	data, _ := json.Marshal(keyboard)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
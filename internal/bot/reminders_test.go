@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestReminderRefresherCoalesces(t *testing.T) {
+	var mu sync.Mutex
+	renders := make(map[int64]int)
+	rendered := make(chan int64, 10)
+	r := newReminderRefresher(20*time.Millisecond, func(chatID int64) {
+		mu.Lock()
+		renders[chatID]++
+		mu.Unlock()
+		rendered <- chatID
+	})
+
+	for i := 0; i < 5; i++ {
+		r.schedule(-1)
+	}
+	r.schedule(2)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-rendered:
+		case <-time.After(time.Second):
+			t.Fatal("reminders were not rendered")
+		}
+	}
+	select {
+	case chatID := <-rendered:
+		t.Fatalf("chat %d rendered again", chatID)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mu.Lock()
+	if renders[-1] != 1 || renders[2] != 1 {
+		t.Errorf("renders = %v; want one per chat", renders)
+	}
+	mu.Unlock()
+
+	// A change after the render is rendered again
+	r.schedule(-1)
+	select {
+	case <-rendered:
+	case <-time.After(time.Second):
+		t.Fatal("later change was not rendered")
+	}
+}
+
+func TestReminderDay(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	at := time.Date(2024, time.March, 5, 20, 0, 0, 0, time.UTC)
+
+	if got := reminderDay(at, time.UTC); got != "2024-03-05" {
+		t.Errorf("reminderDay(UTC) = %q; want 2024-03-05", got)
+	}
+	if got := reminderDay(at, tokyo); got != "2024-03-06" {
+		t.Errorf("reminderDay(JST) = %q; want 2024-03-06", got)
+	}
+}
+
+func TestHashKeyboard(t *testing.T) {
+	keyboard := func(text string) tgbotapi.InlineKeyboardMarkup {
+		return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(text, "c|1|0"),
+		))
+	}
+
+	if hashKeyboard(keyboard("⬜ Buy milk")) != hashKeyboard(keyboard("⬜ Buy milk")) {
+		t.Error("equal keyboards should hash the same")
+	}
+	if hashKeyboard(keyboard("⬜ Buy milk")) == hashKeyboard(keyboard("✅ Buy milk")) {
+		t.Error("changed keyboards should hash differently")
+	}
+}
@@ -0,0 +1,420 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Messages are sent with Telegram's HTML parse mode. Text from users, like task
+// descriptions and names, must go through escapeHTML or the helpers below, which
+// escape their arguments.
+
+// safeHTML is HTML that formatHTML inserts as is
+type safeHTML string
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// escapeHTML escapes text so that Telegram shows it as written
+func escapeHTML(text string) string {
+	return htmlEscaper.Replace(text)
+}
+
+// formatHTML formats like fmt.Sprintf, escaping the format and all arguments except safeHTML
+func formatHTML(format string, args ...any) string {
+	escaped := make([]any, len(args))
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case safeHTML:
+			escaped[i] = string(arg)
+		case string:
+			escaped[i] = escapeHTML(arg)
+		case fmt.Stringer:
+			escaped[i] = escapeHTML(arg.String())
+		default:
+			// Numbers and the like need no escaping, and keep their verbs working
+			escaped[i] = arg
+		}
+	}
+	return fmt.Sprintf(escapeHTML(format), escaped...)
+}
+
+func bold(text string) safeHTML {
+	return safeHTML("<b>" + escapeHTML(text) + "</b>")
+}
+
+func italic(text string) safeHTML {
+	return safeHTML("<i>" + escapeHTML(text) + "</i>")
+}
+
+func strike(text string) safeHTML {
+	return safeHTML("<s>" + escapeHTML(text) + "</s>")
+}
+
+// link links text to url. Only http(s) URLs are linked; others are shown as text.
+func link(url, text string) safeHTML {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return safeHTML(escapeHTML(text))
+	}
+	return safeHTML(`<a href="` + escapeHTML(url) + `">` + escapeHTML(text) + "</a>")
+}
+
+var (
+	htmlAttribute = regexp.MustCompile(`^([a-zA-Z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')\s*`)
+	htmlEntity    = regexp.MustCompile(`^&(?:lt|gt|amp|quot|#[0-9]{1,7}|#x[0-9a-fA-F]{1,6});`)
+)
+
+// htmlTags are the tags Telegram supports
+var htmlTags = map[string]bool{
+	"b": true, "strong": true, "i": true, "em": true, "u": true, "ins": true,
+	"s": true, "strike": true, "del": true, "tg-spoiler": true, "span": true,
+	"a": true, "code": true, "pre": true, "blockquote": true,
+}
+
+// htmlToken is a tag, an entity or a character of a message in Telegram HTML
+type htmlToken struct {
+	raw   string // As written
+	tag   string // Name of a start or end tag, empty for text
+	end   bool   // End tag
+	text  string // Text Telegram shows
+	width int    // Length of text in UTF-16 code units, as Telegram counts it
+}
+
+// tokenizeHTML splits text into tokens, checking that it is HTML Telegram accepts:
+// supported tags, properly nested, and no stray < > or &
+func tokenizeHTML(text string) ([]htmlToken, error) {
+	var tokens []htmlToken
+	var open []string
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			end := strings.IndexByte(text[i:], '>')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed tag at character %d; write &lt; for a literal <", i)
+			}
+			raw := text[i : i+end+1]
+			i += end + 1
+
+			if name, ok := strings.CutPrefix(raw[1:len(raw)-1], "/"); ok {
+				name = strings.ToLower(strings.TrimSpace(name))
+				if len(open) == 0 || open[len(open)-1] != name {
+					return nil, fmt.Errorf("unexpected </%s>", name)
+				}
+				open = open[:len(open)-1]
+				tokens = append(tokens, htmlToken{raw: raw, tag: name, end: true})
+				continue
+			}
+			name, err := checkTag(raw[1 : len(raw)-1])
+			if err != nil {
+				return nil, err
+			}
+			open = append(open, name)
+			tokens = append(tokens, htmlToken{raw: raw, tag: name})
+
+		case '&':
+			raw := htmlEntity.FindString(text[i:])
+			if raw == "" {
+				return nil, fmt.Errorf("invalid entity at character %d; write &amp; for a literal &", i)
+			}
+			shown := html.UnescapeString(raw)
+			tokens = append(tokens, htmlToken{raw: raw, text: shown, width: len(utf16.Encode([]rune(shown)))})
+			i += len(raw)
+
+		case '>':
+			return nil, fmt.Errorf("stray > at character %d; write &gt; for a literal >", i)
+
+		default:
+			r, size := utf8.DecodeRuneInString(text[i:])
+			raw := text[i : i+size]
+			tokens = append(tokens, htmlToken{raw: raw, text: raw, width: utf16.RuneLen(r)})
+			i += size
+		}
+	}
+	if len(open) > 0 {
+		return nil, fmt.Errorf("<%s> is not closed", open[len(open)-1])
+	}
+	return tokens, nil
+}
+
+// checkTag checks the contents of a start tag and returns the tag's name
+func checkTag(tag string) (string, error) {
+	name, attrs, _ := strings.Cut(strings.TrimSpace(tag), " ")
+	name = strings.ToLower(name)
+	if !htmlTags[name] {
+		return "", fmt.Errorf("<%s> is not supported; use b, i, u, s, code, pre, a, blockquote or tg-spoiler", name)
+	}
+
+	values := make(map[string]string)
+	for attrs = strings.TrimSpace(attrs); attrs != ""; {
+		m := htmlAttribute.FindStringSubmatch(attrs)
+		if m == nil {
+			return "", fmt.Errorf("invalid attributes in <%s>", name)
+		}
+		values[strings.ToLower(m[1])] = m[2] + m[3]
+		attrs = attrs[len(m[0]):]
+	}
+
+	switch {
+	case name == "a" && values["href"] == "":
+		return "", fmt.Errorf("<a> needs an href")
+	case name == "span" && values["class"] != "tg-spoiler":
+		return "", fmt.Errorf(`<span> needs class="tg-spoiler"`)
+	}
+	return name, nil
+}
+
+// checkHTML checks that text is HTML Telegram accepts and returns the length of the
+// text Telegram shows
+func checkHTML(text string) (int, error) {
+	tokens, err := tokenizeHTML(text)
+	if err != nil {
+		return 0, err
+	}
+	return tokensWidth(tokens), nil
+}
+
+// htmlLength returns the length of the text Telegram shows for valid HTML
+func htmlLength(text string) int {
+	length, _ := checkHTML(text)
+	return length
+}
+
+// htmlPlainText returns the text Telegram shows for valid HTML
+func htmlPlainText(text string) string {
+	tokens, _ := tokenizeHTML(text)
+	var plain strings.Builder
+	for _, t := range tokens {
+		plain.WriteString(t.text)
+	}
+	return plain.String()
+}
+
+func tokensWidth(tokens []htmlToken) int {
+	width := 0
+	for _, t := range tokens {
+		width += t.width
+	}
+	return width
+}
+
+// Places to split a message, from least to most preferred
+const (
+	breakSpace = iota
+	breakLine
+	breakParagraph
+)
+
+// splitHTML splits valid HTML into messages that show at most limit characters each.
+// It splits between paragraphs, lines or words if it can, and never inside a tag or an
+// entity. Tags open at a split are closed at the end of one message and opened again
+// at the start of the next.
+func splitHTML(text string, limit int) []string {
+	tokens, err := tokenizeHTML(text)
+	if err != nil || tokensWidth(tokens) <= limit {
+		return []string{text}
+	}
+
+	var chunks []string
+	var open []htmlToken
+	for start := 0; start < len(tokens); {
+		cut, openAtCut := splitPoint(tokens, start, open, limit)
+		if chunk := renderChunk(open, tokens[start:cut], openAtCut); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		open, start = openAtCut, cut
+		// Telegram trims the messages anyway
+		for start < len(tokens) && tokens[start].tag == "" && isSpaceText(tokens[start].text) {
+			start++
+		}
+	}
+	return chunks
+}
+
+// splitPoint finds where the message starting at tokens[start] should end, and the
+// tags open there. open are the tags open at start.
+func splitPoint(tokens []htmlToken, start int, open []htmlToken, limit int) (int, []htmlToken) {
+	type candidate struct {
+		at    int
+		width int
+		open  []htmlToken
+	}
+	var breaks [breakParagraph + 1]*candidate
+
+	stack := append([]htmlToken(nil), open...)
+	width := 0
+	for i := start; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.tag != "" && !t.end:
+			stack = append(stack, t)
+		case t.tag != "":
+			stack = stack[:len(stack)-1]
+		default:
+			if width > 0 && width+t.width > limit {
+				// The most preferred break in the second half, else the latest one
+				var best *candidate
+				for kind := breakParagraph; kind >= breakSpace; kind-- {
+					c := breaks[kind]
+					if c == nil {
+						continue
+					}
+					if c.width >= limit/2 {
+						return c.at, c.open
+					}
+					if best == nil || c.at > best.at {
+						best = c
+					}
+				}
+				if best != nil {
+					return best.at, best.open
+				}
+				// No space to break at: cut the word, leaving start tags for the next message
+				for i > start && tokens[i-1].tag != "" && !tokens[i-1].end {
+					i--
+					stack = stack[:len(stack)-1]
+				}
+				return i, stack
+			}
+
+			width += t.width
+			kind := -1
+			switch {
+			case t.text == "\n" && i > start && tokens[i-1].text == "\n":
+				kind = breakParagraph
+			case t.text == "\n":
+				kind = breakLine
+			case isSpaceText(t.text):
+				kind = breakSpace
+			}
+			if kind >= 0 {
+				breaks[kind] = &candidate{at: i + 1, width: width, open: append([]htmlToken(nil), stack...)}
+			}
+		}
+	}
+	return len(tokens), stack
+}
+
+// renderChunk writes a message: the tags open at its start, its tokens without trailing
+// spaces, and end tags for the tags still open. It returns "" if the message shows nothing.
+func renderChunk(open, tokens []htmlToken, stillOpen []htmlToken) string {
+	end := len(tokens)
+	for end > 0 && tokens[end-1].tag == "" && isSpaceText(tokens[end-1].text) {
+		end--
+	}
+	tokens = tokens[:end]
+
+	visible := false
+	for _, t := range tokens {
+		if t.tag == "" && !isSpaceText(t.text) {
+			visible = true
+			break
+		}
+	}
+	if !visible {
+		return ""
+	}
+
+	var out strings.Builder
+	for _, t := range open {
+		out.WriteString(t.raw)
+	}
+	for _, t := range tokens {
+		out.WriteString(t.raw)
+	}
+	for i := len(stillOpen) - 1; i >= 0; i-- {
+		out.WriteString("</" + stillOpen[i].tag + ">")
+	}
+	return out.String()
+}
+
+func isSpaceText(text string) bool {
+	return strings.TrimSpace(text) == ""
+}
+
+// HTML returns the text as Telegram HTML, with mentions of members without a username
+// as links
+func (t *entityText) HTML() string {
+	units := utf16.Encode([]rune(t.String()))
+	escape := func(from, to int) string {
+		return escapeHTML(string(utf16.Decode(units[from:to])))
+	}
+
+	var out strings.Builder
+	pos := 0
+	for _, e := range t.entities {
+		out.WriteString(escape(pos, e.Offset))
+		pos = e.Offset + e.Length
+		if e.Type == "text_mention" && e.User != nil {
+			fmt.Fprintf(&out, `<a href="tg://user?id=%d">%s</a>`, e.User.ID, escape(e.Offset, pos))
+			continue
+		}
+		out.WriteString(escape(e.Offset, pos))
+	}
+	out.WriteString(escape(pos, len(units)))
+	return out.String()
+}
+
+// sendHTML sends a message formatted with Telegram's HTML, split into several messages
+// if it is too long
+func (b *Bot) sendHTML(ctx context.Context, chatID int64, text string) {
+	if _, err := b.sendHTMLMarkup(ctx, chatID, text, nil); err != nil {
+		log.Printf("Error sending message: %v", err)
+		b.handleSendError(ctx, chatID, err)
+	}
+}
+
+// sendHTMLMarkup sends a message formatted with Telegram's HTML like sendHTML, with the
+// reply markup on its last part. It returns the last part.
+func (b *Bot) sendHTMLMarkup(ctx context.Context, chatID int64, text string, markup any) (tgbotapi.Message, error) {
+	return b.sendChunks(ctx, chatID, splitHTML(text, messageTextLimit), markup)
+}
+
+// sendChunks sends the parts of a split HTML message, with the reply markup on the last
+// one, and returns the last one
+func (b *Bot) sendChunks(ctx context.Context, chatID int64, chunks []string, markup any) (tgbotapi.Message, error) {
+	var sent tgbotapi.Message
+	for i, chunk := range chunks {
+		msg := tgbotapi.NewMessage(chatID, chunk)
+		msg.ParseMode = tgbotapi.ModeHTML
+		if i == len(chunks)-1 {
+			msg.ReplyMarkup = markup
+		}
+		var err error
+		if sent, err = b.sender.send(ctx, chatID, priorityInteractive, msg); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// editHTML replaces the text of a message with HTML and its inline keyboard with a
+// signed one, or removes the keyboard if it is nil. Text too long for one message keeps
+// its first part in the message and sends the rest as new messages, the keyboard on the
+// last one.
+func (b *Bot) editHTML(ctx context.Context, chatID int64, messageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	chunks := splitHTML(text, messageTextLimit)
+
+	var edit tgbotapi.EditMessageTextConfig
+	if keyboard != nil && len(chunks) == 1 {
+		edit = tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, chunks[0], b.callbacks.signKeyboard(chatID, *keyboard))
+	} else {
+		edit = tgbotapi.NewEditMessageText(chatID, messageID, chunks[0])
+	}
+	edit.ParseMode = tgbotapi.ModeHTML
+	if _, err := b.sender.send(ctx, chatID, priorityInteractive, edit); err != nil {
+		return err
+	}
+	if len(chunks) == 1 {
+		return nil
+	}
+
+	_, err := b.sendChunks(ctx, chatID, chunks[1:], b.signedKeyboard(chatID, keyboard))
+	return err
+}
@@ -0,0 +1,251 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+)
+
+func TestFormatHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		args   []any
+		want   string
+	}{
+		{"escapes arguments", "Task: %s", []any{"<b>x</b> & y"}, "Task: &lt;b&gt;x&lt;/b&gt; &amp; y"},
+		{"escapes the format", "1 < %d", []any{2}, "1 &lt; 2"},
+		{"keeps safe HTML", "%s done", []any{strike("a<b")}, "<s>a&lt;b</s> done"},
+		{"links", "%s", []any{link("https://t.me/c/1?a=1&b=2", "post")}, `<a href="https://t.me/c/1?a=1&amp;b=2">post</a>`},
+		{"no javascript links", "%s", []any{link("javascript:alert(1)", "x")}, "x"},
+		{"quotes", "%s", []any{bold(`say "hi"`)}, "<b>say &quot;hi&quot;</b>"},
+	}
+
+	for _, tt := range tests {
+		got := formatHTML(tt.format, tt.args...)
+		if got != tt.want {
+			t.Errorf("%s: formatHTML() = %q; want %q", tt.name, got, tt.want)
+		}
+		if _, err := checkHTML(got); err != nil {
+			t.Errorf("%s: formatHTML() = %q is not valid: %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestCheckHTML(t *testing.T) {
+	tests := []struct {
+		text    string
+		length  int
+		wantErr bool
+	}{
+		{"plain text", 10, false},
+		{"<b>bold</b> and <i>italic</i>", 15, false},
+		{"<b><i>nested</i></b>", 6, false},
+		{`<a href="https://example.com">link</a>`, 4, false},
+		{`<span class="tg-spoiler">secret</span>`, 6, false},
+		{"Tom &amp; Jerry &lt;3", 14, false},
+		{"&#128293; streak", 9, false},
+		{"🔥 fire", 7, false},
+		{"<B>caps</B>", 4, false},
+		{"<b>unclosed", 0, true},
+		{"<b><i>crossed</b></i>", 0, true},
+		{"</b>", 0, true},
+		{"<div>block</div>", 0, true},
+		{"<a>no link</a>", 0, true},
+		{"<span>plain span</span>", 0, true},
+		{"Tom & Jerry", 0, true},
+		{"&nbsp;", 0, true},
+		{"1 < 2", 0, true},
+		{"2 > 1", 0, true},
+	}
+
+	for _, tt := range tests {
+		length, err := checkHTML(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkHTML(%q) error = %v; wantErr %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if err == nil && length != tt.length {
+			t.Errorf("checkHTML(%q) = %d; want %d", tt.text, length, tt.length)
+		}
+	}
+}
+
+func TestSplitHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{
+			name:  "fits",
+			text:  "<b>short</b>",
+			limit: 10,
+			want:  []string{"<b>short</b>"},
+		},
+		{
+			name:  "paragraphs",
+			text:  "first paragraph\n\nsecond one",
+			limit: 20,
+			want:  []string{"first paragraph", "second one"},
+		},
+		{
+			name:  "lines before words",
+			text:  "one two\nthree four",
+			limit: 12,
+			want:  []string{"one two", "three four"},
+		},
+		{
+			name:  "words",
+			text:  "alpha beta gamma",
+			limit: 11,
+			want:  []string{"alpha beta", "gamma"},
+		},
+		{
+			name:  "reopens tags",
+			text:  "<b>bold <i>words here</i></b> end",
+			limit: 12,
+			want:  []string{"<b>bold <i>words</i></b>", "<b><i>here</i></b> end"},
+		},
+		{
+			name:  "links",
+			text:  `<a href="https://example.com">a long link</a>`,
+			limit: 7,
+			want:  []string{`<a href="https://example.com">a long</a>`, `<a href="https://example.com">link</a>`},
+		},
+		{
+			name:  "keeps entities",
+			text:  "a&amp;b&amp;c",
+			limit: 2,
+			want:  []string{"a&amp;", "b&amp;", "c"},
+		},
+		{
+			name:  "cuts long words",
+			text:  "<b>abcdef</b>",
+			limit: 4,
+			want:  []string{"<b>abcd</b>", "<b>ef</b>"},
+		},
+		{
+			name:  "surrogate pairs",
+			text:  "🔥🔥🔥",
+			limit: 3,
+			want:  []string{"🔥", "🔥", "🔥"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := splitHTML(tt.text, tt.limit)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: splitHTML() = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// markupSeeds are descriptions with characters that mean something in HTML
+var markupSeeds = []string{
+	"Buy milk",
+	"<b>not bold</b>",
+	"Tom & Jerry",
+	"1 < 2 > 0",
+	"&amp; &lt; &#128293; &bogus;",
+	`<a href="javascript:alert(1)">click</a>`,
+	"</s></b><s>",
+	"<",
+	"&",
+	"\"quoted\" 'single'",
+	"🔥 emoji\nand newline",
+	"\xff invalid UTF-8",
+}
+
+// FuzzListText checks that any description is shown as written, without breaking the list
+func FuzzListText(f *testing.F) {
+	for _, seed := range markupSeeds {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+
+	f.Fuzz(func(t *testing.T, description string, done bool) {
+		if !utf8.ValidString(description) {
+			t.Skip("Telegram only sends valid UTF-8")
+		}
+		status := storage.TaskStatusActive
+		if done {
+			status = storage.TaskStatusCompletedToday
+		}
+		tasks := []storage.Task{{Description: description, Status: status}}
+
+		text := listText(i18n.For("en"), tasks, 0, time.UTC, nil, nil)
+		if _, err := checkHTML(text); err != nil {
+			t.Fatalf("listText() = %q is not valid: %v", text, err)
+		}
+		if want := truncate(description, listDescriptionLimit); !strings.Contains(htmlPlainText(text), want) {
+			t.Errorf("listText() shows %q; want it to contain %q", htmlPlainText(text), want)
+		}
+		if done != strings.Contains(text, "<s>") {
+			t.Errorf("listText() = %q; want strikethrough %v", text, done)
+		}
+
+		message := formatHTML("✅ Task added: %s", bold(description))
+		if _, err := checkHTML(message); err != nil {
+			t.Fatalf("formatHTML() = %q is not valid: %v", message, err)
+		}
+		if got := htmlPlainText(message); got != "✅ Task added: "+description {
+			t.Errorf("formatHTML() shows %q", got)
+		}
+	})
+}
+
+// FuzzSplitHTML checks that split messages are valid, fit the limit and keep the text
+func FuzzSplitHTML(f *testing.F) {
+	for _, seed := range markupSeeds {
+		f.Add(seed, 5)
+	}
+	f.Add(strings.Repeat("word ", 50), 16)
+	f.Add("line one\nline two\n\nparagraph", 9)
+
+	f.Fuzz(func(t *testing.T, description string, limit int) {
+		if !utf8.ValidString(description) || limit < 2 || limit > 100 {
+			t.Skip()
+		}
+		text := formatHTML("%s and %s", bold(description), strike(description))
+
+		chunks := splitHTML(text, limit)
+		var shown strings.Builder
+		for _, chunk := range chunks {
+			length, err := checkHTML(chunk)
+			if err != nil {
+				t.Fatalf("chunk %q of %q is not valid: %v", chunk, text, err)
+			}
+			if length > limit {
+				t.Errorf("chunk %q is %d characters long; limit %d", chunk, length, limit)
+			}
+			shown.WriteString(htmlPlainText(chunk))
+		}
+
+		// Only the spaces at the splits may be lost
+		if got, want := strings.Join(strings.Fields(shown.String()), ""), strings.Join(strings.Fields(htmlPlainText(text)), ""); got != want {
+			t.Errorf("chunks show %q; want %q", got, want)
+		}
+	})
+}
+
+func TestEntityTextHTML(t *testing.T) {
+	var text entityText
+	text.WriteString("🔔 <Fix> & ")
+	text.writeMention(storage.ChatMember{UserID: 1, Username: "alice"})
+	text.WriteString(", ")
+	text.writeMention(storage.ChatMember{UserID: 2, FirstName: "Бob <3"})
+
+	want := `🔔 &lt;Fix&gt; &amp; @alice, <a href="tg://user?id=2">Бob &lt;3</a>`
+	if got := text.HTML(); got != want {
+		t.Errorf("HTML() = %q; want %q", got, want)
+	}
+	if _, err := checkHTML(text.HTML()); err != nil {
+		t.Errorf("HTML() is not valid: %v", err)
+	}
+}
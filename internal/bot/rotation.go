@@ -0,0 +1,579 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// scheduleLength is how many turns /rotation shows for each rotating task
+const scheduleLength = 5
+
+func init() {
+	registerCommands(helpRotations, (*Bot).rotationCommands)
+}
+
+// rotationCommands are the commands for chores members take turns on
+func (b *Bot) rotationCommands() []*command {
+	return []*command{
+		{name: "rotate", usage: "<task> @user @user... [daily|weekly|done]", handler: b.handleRotate},
+		{name: "rotation", usage: "[swap <task> @user @user|pause @user...|resume @user...]", handler: b.handleRotation},
+	}
+}
+
+// handleRotate adds a chore the members take turns on: /rotate <task> @user @user... [daily|weekly|done]
+func (b *Bot) handleRotate(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	if !isGroup(message.Chat) {
+		b.sendMessage(message.Chat.ID, p.T("rotation.groups_only"))
+		return
+	}
+
+	description, mentions, rest := splitAtMentions(message)
+	period, ok := parseRotationPeriod(rest)
+	if description == "" || len(mentions) < 2 || !ok {
+		b.sendMessage(message.Chat.ID, p.T("rotation.need_members")+"\n"+p.T("rotation.usage"))
+		return
+	}
+
+	memberIDs, ok := b.resolveMentions(ctx, message, mentions)
+	if !ok {
+		return
+	}
+	if len(memberIDs) < 2 {
+		b.sendMessage(message.Chat.ID, p.T("rotation.need_different")+"\n"+p.T("rotation.usage"))
+		return
+	}
+
+	now := time.Now().In(b.chatLocation(ctx, message.Chat.ID))
+	rotation := &storage.TaskRotation{
+		Members: memberIDs,
+		Period:  period,
+		Since:   turnStart(now, period),
+	}
+	rotation.Current = onDuty(*rotation, pausedMembers(b.chatMembers(ctx, message.Chat.ID)))
+
+	b.addTask(ctx, message, &storage.Task{
+		Description: description,
+		Assignees:   []int64{rotation.OnDuty()},
+		Rotation:    rotation,
+	})
+}
+
+// handleRotation shows the schedule of the chat's rotating tasks, or changes it:
+// /rotation swap <task> @user @user, /rotation pause @user..., /rotation resume @user...
+func (b *Bot) handleRotation(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	if !isGroup(message.Chat) {
+		b.sendMessage(message.Chat.ID, p.T("rotation.groups_only"))
+		return
+	}
+
+	fields := strings.Fields(message.CommandArguments())
+	if len(fields) == 0 {
+		b.sendRotations(ctx, p, message.Chat.ID)
+		return
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "swap":
+		b.swapTurns(ctx, message, fields)
+	case "pause", "resume":
+		b.pauseMembers(ctx, message, strings.ToLower(fields[0]) == "pause")
+	default:
+		b.sendMessage(message.Chat.ID, p.T("rotation.command_usage"))
+	}
+}
+
+// swapTurns exchanges the places of two members in a rotation
+func (b *Bot) swapTurns(ctx context.Context, message *tgbotapi.Message, fields []string) {
+	const usage = "/rotation swap <task> @user @user"
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	if len(fields) < 2 {
+		b.sendMessage(message.Chat.ID, p.T("task.ref_usage", usage))
+		return
+	}
+
+	mentions, _ := leadingMentions(message, 2)
+	ids, ok := b.resolveMentions(ctx, message, mentions)
+	if !ok {
+		return
+	}
+	if len(ids) != 2 {
+		b.sendMessage(message.Chat.ID, p.T("rotation.swap_members", usage))
+		return
+	}
+
+	task := b.findTask(ctx, message, fields[1], usage)
+	if task == nil {
+		return
+	}
+	if task.Rotation == nil {
+		b.sendMessage(message.Chat.ID, p.T("rotation.not_rotating", task.Description))
+		return
+	}
+	if task.ChatID != message.Chat.ID {
+		b.sendMessage(message.Chat.ID, p.T("rotation.shared"))
+		return
+	}
+	if !b.permit(ctx, message, permEdit, task) {
+		return
+	}
+
+	next, ok := swapMembers(*task.Rotation, ids[0], ids[1])
+	if !ok {
+		b.sendMessage(message.Chat.ID, p.T("rotation.not_members"))
+		return
+	}
+	if !b.saveRotation(ctx, p, message.Chat.ID, task, next, false) {
+		return
+	}
+
+	members := b.chatMembers(ctx, message.Chat.ID)
+	b.sendMessage(message.Chat.ID, p.T("rotation.swapped", task.Description,
+		memberNames(p, upcomingTurns(next, scheduleLength, pausedMembers(members)), members)))
+}
+
+// pauseMembers skips members in all rotations of the chat, or stops skipping them
+func (b *Bot) pauseMembers(ctx context.Context, message *tgbotapi.Message, paused bool) {
+	if !b.permit(ctx, message, permSettings, nil) {
+		return
+	}
+
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	mentions, _ := leadingMentions(message, 1)
+	ids, ok := b.resolveMentions(ctx, message, mentions)
+	if !ok {
+		return
+	}
+	if len(ids) == 0 {
+		b.sendMessage(message.Chat.ID, p.T("rotation.pause_usage"))
+		return
+	}
+
+	for _, id := range ids {
+		if _, err := b.storage.SetChatMemberPaused(ctx, message.Chat.ID, id, paused); err != nil {
+			log.Printf("Error pausing chat member: %v", err)
+			b.sendMessage(message.Chat.ID, p.T("rotation.update_failed"))
+			return
+		}
+	}
+
+	names := memberNames(p, ids, b.chatMembers(ctx, message.Chat.ID))
+	if paused {
+		b.sendMessage(message.Chat.ID, p.T("rotation.paused", names))
+	} else {
+		b.sendMessage(message.Chat.ID, p.T("rotation.resumed", names))
+	}
+}
+
+func (b *Bot) sendRotations(ctx context.Context, p *i18n.Printer, chatID int64) {
+	text, keyboard, err := b.rotationView(ctx, p, chatID)
+	if err != nil {
+		log.Printf("Error getting tasks: %v", err)
+		b.sendMessage(chatID, p.T("tasks.get_failed"))
+		return
+	}
+
+	if _, err := b.sendHTMLMarkup(ctx, chatID, text, b.signedKeyboard(chatID, keyboard)); err != nil {
+		log.Printf("Error sending rotations: %v", err)
+		b.handleSendError(ctx, chatID, err)
+	}
+}
+
+// rotationView renders the schedule of the chat's rotating tasks in HTML with buttons to
+// change turns
+func (b *Bot) rotationView(ctx context.Context, p *i18n.Printer, chatID int64) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	tasks, err := b.storage.GetTasksByChatID(ctx, chatID)
+	if err != nil {
+		return "", nil, err
+	}
+	members := b.chatMembers(ctx, chatID)
+	b.refreshRotations(ctx, chatID, tasks, members)
+
+	now := time.Now().In(b.chatLocation(ctx, chatID))
+	return rotationText(p, tasks, members, now), rotationKeyboard(p, tasks, members), nil
+}
+
+// rotationText lists the upcoming turns of every rotating task in HTML
+func rotationText(p *i18n.Printer, tasks []storage.Task, members []storage.ChatMember, now time.Time) string {
+	paused := pausedMembers(members)
+
+	var text strings.Builder
+	for i, task := range tasks {
+		rotation := task.Rotation
+		if rotation == nil {
+			continue
+		}
+
+		text.WriteString(formatHTML("%d. %s (%s)\n", i+1, task.Description, periodLabel(p, rotation.Period)))
+		for turn, id := range upcomingTurns(*rotation, scheduleLength, paused) {
+			text.WriteString(formatHTML("   %s: %s\n", turnLabel(p, rotation.Period, now, turn), memberNames(p, []int64{id}, members)))
+		}
+
+		var pausedIDs []int64
+		for _, id := range rotation.Members {
+			if paused[id] {
+				pausedIDs = append(pausedIDs, id)
+			}
+		}
+		if len(pausedIDs) > 0 {
+			text.WriteString("   " + escapeHTML(p.T("rotation.paused_members", memberNames(p, pausedIDs, members))) + "\n")
+		}
+		text.WriteString("\n")
+	}
+
+	if text.Len() == 0 {
+		return escapeHTML(p.T("rotation.none"))
+	}
+	return formatHTML("🔄 %s\n\n", bold(p.T("rotation.title"))) + text.String() + escapeHTML(p.T("rotation.hint"))
+}
+
+// rotationKeyboard has a row per rotating task to swap the current turn with the next
+// one, or skip it
+func rotationKeyboard(p *i18n.Printer, tasks []storage.Task, members []storage.ChatMember) *tgbotapi.InlineKeyboardMarkup {
+	paused := pausedMembers(members)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, task := range tasks {
+		if task.Rotation == nil {
+			continue
+		}
+		turns := upcomingTurns(*task.Rotation, 2, paused)
+		if turns[0] == turns[1] {
+			continue
+		}
+		id := task.ID.Hex()
+		current := truncate(memberNames(p, turns[:1], members), 12)
+		next := truncate(memberNames(p, turns[1:], members), 12)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d. 🔁 %s ↔ %s", i+1, current, next), callbackData(callbackRotation, "w", id)),
+			tgbotapi.NewInlineKeyboardButtonData(p.T("rotation.skip_button", current), callbackData(callbackRotation, "k", id)),
+		))
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// handleRotationCallback changes a turn from the /rotation message. Args: "w" to swap the
+// current member with the next one or "k" to skip the current member, task ID.
+func (b *Bot) handleRotationCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) callbackAnswer {
+	if len(args) != 2 {
+		return answerStale
+	}
+	chatID := query.Message.Chat.ID
+	p := b.printer(ctx, chatID, query.From)
+
+	task, answer := b.taskForCallback(ctx, query, args[1])
+	if task != nil && task.Rotation != nil {
+		if denied, ok := b.permitCallback(ctx, query, permEdit, task); !ok {
+			return denied
+		}
+		members := b.chatMembers(ctx, chatID)
+		b.refreshRotations(ctx, chatID, []storage.Task{*task}, members)
+		paused := pausedMembers(members)
+		turns := upcomingTurns(*task.Rotation, 2, paused)
+
+		var next storage.TaskRotation
+		switch args[0] {
+		case "w":
+			next, _ = swapMembers(*task.Rotation, turns[0], turns[1])
+			answer = callbackAnswer{text: p.T("rotation.goes_first", memberNames(p, turns[1:], members))}
+		case "k":
+			next = passTurn(*task.Rotation, time.Now(), paused)
+			answer = callbackAnswer{text: p.T("rotation.turn_now", memberNames(p, turns[1:], members))}
+		default:
+			return answerStale
+		}
+		if !b.saveRotation(ctx, p, chatID, task, next, args[0] == "k") {
+			return answerError
+		}
+	}
+
+	text, keyboard, err := b.rotationView(ctx, p, chatID)
+	if err != nil {
+		log.Printf("Error getting tasks: %v", err)
+		return answerError
+	}
+	if err := b.editHTML(ctx, chatID, query.Message.MessageID, text, keyboard); err != nil {
+		log.Printf("Error updating rotations: %v", err)
+	}
+	return answer
+}
+
+// completeTurn completes a task that rotates on completion by passing it to the next
+// member. It returns the member whose turn it is now.
+func (b *Bot) completeTurn(ctx context.Context, task *storage.Task) (int64, error) {
+	paused := pausedMembers(b.chatMembers(ctx, task.ChatID))
+	next := passTurn(*task.Rotation, time.Now(), paused)
+
+	ok, err := b.storage.UpdateTaskRotation(ctx, task.ID, *task.Rotation, next, true)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("rotation of task %s changed concurrently", task.ID.Hex())
+	}
+	b.remindersChanged(ctx, task.ChatID)
+	b.recordStreak(ctx, task.ChatID)
+	return next.OnDuty(), nil
+}
+
+// saveRotation stores a changed rotation, telling the user if that failed
+func (b *Bot) saveRotation(ctx context.Context, p *i18n.Printer, chatID int64, task *storage.Task, next storage.TaskRotation, newTurn bool) bool {
+	ok, err := b.storage.UpdateTaskRotation(ctx, task.ID, *task.Rotation, next, newTurn)
+	if err != nil {
+		log.Printf("Error updating rotation: %v", err)
+	}
+	if err != nil || !ok {
+		b.sendMessage(chatID, p.T("rotation.update_failed"))
+		return false
+	}
+	task.Rotation = &next
+	return true
+}
+
+// refreshRotations moves the turns of rotating tasks forward for the periods that passed
+// and past paused members, and saves them. Turns are only moved when a chat's tasks are
+// read, e.g. for a reminder or /list. tasks are updated in place.
+func (b *Bot) refreshRotations(ctx context.Context, chatID int64, tasks []storage.Task, members []storage.ChatMember) {
+	var now time.Time
+	paused := pausedMembers(members)
+
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Rotation == nil || len(task.Rotation.Members) == 0 || task.ChatID != chatID {
+			// Shared tasks turn in their own chat
+			continue
+		}
+		if now.IsZero() {
+			now = time.Now().In(b.chatLocation(ctx, chatID))
+		}
+
+		next, newTurn := advanceRotation(*task.Rotation, now, paused)
+		if next.Current == task.Rotation.Current && next.Since.Equal(task.Rotation.Since) {
+			continue
+		}
+
+		ok, err := b.storage.UpdateTaskRotation(ctx, task.ID, *task.Rotation, next, newTurn)
+		if err != nil {
+			log.Printf("Error updating rotation: %v", err)
+			continue
+		}
+		if !ok {
+			// Another request moved the turn already
+			continue
+		}
+
+		task.Rotation = &next
+		task.Assignees = []int64{next.OnDuty()}
+		if newTurn {
+			task.Status = storage.TaskStatusActive
+			task.CompletedAt = nil
+		}
+	}
+}
+
+func parseRotationPeriod(s string) (storage.RotationPeriod, bool) {
+	switch strings.ToLower(s) {
+	case "", "daily":
+		return storage.RotationDaily, true
+	case "weekly":
+		return storage.RotationWeekly, true
+	case "done":
+		return storage.RotationOnCompletion, true
+	}
+	return "", false
+}
+
+func periodLabel(p *i18n.Printer, period storage.RotationPeriod) string {
+	switch period {
+	case storage.RotationWeekly:
+		return p.T("rotation.period_weekly")
+	case storage.RotationOnCompletion:
+		return p.T("rotation.period_done")
+	default:
+		return p.T("rotation.period_daily")
+	}
+}
+
+// turnLabel names the turn that is the given number of turns from now
+func turnLabel(p *i18n.Printer, period storage.RotationPeriod, now time.Time, turn int) string {
+	switch {
+	case period == storage.RotationOnCompletion && turn == 0:
+		return p.T("rotation.turn_now_label")
+	case period == storage.RotationOnCompletion:
+		return p.T("rotation.turn_then")
+	case period == storage.RotationWeekly && turn == 0:
+		return p.T("rotation.turn_this_week")
+	case period == storage.RotationWeekly:
+		return p.T("rotation.turn_week_of", p.Date(turnStart(now, period).AddDate(0, 0, 7*turn)))
+	case turn == 0:
+		return p.T("rotation.turn_today")
+	case turn == 1:
+		return p.T("rotation.turn_tomorrow")
+	default:
+		return p.Date(startOfDay(now, turn))
+	}
+}
+
+// turnStart returns when the turn that includes t started, in t's location
+func turnStart(t time.Time, period storage.RotationPeriod) time.Time {
+	switch period {
+	case storage.RotationOnCompletion:
+		return t
+	case storage.RotationWeekly:
+		// Weeks start on Monday
+		return startOfDay(t, -((int(t.Weekday()) + 6) % 7))
+	default:
+		return startOfDay(t, 0)
+	}
+}
+
+// turnsBetween counts the turns that started after since, up to now
+func turnsBetween(since, now time.Time, period storage.RotationPeriod) int {
+	if period == storage.RotationOnCompletion {
+		return 0
+	}
+	from := turnStart(since.In(now.Location()), period)
+	to := turnStart(now, period)
+	// Days can be 23 or 25 hours long around DST changes
+	days := int(math.Round(to.Sub(from).Hours() / 24))
+	if period == storage.RotationWeekly {
+		return days / 7
+	}
+	return days
+}
+
+// advanceRotation moves the turn forward for every turn that started since the current
+// one, skipping paused members. newTurn reports whether a turn started.
+func advanceRotation(r storage.TaskRotation, now time.Time, paused map[int64]bool) (next storage.TaskRotation, newTurn bool) {
+	next = r
+	turns := turnsBetween(r.Since, now, r.Period)
+	if turns > 0 {
+		// Only members who aren't paused take turns, so the order repeats after them
+		if active := activeCount(r, paused); active > 0 {
+			turns %= active
+		}
+		for i := 0; i < turns; i++ {
+			next.Current = nextActive(next, next.Current, paused)
+		}
+		next.Since = turnStart(now, r.Period)
+		newTurn = true
+	}
+	next.Current = onDuty(next, paused)
+	return next, newTurn
+}
+
+// passTurn hands the task to the next member who isn't paused, starting a new turn at now
+func passTurn(r storage.TaskRotation, now time.Time, paused map[int64]bool) storage.TaskRotation {
+	next := r
+	next.Current = nextActive(r, onDuty(r, paused), paused)
+	next.Since = turnStart(now, r.Period)
+	return next
+}
+
+// swapMembers exchanges the places of two members in the turn order.
+// It returns false if either isn't part of the rotation.
+func swapMembers(r storage.TaskRotation, a, b int64) (storage.TaskRotation, bool) {
+	i, j := indexOf(r.Members, a), indexOf(r.Members, b)
+	if i < 0 || j < 0 {
+		return r, false
+	}
+	next := r
+	next.Members = append([]int64(nil), r.Members...)
+	next.Members[i], next.Members[j] = next.Members[j], next.Members[i]
+	return next, true
+}
+
+// upcomingTurns lists the members whose turns come next, starting with the current one
+func upcomingTurns(r storage.TaskRotation, count int, paused map[int64]bool) []int64 {
+	i := onDuty(r, paused)
+	turns := []int64{r.Members[i]}
+	for len(turns) < count {
+		i = nextActive(r, i, paused)
+		turns = append(turns, r.Members[i])
+	}
+	return turns
+}
+
+// onDuty returns the index of the member whose turn it is: the current one, or the next
+// one who isn't paused. If everyone is paused the current member stays on duty.
+func onDuty(r storage.TaskRotation, paused map[int64]bool) int {
+	if !paused[r.Members[r.Current]] || activeCount(r, paused) == 0 {
+		return r.Current
+	}
+	return nextActive(r, r.Current, paused)
+}
+
+// nextActive returns the index of the first member after i who isn't paused.
+// If everyone is paused it returns the member after i.
+func nextActive(r storage.TaskRotation, i int, paused map[int64]bool) int {
+	n := len(r.Members)
+	for step := 1; step <= n; step++ {
+		j := (i + step) % n
+		if !paused[r.Members[j]] {
+			return j
+		}
+	}
+	return (i + 1) % n
+}
+
+func activeCount(r storage.TaskRotation, paused map[int64]bool) int {
+	count := 0
+	for _, id := range r.Members {
+		if !paused[id] {
+			count++
+		}
+	}
+	return count
+}
+
+func pausedMembers(members []storage.ChatMember) map[int64]bool {
+	paused := make(map[int64]bool)
+	for _, m := range members {
+		if m.Paused {
+			paused[m.UserID] = true
+		}
+	}
+	return paused
+}
+
+func indexOf(ids []int64, id int64) int {
+	for i, v := range ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// rotationLine describes whose turn a rotating task is, for /list
+func rotationLine(p *i18n.Printer, r storage.TaskRotation, members []storage.ChatMember) string {
+	turns := upcomingTurns(r, 2, pausedMembers(members))
+	if turns[1] != turns[0] {
+		return p.T("rotation.line_then", memberNames(p, turns[:1], members), memberNames(p, turns[1:], members))
+	}
+	return p.T("rotation.line", memberNames(p, turns[:1], members))
+}
+
+// formatTurn is used by the confirmation of /rotate
+func formatTurn(p *i18n.Printer, r storage.TaskRotation, members []storage.ChatMember) string {
+	order := make([]string, len(r.Members))
+	for i, id := range r.Members {
+		order[i] = memberNames(p, []int64{id}, members)
+	}
+	return p.T("rotation.takes_turns", periodLabel(p, r.Period), strings.Join(order, " → "),
+		memberNames(p, []int64{r.OnDuty()}, members))
+}
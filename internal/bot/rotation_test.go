@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestTurnsBetween(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		since  time.Time
+		now    time.Time
+		period storage.RotationPeriod
+		want   int
+	}{
+		{name: "Same day", since: time.Date(2026, 3, 2, 0, 0, 0, 0, berlin), now: time.Date(2026, 3, 2, 23, 59, 0, 0, berlin), period: storage.RotationDaily, want: 0},
+		{name: "Next morning", since: time.Date(2026, 3, 2, 0, 0, 0, 0, berlin), now: time.Date(2026, 3, 3, 0, 1, 0, 0, berlin), period: storage.RotationDaily, want: 1},
+		// The night of March 29 is an hour shorter
+		{name: "Across DST change", since: time.Date(2026, 3, 28, 0, 0, 0, 0, berlin), now: time.Date(2026, 3, 31, 8, 0, 0, 0, berlin), period: storage.RotationDaily, want: 3},
+		{name: "Since stored in UTC", since: time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC), now: time.Date(2026, 3, 2, 9, 0, 0, 0, berlin), period: storage.RotationDaily, want: 0},
+		{name: "Sunday to Monday", since: time.Date(2026, 3, 2, 0, 0, 0, 0, berlin), now: time.Date(2026, 3, 9, 7, 0, 0, 0, berlin), period: storage.RotationWeekly, want: 1},
+		{name: "Within the week", since: time.Date(2026, 3, 2, 0, 0, 0, 0, berlin), now: time.Date(2026, 3, 8, 23, 0, 0, 0, berlin), period: storage.RotationWeekly, want: 0},
+		{name: "On completion", since: time.Date(2026, 3, 2, 0, 0, 0, 0, berlin), now: time.Date(2026, 4, 2, 0, 0, 0, 0, berlin), period: storage.RotationOnCompletion, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := turnsBetween(tt.since, tt.now, tt.period); got != tt.want {
+				t.Errorf("turnsBetween() = %d; want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTurnStartWeekly(t *testing.T) {
+	sunday := time.Date(2026, 3, 8, 18, 30, 0, 0, time.UTC)
+	want := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	if got := turnStart(sunday, storage.RotationWeekly); !got.Equal(want) {
+		t.Errorf("turnStart(Sunday) = %v; want Monday %v", got, want)
+	}
+}
+
+func TestAdvanceRotation(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		rotation    storage.TaskRotation
+		now         time.Time
+		paused      map[int64]bool
+		wantOnDuty  int64
+		wantNewTurn bool
+	}{
+		{
+			name:       "Same day keeps the turn",
+			rotation:   storage.TaskRotation{Members: []int64{1, 2, 3}, Period: storage.RotationDaily, Since: monday},
+			now:        monday.Add(20 * time.Hour),
+			wantOnDuty: 1,
+		},
+		{
+			name:        "Next day",
+			rotation:    storage.TaskRotation{Members: []int64{1, 2, 3}, Period: storage.RotationDaily, Since: monday},
+			now:         monday.AddDate(0, 0, 1),
+			wantOnDuty:  2,
+			wantNewTurn: true,
+		},
+		{
+			name:        "Several days wrap around",
+			rotation:    storage.TaskRotation{Members: []int64{1, 2, 3}, Current: 1, Period: storage.RotationDaily, Since: monday},
+			now:         monday.AddDate(0, 0, 4),
+			wantOnDuty:  3,
+			wantNewTurn: true,
+		},
+		{
+			name:        "Paused member is skipped",
+			rotation:    storage.TaskRotation{Members: []int64{1, 2, 3}, Period: storage.RotationDaily, Since: monday},
+			now:         monday.AddDate(0, 0, 1),
+			paused:      map[int64]bool{2: true},
+			wantOnDuty:  3,
+			wantNewTurn: true,
+		},
+		{
+			name:       "Paused member on duty hands over without a new turn",
+			rotation:   storage.TaskRotation{Members: []int64{1, 2, 3}, Period: storage.RotationDaily, Since: monday},
+			now:        monday.Add(time.Hour),
+			paused:     map[int64]bool{1: true},
+			wantOnDuty: 2,
+		},
+		{
+			name:       "Everyone paused",
+			rotation:   storage.TaskRotation{Members: []int64{1, 2}, Current: 1, Period: storage.RotationWeekly, Since: monday},
+			now:        monday.Add(time.Hour),
+			paused:     map[int64]bool{1: true, 2: true},
+			wantOnDuty: 2,
+		},
+		{
+			name:       "On completion doesn't advance with time",
+			rotation:   storage.TaskRotation{Members: []int64{1, 2}, Period: storage.RotationOnCompletion, Since: monday},
+			now:        monday.AddDate(0, 1, 0),
+			wantOnDuty: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, newTurn := advanceRotation(tt.rotation, tt.now, tt.paused)
+			if next.OnDuty() != tt.wantOnDuty {
+				t.Errorf("on duty = %d; want %d", next.OnDuty(), tt.wantOnDuty)
+			}
+			if newTurn != tt.wantNewTurn {
+				t.Errorf("newTurn = %v; want %v", newTurn, tt.wantNewTurn)
+			}
+			if newTurn && !next.Since.Equal(turnStart(tt.now, tt.rotation.Period)) {
+				t.Errorf("since = %v; want the start of the current turn", next.Since)
+			}
+		})
+	}
+}
+
+func TestUpcomingTurns(t *testing.T) {
+	r := storage.TaskRotation{Members: []int64{1, 2, 3, 4}, Current: 2}
+	got := upcomingTurns(r, 5, map[int64]bool{4: true})
+	want := []int64{3, 1, 2, 3, 1}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("upcomingTurns() = %v; want %v", got, want)
+		}
+	}
+
+	swapped, ok := swapMembers(r, 3, 1)
+	if !ok || swapped.OnDuty() != 1 || r.Members[0] != 1 {
+		t.Errorf("swapMembers() = %v, %v; want member 1 on duty and the original unchanged", swapped.Members, ok)
+	}
+	if _, ok := swapMembers(r, 3, 9); ok {
+		t.Error("swapMembers() with a non-member should fail")
+	}
+}
+
+func TestSplitAtMentions(t *testing.T) {
+	message := commandMessage("/rotate take out trash @alice @bob weekly",
+		tgbotapi.MessageEntity{Type: "mention", Offset: 23, Length: 6},
+		tgbotapi.MessageEntity{Type: "mention", Offset: 30, Length: 4})
+
+	before, mentions, after := splitAtMentions(message)
+	if before != "take out trash" || after != "weekly" {
+		t.Errorf("splitAtMentions() = %q, %q; want %q, %q", before, after, "take out trash", "weekly")
+	}
+	if len(mentions) != 2 || mentions[0].username != "alice" || mentions[1].username != "bob" {
+		t.Errorf("mentions = %v; want alice, bob", mentions)
+	}
+}
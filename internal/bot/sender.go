@@ -0,0 +1,463 @@
+package bot
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sendPriority orders queued requests; lower values are sent first
+type sendPriority int
+
+const (
+	// priorityInteractive is used for replies to user actions
+	priorityInteractive sendPriority = iota
+	// priorityBulk is used for scheduled messages such as daily reminders
+	priorityBulk
+	numPriorities
+)
+
+// Default Telegram limits, see https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this
+const (
+	defaultGlobalPerSecond = 30
+	defaultChatPerSecond   = 1
+	defaultGroupPerMinute  = 20
+)
+
+// bucketIdleTimeout is how long an unused full per-chat bucket is kept
+const bucketIdleTimeout = time.Minute
+
+var errSenderStopped = errors.New("sender stopped")
+
+// clock abstracts time so the sender can be tested without sleeping
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// messageAPI is the part of tgbotapi.BotAPI used by the sender
+type messageAPI interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+}
+
+// tokenBucket is a classic token bucket refilled continuously at rate tokens per second
+type tokenBucket struct {
+	capacity float64
+	rate     float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(capacity, rate float64, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		capacity: capacity,
+		rate:     rate,
+		tokens:   capacity,
+		last:     now,
+	}
+}
+
+func (tb *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(tb.last).Seconds(); elapsed > 0 {
+		tb.tokens += elapsed * tb.rate
+		if tb.tokens > tb.capacity {
+			tb.tokens = tb.capacity
+		}
+	}
+	tb.last = now
+}
+
+// wait returns how long until a token is available
+func (tb *tokenBucket) wait(now time.Time) time.Duration {
+	tb.refill(now)
+	if tb.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second))
+}
+
+func (tb *tokenBucket) take(now time.Time) {
+	tb.refill(now)
+	tb.tokens--
+}
+
+func (tb *tokenBucket) full(now time.Time) bool {
+	tb.refill(now)
+	return tb.tokens >= tb.capacity
+}
+
+// sendLimits configures the sender's token buckets
+type sendLimits struct {
+	GlobalPerSecond float64
+	ChatPerSecond   float64
+	GroupPerMinute  float64
+}
+
+func defaultSendLimits() sendLimits {
+	return sendLimits{
+		GlobalPerSecond: defaultGlobalPerSecond,
+		ChatPerSecond:   defaultChatPerSecond,
+		GroupPerMinute:  defaultGroupPerMinute,
+	}
+}
+
+type sendResult struct {
+	message tgbotapi.Message
+	err     error
+}
+
+type sendRequest struct {
+	ctx       context.Context
+	chatID    int64
+	chattable tgbotapi.Chattable
+	result    chan sendResult
+}
+
+// chatQueue holds a chat's queued requests, oldest first for each priority
+type chatQueue struct {
+	pending [numPriorities][]*sendRequest
+	listed  [numPriorities]bool // The chat is on the priority's ready list or parked for it
+}
+
+// head returns the oldest request of the priority, answering and dropping the ones whose
+// ctx is done on the way
+func (q *chatQueue) head(p sendPriority) *sendRequest {
+	for len(q.pending[p]) > 0 {
+		req := q.pending[p][0]
+		err := req.ctx.Err()
+		if err == nil {
+			return req
+		}
+		req.result <- sendResult{err: err}
+		q.pending[p][0] = nil
+		q.pending[p] = q.pending[p][1:]
+	}
+	return nil
+}
+
+func (q *chatQueue) idle() bool {
+	for p := range q.pending {
+		if len(q.pending[p]) > 0 || q.listed[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// chatList is a FIFO of chat IDs
+type chatList struct {
+	ids  []int64
+	head int
+}
+
+func (l *chatList) len() int { return len(l.ids) - l.head }
+
+func (l *chatList) push(chatID int64) { l.ids = append(l.ids, chatID) }
+
+func (l *chatList) peek() int64 { return l.ids[l.head] }
+
+func (l *chatList) pop() {
+	l.head++
+	// Reuse the space of the popped IDs once they are the larger part
+	if l.head > len(l.ids)/2 {
+		l.ids = append(l.ids[:0], l.ids[l.head:]...)
+		l.head = 0
+	}
+}
+
+// parkedChat is a chat whose token buckets hold back its requests of a priority until at
+type parkedChat struct {
+	chatID   int64
+	priority sendPriority
+	at       time.Time
+}
+
+// parkedChats is a min-heap of parked chats by the time they can send again
+type parkedChats []parkedChat
+
+func (h parkedChats) Len() int           { return len(h) }
+func (h parkedChats) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h parkedChats) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *parkedChats) Push(x any)        { *h = append(*h, x.(parkedChat)) }
+func (h *parkedChats) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// sender is the single path for outgoing Telegram messages. It queues requests
+// by priority and releases them as the global, per-chat and per-group token
+// buckets allow, so bursts such as thousands of 09:00 reminders stay within
+// Telegram's limits.
+//
+// Each chat keeps its requests in a queue per priority. A chat with requests of a
+// priority is on that priority's ready list, parked until its buckets allow the next
+// send, or in flight, so picking the next request doesn't depend on the queue length.
+type sender struct {
+	api    messageAPI
+	clock  clock
+	limits sendLimits
+
+	mu        sync.Mutex
+	queues    map[int64]*chatQueue
+	ready     [numPriorities]chatList
+	parked    parkedChats
+	global    *tokenBucket
+	chats     map[int64]*tokenBucket
+	groups    map[int64]*tokenBucket
+	inFlight  map[int64]bool // Chats with a request being sent
+	lastPrune time.Time
+
+	wake chan struct{}
+	done chan struct{}
+}
+
+func newSender(api messageAPI, clk clock, limits sendLimits) *sender {
+	now := clk.Now()
+	return &sender{
+		api:       api,
+		clock:     clk,
+		limits:    limits,
+		queues:    make(map[int64]*chatQueue),
+		global:    newTokenBucket(limits.GlobalPerSecond, limits.GlobalPerSecond, now),
+		chats:     make(map[int64]*tokenBucket),
+		groups:    make(map[int64]*tokenBucket),
+		inFlight:  make(map[int64]bool),
+		lastPrune: now,
+		wake:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+}
+
+// send queues c for chatID and blocks until it has been sent, ctx is done or the sender stops
+func (s *sender) send(ctx context.Context, chatID int64, priority sendPriority, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	req := &sendRequest{
+		ctx:       ctx,
+		chatID:    chatID,
+		chattable: c,
+		result:    make(chan sendResult, 1),
+	}
+
+	select {
+	case <-s.done:
+		return tgbotapi.Message{}, errSenderStopped
+	default:
+	}
+
+	s.mu.Lock()
+	s.push(req, priority)
+	s.mu.Unlock()
+	s.notify()
+
+	select {
+	case res := <-req.result:
+		return res.message, res.err
+	case <-ctx.Done():
+		// The request is dropped from the queue the next time it is considered
+		return tgbotapi.Message{}, ctx.Err()
+	case <-s.done:
+		return tgbotapi.Message{}, errSenderStopped
+	}
+}
+
+// push queues a request for its chat. The caller must hold s.mu.
+func (s *sender) push(req *sendRequest, priority sendPriority) {
+	q, ok := s.queues[req.chatID]
+	if !ok {
+		q = &chatQueue{}
+		s.queues[req.chatID] = q
+	}
+	q.pending[priority] = append(q.pending[priority], req)
+	// A chat in flight is listed again when its request finishes
+	if !q.listed[priority] && !s.inFlight[req.chatID] {
+		q.listed[priority] = true
+		s.ready[priority].push(req.chatID)
+	}
+}
+
+func (s *sender) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run releases queued requests until ctx is done
+func (s *sender) run(ctx context.Context) {
+	defer close(s.done)
+
+	for {
+		s.mu.Lock()
+		req, wait := s.next(s.clock.Now())
+		s.mu.Unlock()
+
+		if req != nil {
+			// Sending concurrently keeps slow HTTP round trips from eating into the rate.
+			// A chat's next request waits until this one is done, so its order is kept.
+			go s.deliver(req)
+			continue
+		}
+
+		var timer <-chan time.Time
+		if wait > 0 {
+			timer = s.clock.After(wait)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer:
+		}
+	}
+}
+
+func (s *sender) deliver(req *sendRequest) {
+	msg, err := s.api.Send(req.chattable)
+	s.finish(req.chatID)
+	req.result <- sendResult{message: msg, err: err}
+}
+
+// finish lets the chat's next request be sent
+func (s *sender) finish(chatID int64) {
+	s.mu.Lock()
+	delete(s.inFlight, chatID)
+	if q, ok := s.queues[chatID]; ok {
+		for p := range q.pending {
+			if len(q.pending[p]) > 0 && !q.listed[p] {
+				q.listed[p] = true
+				s.ready[p].push(chatID)
+			}
+		}
+		s.forgetIdle(chatID, q)
+	}
+	s.mu.Unlock()
+	s.notify()
+}
+
+// next pops the oldest request of the first chat on the highest priority ready list
+// that the token buckets allow to send now, and marks its chat as in flight until
+// finish is called. Chats the buckets hold back are parked until they can send again.
+// If nothing can be sent it returns how long to wait, or zero if the queues are empty or
+// only wait for requests in flight. The caller must hold s.mu.
+func (s *sender) next(now time.Time) (*sendRequest, time.Duration) {
+	s.prune(now)
+	for len(s.parked) > 0 && !s.parked[0].at.After(now) {
+		parked := heap.Pop(&s.parked).(parkedChat)
+		s.ready[parked.priority].push(parked.chatID)
+	}
+
+	globalWait := s.global.wait(now)
+	for p := range s.ready {
+		priority := sendPriority(p)
+		ready := &s.ready[p]
+		for ready.len() > 0 {
+			chatID := ready.peek()
+			q := s.queues[chatID]
+			req := q.head(priority)
+			if req == nil || s.inFlight[chatID] {
+				// finish lists a chat in flight again
+				ready.pop()
+				q.listed[p] = false
+				s.forgetIdle(chatID, q)
+				continue
+			}
+
+			if wait := s.chatWait(chatID, now); wait > 0 {
+				ready.pop()
+				heap.Push(&s.parked, parkedChat{chatID: chatID, priority: priority, at: now.Add(wait)})
+				continue
+			}
+			if globalWait > 0 {
+				return nil, globalWait
+			}
+
+			s.global.take(now)
+			s.chatBucket(chatID, now).take(now)
+			if isGroupChat(chatID) {
+				s.groupBucket(chatID, now).take(now)
+			}
+			ready.pop()
+			q.listed[p] = false
+			q.pending[p][0] = nil
+			q.pending[p] = q.pending[p][1:]
+			s.inFlight[chatID] = true
+			return req, 0
+		}
+	}
+
+	if len(s.parked) > 0 {
+		return nil, s.parked[0].at.Sub(now)
+	}
+	return nil, 0
+}
+
+// chatWait returns how long the chat's own buckets hold back its next request
+func (s *sender) chatWait(chatID int64, now time.Time) time.Duration {
+	wait := s.chatBucket(chatID, now).wait(now)
+	if isGroupChat(chatID) {
+		if w := s.groupBucket(chatID, now).wait(now); w > wait {
+			wait = w
+		}
+	}
+	return wait
+}
+
+// forgetIdle drops the queue of a chat with nothing left to send
+func (s *sender) forgetIdle(chatID int64, q *chatQueue) {
+	if q.idle() && !s.inFlight[chatID] {
+		delete(s.queues, chatID)
+	}
+}
+
+func (s *sender) chatBucket(chatID int64, now time.Time) *tokenBucket {
+	tb, ok := s.chats[chatID]
+	if !ok {
+		tb = newTokenBucket(1, s.limits.ChatPerSecond, now)
+		s.chats[chatID] = tb
+	}
+	return tb
+}
+
+func (s *sender) groupBucket(chatID int64, now time.Time) *tokenBucket {
+	tb, ok := s.groups[chatID]
+	if !ok {
+		tb = newTokenBucket(s.limits.GroupPerMinute, s.limits.GroupPerMinute/60, now)
+		s.groups[chatID] = tb
+	}
+	return tb
+}
+
+// prune forgets buckets that are full again, they behave exactly like new ones
+func (s *sender) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < bucketIdleTimeout {
+		return
+	}
+	s.lastPrune = now
+
+	for chatID, tb := range s.chats {
+		if tb.full(now) {
+			delete(s.chats, chatID)
+		}
+	}
+	for chatID, tb := range s.groups {
+		if tb.full(now) {
+			delete(s.groups, chatID)
+		}
+	}
+}
+
+// isGroupChat reports whether chatID belongs to a group, supergroup or channel
+func isGroupChat(chatID int64) bool {
+	return chatID < 0
+}
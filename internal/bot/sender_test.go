@@ -0,0 +1,377 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// fakeClock only moves when the test advances it
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []fakeTimer
+	waiting chan struct{}
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:     time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		waiting: make(chan struct{}, 100),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), ch: ch})
+	c.waiting <- struct{}{}
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	remaining := c.timers[:0]
+	for _, t := range c.timers {
+		if !t.at.After(c.now) {
+			t.ch <- c.now
+		} else {
+			remaining = append(remaining, t)
+		}
+	}
+	c.timers = remaining
+}
+
+type fakeAPI struct {
+	mu   sync.Mutex
+	sent []int64
+	ch   chan int64
+}
+
+func (a *fakeAPI) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	msg := c.(tgbotapi.MessageConfig)
+	a.mu.Lock()
+	a.sent = append(a.sent, msg.ChatID)
+	a.mu.Unlock()
+	a.ch <- msg.ChatID
+	return tgbotapi.Message{}, nil
+}
+
+func testLimits() sendLimits {
+	return sendLimits{GlobalPerSecond: 30, ChatPerSecond: 1, GroupPerMinute: 20}
+}
+
+func enqueue(s *sender, chatID int64, priority sendPriority) *sendRequest {
+	req := &sendRequest{
+		ctx:       context.Background(),
+		chatID:    chatID,
+		chattable: tgbotapi.NewMessage(chatID, "test"),
+		result:    make(chan sendResult, 1),
+	}
+	s.push(req, priority)
+	return req
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tb := newTokenBucket(2, 1, now)
+
+	tb.take(now)
+	tb.take(now)
+	if wait := tb.wait(now); wait != time.Second {
+		t.Errorf("wait after draining = %v, want 1s", wait)
+	}
+	if wait := tb.wait(now.Add(500 * time.Millisecond)); wait != 500*time.Millisecond {
+		t.Errorf("wait after half refill = %v, want 500ms", wait)
+	}
+	if !tb.full(now.Add(10 * time.Second)) {
+		t.Error("bucket should be full after a long idle period")
+	}
+}
+
+func TestSenderGlobalLimit(t *testing.T) {
+	clk := newFakeClock()
+	s := newSender(nil, clk, testLimits())
+	now := clk.Now()
+
+	for chatID := int64(1); chatID <= 31; chatID++ {
+		enqueue(s, chatID, priorityBulk)
+	}
+
+	for i := 0; i < 30; i++ {
+		if req, _ := s.next(now); req == nil {
+			t.Fatalf("request %d was not released within the global burst", i+1)
+		}
+	}
+
+	req, wait := s.next(now)
+	if req != nil {
+		t.Fatal("31st request released within the same instant")
+	}
+	if want := time.Second / 30; wait < want-time.Millisecond || wait > want+time.Millisecond {
+		t.Errorf("wait = %v, want about %v", wait, want)
+	}
+
+	if req, _ := s.next(now.Add(wait)); req == nil || req.chatID != 31 {
+		t.Error("31st request not released after waiting")
+	}
+}
+
+func TestSenderPerChatLimit(t *testing.T) {
+	clk := newFakeClock()
+	s := newSender(nil, clk, testLimits())
+	now := clk.Now()
+
+	enqueue(s, 42, priorityBulk)
+	enqueue(s, 42, priorityBulk)
+	enqueue(s, 43, priorityBulk)
+
+	if req, _ := s.next(now); req == nil || req.chatID != 42 {
+		t.Fatal("first request for chat 42 not released")
+	}
+	s.finish(42)
+	// The second message for chat 42 must not block other chats
+	if req, _ := s.next(now); req == nil || req.chatID != 43 {
+		t.Fatal("request for chat 43 blocked behind chat 42")
+	}
+	req, wait := s.next(now)
+	if req != nil {
+		t.Fatal("second request for chat 42 released within a second")
+	}
+	if wait != time.Second {
+		t.Errorf("wait = %v, want 1s", wait)
+	}
+	if req, _ := s.next(now.Add(time.Second)); req == nil || req.chatID != 42 {
+		t.Error("second request for chat 42 not released after a second")
+	}
+}
+
+func TestSenderGroupLimit(t *testing.T) {
+	clk := newFakeClock()
+	limits := testLimits()
+	// Lift the per-chat limit so only the group budget applies
+	limits.ChatPerSecond = 100
+	s := newSender(nil, clk, limits)
+	now := clk.Now()
+	const groupID = -100123
+
+	for i := 0; i < 21; i++ {
+		enqueue(s, groupID, priorityBulk)
+	}
+
+	for i := 0; i < 20; i++ {
+		req, _ := s.next(now)
+		if req == nil {
+			t.Fatalf("group request %d not released", i+1)
+		}
+		s.finish(groupID)
+		now = now.Add(10 * time.Millisecond)
+	}
+
+	req, wait := s.next(now)
+	if req != nil {
+		t.Fatal("21st group request released within a minute")
+	}
+	if wait <= 2*time.Second || wait > 3*time.Second {
+		t.Errorf("wait = %v, want about 3s", wait)
+	}
+	if req, _ := s.next(now.Add(wait)); req == nil {
+		t.Error("21st group request not released after waiting")
+	}
+}
+
+func TestSenderPrivateChatsIgnoreGroupLimit(t *testing.T) {
+	clk := newFakeClock()
+	s := newSender(nil, clk, testLimits())
+	now := clk.Now()
+
+	for i := 0; i < 25; i++ {
+		enqueue(s, 42, priorityBulk)
+	}
+	for i := 0; i < 25; i++ {
+		if req, _ := s.next(now); req == nil {
+			t.Fatalf("private request %d not released", i+1)
+		}
+		s.finish(42)
+		now = now.Add(time.Second)
+	}
+}
+
+func TestSenderPriority(t *testing.T) {
+	clk := newFakeClock()
+	s := newSender(nil, clk, testLimits())
+	now := clk.Now()
+
+	enqueue(s, 1, priorityBulk)
+	enqueue(s, 2, priorityBulk)
+	enqueue(s, 3, priorityInteractive)
+
+	want := []int64{3, 1, 2}
+	for _, chatID := range want {
+		req, _ := s.next(now)
+		if req == nil || req.chatID != chatID {
+			t.Fatalf("got %v, want chat %d", req, chatID)
+		}
+	}
+}
+
+func TestSenderKeepsChatOrder(t *testing.T) {
+	clk := newFakeClock()
+	limits := testLimits()
+	// Lift the per-chat limit so only the request in flight holds the chat back
+	limits.ChatPerSecond = 100
+	s := newSender(nil, clk, limits)
+	now := clk.Now()
+
+	first := enqueue(s, 42, priorityBulk)
+	second := enqueue(s, 42, priorityBulk)
+	enqueue(s, 43, priorityBulk)
+
+	if req, _ := s.next(now); req != first {
+		t.Fatal("first request for chat 42 not released")
+	}
+	// Chat 42 waits for its first request, other chats don't
+	if req, _ := s.next(now); req == nil || req.chatID != 43 {
+		t.Fatal("request for chat 43 blocked behind chat 42")
+	}
+	if req, wait := s.next(now); req != nil || wait != 0 {
+		t.Fatalf("next() = %v, %v while chat 42 has a request in flight; want nothing", req, wait)
+	}
+
+	s.finish(42)
+	if req, _ := s.next(now.Add(10 * time.Millisecond)); req != second {
+		t.Error("second request for chat 42 not released after the first finished")
+	}
+}
+
+func TestSenderChatWithBothPriorities(t *testing.T) {
+	clk := newFakeClock()
+	s := newSender(nil, clk, testLimits())
+	now := clk.Now()
+
+	bulk := enqueue(s, 1, priorityBulk)
+	interactive := enqueue(s, 1, priorityInteractive)
+	enqueue(s, 2, priorityBulk)
+
+	if req, _ := s.next(now); req != interactive {
+		t.Fatal("interactive request for chat 1 not released first")
+	}
+	if req, _ := s.next(now); req == nil || req.chatID != 2 {
+		t.Fatal("request for chat 2 blocked behind chat 1 in flight")
+	}
+	if req, wait := s.next(now); req != nil || wait != 0 {
+		t.Fatalf("next() = %v, %v while chat 1 has a request in flight; want nothing", req, wait)
+	}
+
+	s.finish(1)
+	if req, _ := s.next(now.Add(time.Second)); req != bulk {
+		t.Error("bulk request for chat 1 not released after the interactive one finished")
+	}
+}
+
+func TestSenderDropsCancelledRequests(t *testing.T) {
+	clk := newFakeClock()
+	s := newSender(nil, clk, testLimits())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := enqueue(s, 1, priorityBulk)
+	req.ctx = ctx
+	cancel()
+
+	if got, wait := s.next(clk.Now()); got != nil || wait != 0 {
+		t.Fatalf("next() = %v, %v; want nothing to send", got, wait)
+	}
+	select {
+	case res := <-req.result:
+		if res.err != context.Canceled {
+			t.Errorf("err = %v, want context.Canceled", res.err)
+		}
+	default:
+		t.Error("cancelled request was not answered")
+	}
+}
+
+func TestSenderRun(t *testing.T) {
+	clk := newFakeClock()
+	api := &fakeAPI{ch: make(chan int64, 10)}
+	s := newSender(api, clk, testLimits())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.run(ctx)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := s.send(ctx, 7, priorityInteractive, tgbotapi.NewMessage(7, "hi"))
+			errs <- err
+		}()
+	}
+
+	<-api.ch
+	// The second message waits for the per-chat bucket, and the clock doesn't move by itself
+	<-clk.waiting
+	api.mu.Lock()
+	sent := len(api.sent)
+	api.mu.Unlock()
+	if sent != 1 {
+		t.Fatalf("%d messages sent before the clock advanced; want 1", sent)
+	}
+
+	clk.Advance(time.Second)
+	select {
+	case <-api.ch:
+	case <-time.After(time.Second):
+		t.Fatal("second message not sent after the clock advanced")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("send() error = %v", err)
+		}
+	}
+}
+
+// BenchmarkSenderNext picks requests from a backlog of about 10k queued sends, as when
+// thousands of reminders are due at once
+func BenchmarkSenderNext(b *testing.B) {
+	const chats, perChat = 5000, 2
+	clk := newFakeClock()
+	s := newSender(nil, clk, defaultSendLimits())
+	for i := 0; i < perChat; i++ {
+		for chatID := int64(1); chatID <= chats; chatID++ {
+			enqueue(s, chatID, priorityBulk)
+		}
+	}
+
+	now := clk.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req, wait := s.next(now)
+		if req == nil {
+			now = now.Add(wait)
+			continue
+		}
+		s.finish(req.chatID)
+		// Keep the backlog at the same size
+		enqueue(s, req.chatID, priorityBulk)
+		now = now.Add(time.Second / defaultGlobalPerSecond)
+	}
+}
@@ -0,0 +1,334 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	"github.com/dm-popov-sdg/nagger/internal/timezone"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// zonesPerPage is how many timezones the region browser shows at once
+const zonesPerPage = 24
+
+func init() {
+	registerCommands(helpSettings, (*Bot).settingsCommands)
+}
+
+// settingsCommands are the commands for the chat's settings
+func (b *Bot) settingsCommands() []*command {
+	return []*command{
+		{name: "settings", handler: b.handleSettings},
+	}
+}
+
+func (b *Bot) handleSettings(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	settings, err := b.getSettings(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("settings.get_failed"))
+		return
+	}
+
+	if _, err := b.sendHTMLMarkup(ctx, message.Chat.ID, settingsText(p, settings), b.signedKeyboard(message.Chat.ID, settingsKeyboard(p, settings))); err != nil {
+		log.Printf("Error sending settings: %v", err)
+	}
+}
+
+// handleSettingsCallback applies a /settings menu action and redraws the menu in place.
+// Args: the menu action.
+func (b *Bot) handleSettingsCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) callbackAnswer {
+	if len(args) != 1 {
+		return answerStale
+	}
+	chatID := query.Message.Chat.ID
+	action := args[0]
+
+	settings, err := b.getSettings(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		return answerError
+	}
+
+	p := b.printer(ctx, chatID, query.From)
+	menu, ok := applySettingsAction(p, settings, action)
+	if !ok {
+		log.Printf("Invalid settings callback: %s", query.Data)
+		return answerStale
+	}
+
+	answer := answerNone
+	if menu.changed {
+		if denied, ok := b.permitCallback(ctx, query, permSettings, nil); !ok {
+			return denied
+		}
+		settings.UserID = query.From.ID
+		if err := b.storage.SetUserSettings(ctx, settings); err != nil {
+			log.Printf("Error setting user settings: %v", err)
+			return callbackAnswer{key: "settings.save_failed", alert: true}
+		}
+		menu = settingsMenu{text: settingsText(p, settings), keyboard: settingsKeyboard(p, settings)}
+		answer = callbackAnswer{key: "settings.saved_short"}
+	}
+
+	if err := b.editHTML(ctx, chatID, query.Message.MessageID, menu.text, menu.keyboard); err != nil {
+		log.Printf("Error updating settings message: %v", err)
+	}
+	return answer
+}
+
+// settingsMenu is what the /settings message shows after a menu action
+type settingsMenu struct {
+	text     string // HTML
+	keyboard *tgbotapi.InlineKeyboardMarkup
+	changed  bool // The settings changed and need saving
+}
+
+// applySettingsAction applies a /settings menu action to settings and returns the menu
+// to show, or false if the action is unknown or invalid
+func applySettingsAction(p *i18n.Printer, settings *storage.UserSettings, action string) (settingsMenu, bool) {
+	text := settingsText(p, settings)
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	changed := false
+
+	switch {
+	case action == "main":
+		keyboard = settingsKeyboard(p, settings)
+	case action == "hour":
+		keyboard = hourPickerKeyboard(p, settingsCallbackPrefix, settingsCallbackPrefix+"main")
+		text += "\n\n" + escapeHTML(p.T("settings.choose_hour"))
+	case action == "minute":
+		keyboard = minutePickerKeyboard(p, settingsCallbackPrefix, settingsCallbackPrefix+"main")
+		text += "\n\n" + escapeHTML(p.T("settings.choose_minute"))
+	case strings.HasPrefix(action, "h_"):
+		hour, err := strconv.Atoi(strings.TrimPrefix(action, "h_"))
+		if err != nil || hour < 0 || hour > 23 {
+			return settingsMenu{}, false
+		}
+		settings.ReminderTime = fmt.Sprintf("%02d:%s", hour, reminderMinute(settings.ReminderTime))
+		changed = true
+	case strings.HasPrefix(action, "m_"):
+		minute, err := strconv.Atoi(strings.TrimPrefix(action, "m_"))
+		if err != nil || minute < 0 || minute > 59 {
+			return settingsMenu{}, false
+		}
+		settings.ReminderTime = fmt.Sprintf("%s:%02d", reminderHour(settings.ReminderTime), minute)
+		changed = true
+	case action == "tz":
+		keyboard = regionKeyboard(p, settingsCallbackPrefix, settingsCallbackPrefix+"main")
+		text += "\n\n" + escapeHTML(p.T("settings.choose_region"))
+	case strings.HasPrefix(action, "tzr_"):
+		region, page := parseRegionPage(strings.TrimPrefix(action, "tzr_"))
+		keyboard = zoneKeyboard(p, settingsCallbackPrefix, region, page, settingsCallbackPrefix+"tz")
+		text += "\n\n" + escapeHTML(p.T("settings.choose_city", region))
+	case strings.HasPrefix(action, "tzz_"):
+		tzName := strings.TrimPrefix(action, "tzz_")
+		if _, err := timezone.Load(tzName); err != nil {
+			return settingsMenu{}, false
+		}
+		settings.Timezone = tzName
+		changed = true
+	case action == "paused":
+		settings.Paused = !settings.Paused
+		changed = true
+	case action == "weekends":
+		settings.SkipWeekends = !settings.SkipWeekends
+		changed = true
+	case action == "capture":
+		settings.QuickCapture = !settings.QuickCapture
+		changed = true
+	case action == "digest":
+		settings.Digest = !settings.Digest
+		changed = true
+	case action == "close":
+		text += "\n\n" + escapeHTML(p.T("settings.saved"))
+	default:
+		return settingsMenu{}, false
+	}
+	return settingsMenu{text: text, keyboard: keyboard, changed: changed}, true
+}
+
+// settingsText shows the chat's settings in HTML
+func settingsText(p *i18n.Printer, settings *storage.UserSettings) string {
+	localTime := ""
+	if loc, err := timezone.Load(settings.Timezone); err == nil {
+		localTime = p.T("settings.local_time", p.Time(time.Now().In(loc)))
+	}
+
+	var text strings.Builder
+	text.WriteString(formatHTML("⚙️ %s\n\n", bold(p.T("settings.title"))))
+	text.WriteString(escapeHTML(p.T("settings.reminder_time", settings.ReminderTime)) + "\n")
+	text.WriteString(escapeHTML(p.T("settings.timezone", settings.Timezone, localTime)) + "\n")
+	text.WriteString(escapeHTML(p.T("settings.daily_reminders", onOff(p, !settings.Paused))) + "\n")
+	text.WriteString(escapeHTML(p.T("settings.weekend_reminders", onOff(p, !settings.SkipWeekends))))
+	if settings.ChatID > 0 {
+		text.WriteString("\n" + escapeHTML(p.T("settings.quick_capture", onOff(p, settings.QuickCapture))))
+		text.WriteString("\n" + escapeHTML(p.T("settings.digest", onOff(p, settings.Digest))))
+	}
+	return text.String()
+}
+
+func settingsKeyboard(p *i18n.Printer, settings *storage.UserSettings) *tgbotapi.InlineKeyboardMarkup {
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(p.T("settings.hour_button", reminderHour(settings.ReminderTime)), settingsCallbackPrefix+"hour"),
+			tgbotapi.NewInlineKeyboardButtonData(p.T("settings.minute_button", reminderMinute(settings.ReminderTime)), settingsCallbackPrefix+"minute"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(p.T("settings.timezone_button", settings.Timezone), settingsCallbackPrefix+"tz"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(p.T("settings.reminders_button", onOff(p, !settings.Paused)), settingsCallbackPrefix+"paused"),
+			tgbotapi.NewInlineKeyboardButtonData(p.T("settings.weekends_button", onOff(p, !settings.SkipWeekends)), settingsCallbackPrefix+"weekends"),
+		),
+	}
+	// Quick capture and the digest are available in private chats only
+	if settings.ChatID > 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(p.T("settings.capture_button", onOff(p, settings.QuickCapture)), settingsCallbackPrefix+"capture"),
+			tgbotapi.NewInlineKeyboardButtonData(p.T("settings.digest_button", onOff(p, settings.Digest)), settingsCallbackPrefix+"digest"),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(p.T("settings.close_button"), settingsCallbackPrefix+"close"),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// hourPickerKeyboard offers the 24 hours as "<prefix>h_HH" callbacks
+func hourPickerKeyboard(p *i18n.Printer, prefix, back string) *tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for start := 0; start < 24; start += 6 {
+		var row []tgbotapi.InlineKeyboardButton
+		for hour := start; hour < start+6; hour++ {
+			label := fmt.Sprintf("%02d", hour)
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, prefix+"h_"+label))
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, backRow(p, back))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// minutePickerKeyboard offers five-minute steps as "<prefix>m_MM" callbacks
+func minutePickerKeyboard(p *i18n.Printer, prefix, back string) *tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for start := 0; start < 60; start += 20 {
+		var row []tgbotapi.InlineKeyboardButton
+		for minute := start; minute < start+20; minute += 5 {
+			label := fmt.Sprintf("%02d", minute)
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(":"+label, prefix+"m_"+label))
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, backRow(p, back))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// regionKeyboard offers the timezone regions as "<prefix>tzr_<region>" callbacks
+func regionKeyboard(p *i18n.Printer, prefix, back string) *tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, region := range timezone.Regions() {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(region, prefix+"tzr_"+region))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("UTC", prefix+"tzz_UTC"),
+	))
+	rows = append(rows, backRow(p, back))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// zoneKeyboard offers one page of a region's timezones as "<prefix>tzz_<zone>" callbacks
+func zoneKeyboard(p *i18n.Printer, prefix, region string, page int, back string) *tgbotapi.InlineKeyboardMarkup {
+	zones := timezone.ZonesInRegion(region)
+	pages := (len(zones) + zonesPerPage - 1) / zonesPerPage
+	if page < 0 || page >= pages {
+		page = 0
+	}
+
+	start := page * zonesPerPage
+	end := min(start+zonesPerPage, len(zones))
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, zone := range zones[start:end] {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(timezone.CityName(zone), prefix+"tzz_"+zone))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	if pages > 1 {
+		var nav []tgbotapi.InlineKeyboardButton
+		if page > 0 {
+			nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("◀", fmt.Sprintf("%stzr_%s_%d", prefix, region, page-1)))
+		}
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d", page+1, pages), fmt.Sprintf("%stzr_%s_%d", prefix, region, page)))
+		if page < pages-1 {
+			nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("▶", fmt.Sprintf("%stzr_%s_%d", prefix, region, page+1)))
+		}
+		rows = append(rows, nav)
+	}
+	rows = append(rows, backRow(p, back))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// parseRegionPage splits "Europe_2" into the region and page number
+func parseRegionPage(arg string) (string, int) {
+	if i := strings.LastIndex(arg, "_"); i >= 0 {
+		if page, err := strconv.Atoi(arg[i+1:]); err == nil {
+			return arg[:i], page
+		}
+	}
+	return arg, 0
+}
+
+func backRow(p *i18n.Printer, data string) []tgbotapi.InlineKeyboardButton {
+	return tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(p.T("settings.back_button"), data))
+}
+
+func reminderHour(reminderTime string) string {
+	hour, _, _ := strings.Cut(reminderTime, ":")
+	return hour
+}
+
+func reminderMinute(reminderTime string) string {
+	_, minute, _ := strings.Cut(reminderTime, ":")
+	return minute
+}
+
+func onOff(p *i18n.Printer, on bool) string {
+	if on {
+		return p.T("settings.on")
+	}
+	return p.T("settings.off")
+}
@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+)
+
+func TestApplySettingsAction(t *testing.T) {
+	base := storage.UserSettings{ChatID: 1, ReminderTime: "09:30", Timezone: "UTC"}
+
+	tests := []struct {
+		action  string
+		want    func(s *storage.UserSettings) // Changes the action makes
+		changed bool
+		ok      bool
+		button  string // Callback action of the menu's first button, if it has a menu
+		prompt  string // Question added to the text
+	}{
+		{action: "main", ok: true, button: "hour"},
+		{action: "hour", ok: true, button: "h_00", prompt: "Choose the hour:"},
+		{action: "minute", ok: true, button: "m_00", prompt: "Choose the minute:"},
+		{action: "h_07", want: func(s *storage.UserSettings) { s.ReminderTime = "07:30" }, changed: true, ok: true},
+		{action: "h_24"},
+		{action: "h_x"},
+		{action: "m_05", want: func(s *storage.UserSettings) { s.ReminderTime = "09:05" }, changed: true, ok: true},
+		{action: "m_60"},
+		{action: "tz", ok: true, button: "tzr_Africa", prompt: "Choose your region, or share your location:"},
+		{action: "tzr_Europe", ok: true, button: "tzz_Europe/Amsterdam", prompt: "Choose a city in Europe:"},
+		{action: "tzz_Europe/Berlin", want: func(s *storage.UserSettings) { s.Timezone = "Europe/Berlin" }, changed: true, ok: true},
+		{action: "tzz_Mars/Olympus"},
+		{action: "paused", want: func(s *storage.UserSettings) { s.Paused = true }, changed: true, ok: true},
+		{action: "weekends", want: func(s *storage.UserSettings) { s.SkipWeekends = true }, changed: true, ok: true},
+		{action: "capture", want: func(s *storage.UserSettings) { s.QuickCapture = true }, changed: true, ok: true},
+		{action: "digest", want: func(s *storage.UserSettings) { s.Digest = true }, changed: true, ok: true},
+		{action: "close", ok: true, prompt: "✅ Settings saved."},
+		{action: "unknown"},
+	}
+
+	for _, tt := range tests {
+		settings := base
+		menu, ok := applySettingsAction(i18n.For("en"), &settings, tt.action)
+		if ok != tt.ok {
+			t.Errorf("applySettingsAction(%q) ok = %v; want %v", tt.action, ok, tt.ok)
+			continue
+		}
+		want := base
+		if tt.want != nil {
+			tt.want(&want)
+		}
+		if settings != want {
+			t.Errorf("applySettingsAction(%q) settings = %+v; want %+v", tt.action, settings, want)
+		}
+		if !ok {
+			continue
+		}
+		if menu.changed != tt.changed {
+			t.Errorf("applySettingsAction(%q) changed = %v; want %v", tt.action, menu.changed, tt.changed)
+		}
+
+		button := ""
+		if menu.keyboard != nil {
+			button = strings.TrimPrefix(*menu.keyboard.InlineKeyboard[0][0].CallbackData, settingsCallbackPrefix)
+		}
+		if button != tt.button {
+			t.Errorf("applySettingsAction(%q) first button = %q; want %q", tt.action, button, tt.button)
+		}
+		if tt.prompt != "" && !strings.HasSuffix(menu.text, "\n\n"+tt.prompt) {
+			t.Errorf("applySettingsAction(%q) text = %q; want it to end with %q", tt.action, menu.text, tt.prompt)
+		}
+	}
+}
+
+func TestSettingsToggles(t *testing.T) {
+	settings := storage.UserSettings{ChatID: 1, ReminderTime: "09:00", Timezone: "UTC"}
+	for _, action := range []string{"paused", "weekends", "paused"} {
+		if _, ok := applySettingsAction(i18n.For("en"), &settings, action); !ok {
+			t.Fatalf("applySettingsAction(%q) failed", action)
+		}
+	}
+	if settings.Paused || !settings.SkipWeekends {
+		t.Errorf("after pause, weekends, pause: Paused = %v, SkipWeekends = %v; want false, true", settings.Paused, settings.SkipWeekends)
+	}
+
+	text := settingsText(i18n.For("en"), &settings)
+	for _, want := range []string{"🔔 Daily reminders: On", "📅 Weekend reminders: Off"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("settingsText() = %q; want it to contain %q", text, want)
+		}
+	}
+}
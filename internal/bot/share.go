@@ -0,0 +1,335 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// joinPayloadPrefix starts the /start payload of invite deep links: t.me/<bot>?start=join_<token>
+const joinPayloadPrefix = "join_"
+
+// inviteTokenBytes is the entropy of invite tokens; deep link payloads allow 64 characters
+const inviteTokenBytes = 16
+
+func init() {
+	registerCommands(helpShares, (*Bot).shareCommands)
+}
+
+// shareCommands are the commands for lists shared between chats
+func (b *Bot) shareCommands() []*command {
+	return []*command{
+		{name: "share", usage: "[edit|view|list|revoke <n>|leave <n>]", handler: b.handleShare},
+	}
+}
+
+// handleShare manages the sharing of the chat's tasks:
+// /share [edit|view], /share list, /share revoke <n>, /share leave <n>
+func (b *Bot) handleShare(ctx context.Context, message *tgbotapi.Message) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	fields := strings.Fields(message.CommandArguments())
+	sub := ""
+	if len(fields) > 0 {
+		sub = strings.ToLower(fields[0])
+	}
+
+	switch sub {
+	case "", "edit", "view":
+		access := storage.ShareAccessEdit
+		if sub == "view" {
+			access = storage.ShareAccessView
+		}
+		b.createInvite(ctx, p, message, access)
+	case "list":
+		b.sendShares(ctx, p, message.Chat.ID)
+	case "revoke", "leave":
+		if len(fields) != 2 {
+			b.sendMessage(message.Chat.ID, p.T("share.number_usage", sub))
+			return
+		}
+		if sub == "revoke" {
+			b.revokeInvite(ctx, p, message, fields[1])
+		} else {
+			b.leaveList(ctx, p, message, fields[1])
+		}
+	default:
+		b.sendMessage(message.Chat.ID, p.T("share.usage"))
+	}
+}
+
+// createInvite sends deep links that let another chat join this chat's tasks
+func (b *Bot) createInvite(ctx context.Context, p *i18n.Printer, message *tgbotapi.Message, access storage.ShareAccess) {
+	if !b.permit(ctx, message, permSettings, nil) {
+		return
+	}
+
+	token, err := newInviteToken()
+	if err != nil {
+		log.Printf("Error creating invite token: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("share.invite_failed"))
+		return
+	}
+
+	name := message.Chat.Title
+	if name == "" && message.From != nil {
+		name = message.From.FirstName
+	}
+	invite := &storage.ShareInvite{
+		Token:     token,
+		ChatID:    message.Chat.ID,
+		Name:      name,
+		Access:    access,
+		CreatedBy: message.From.ID,
+	}
+	if err := b.storage.CreateShareInvite(ctx, invite); err != nil {
+		log.Printf("Error creating share invite: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("share.invite_failed"))
+		return
+	}
+
+	payload := joinPayloadPrefix + token
+	username := b.api.Self.UserName
+	b.sendMessage(message.Chat.ID, p.T("share.invite", name, accessLabel(p, access), username, payload, username, payload))
+}
+
+// joinList adds the tasks of an invite's chat to the chat /start was sent in
+func (b *Bot) joinList(ctx context.Context, message *tgbotapi.Message, token string) {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	invite, err := b.storage.GetShareInvite(ctx, token)
+	if err != nil {
+		log.Printf("Error getting share invite: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("share.join_failed"))
+		return
+	}
+	if invite == nil {
+		b.sendMessage(message.Chat.ID, p.T("share.invite_invalid"))
+		return
+	}
+	if invite.ChatID == message.Chat.ID {
+		b.sendMessage(message.Chat.ID, p.T("share.invite_own"))
+		return
+	}
+	if !b.permit(ctx, message, permSettings, nil) {
+		return
+	}
+
+	share := &storage.ListShare{
+		OwnerChatID: invite.ChatID,
+		ChatID:      message.Chat.ID,
+		Name:        invite.Name,
+		Access:      invite.Access,
+		Token:       invite.Token,
+		JoinedBy:    message.From.ID,
+	}
+	if err := b.storage.JoinSharedList(ctx, share); err != nil {
+		log.Printf("Error joining shared list: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("share.join_failed"))
+		return
+	}
+
+	b.sendMessage(message.Chat.ID, p.T("share.joined", invite.Name, accessLabel(p, invite.Access)))
+
+	// New chats still need their own reminder time
+	if existing, err := b.storage.GetUserSettings(ctx, message.Chat.ID); err == nil && existing == nil {
+		b.startOnboarding(ctx, message)
+	}
+}
+
+// sendShares lists the chat's invites and the lists it joined
+func (b *Bot) sendShares(ctx context.Context, p *i18n.Printer, chatID int64) {
+	invites, err := b.storage.GetShareInvites(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting share invites: %v", err)
+		b.sendMessage(chatID, p.T("share.get_failed"))
+		return
+	}
+	subscribers, err := b.storage.GetListSubscribers(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting list shares: %v", err)
+		b.sendMessage(chatID, p.T("share.get_failed"))
+		return
+	}
+	joined, err := b.storage.GetListShares(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting list shares: %v", err)
+		b.sendMessage(chatID, p.T("share.get_failed"))
+		return
+	}
+
+	b.sendMessage(chatID, sharesText(p, invites, subscribers, joined))
+}
+
+func sharesText(p *i18n.Printer, invites []storage.ShareInvite, subscribers, joined []storage.ListShare) string {
+	if len(invites) == 0 && len(joined) == 0 {
+		return p.T("share.none")
+	}
+
+	var text strings.Builder
+	text.WriteString(p.T("share.title"))
+	if len(invites) > 0 {
+		text.WriteString("\n\n" + p.T("share.invites"))
+		for i, invite := range invites {
+			chats := 0
+			for _, s := range subscribers {
+				if s.Token == invite.Token {
+					chats++
+				}
+			}
+			text.WriteString("\n" + p.T("share.invite_line", i+1, accessLabel(p, invite.Access), p.Date(invite.CreatedAt), p.N("share.chats_joined", chats)))
+		}
+		text.WriteString("\n" + p.T("share.revoke_hint"))
+	}
+	if len(joined) > 0 {
+		text.WriteString("\n\n" + p.T("share.joined_lists"))
+		for i, share := range joined {
+			text.WriteString(fmt.Sprintf("\n%d. %s (%s)", i+1, share.Name, accessLabel(p, share.Access)))
+		}
+		text.WriteString("\n" + p.T("share.leave_hint"))
+	}
+	return text.String()
+}
+
+func (b *Bot) revokeInvite(ctx context.Context, p *i18n.Printer, message *tgbotapi.Message, arg string) {
+	if !b.permit(ctx, message, permSettings, nil) {
+		return
+	}
+
+	invites, err := b.storage.GetShareInvites(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting share invites: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("share.revoke_failed"))
+		return
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil || n < 1 || n > len(invites) {
+		b.sendMessage(message.Chat.ID, p.N("share.invalid_invite", len(invites)))
+		return
+	}
+
+	removed, err := b.storage.RevokeShareInvite(ctx, message.Chat.ID, invites[n-1].Token)
+	if err != nil {
+		log.Printf("Error revoking share invite: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("share.revoke_failed"))
+		return
+	}
+	b.sendMessage(message.Chat.ID, p.N("share.revoked", int(removed)))
+}
+
+func (b *Bot) leaveList(ctx context.Context, p *i18n.Printer, message *tgbotapi.Message, arg string) {
+	if !b.permit(ctx, message, permSettings, nil) {
+		return
+	}
+
+	joined, err := b.storage.GetListShares(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting list shares: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("share.leave_failed"))
+		return
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil || n < 1 || n > len(joined) {
+		b.sendMessage(message.Chat.ID, p.N("share.invalid_list", len(joined)))
+		return
+	}
+
+	share := joined[n-1]
+	if _, err := b.storage.LeaveSharedList(ctx, share.OwnerChatID, message.Chat.ID); err != nil {
+		log.Printf("Error leaving shared list: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("share.leave_failed"))
+		return
+	}
+	b.sendMessage(message.Chat.ID, p.T("share.left", share.Name))
+}
+
+// chatTasks returns the chat's own tasks followed by the tasks of the lists it joined,
+// in the order /list numbers them, along with those lists
+func (b *Bot) chatTasks(ctx context.Context, chatID int64) ([]storage.Task, []storage.ListShare, error) {
+	tasks, err := b.storage.GetTasksByChatID(ctx, chatID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shares, err := b.storage.GetListShares(ctx, chatID)
+	if err != nil || len(shares) == 0 {
+		return tasks, nil, err
+	}
+	ownerIDs := make([]int64, len(shares))
+	for i, share := range shares {
+		ownerIDs[i] = share.OwnerChatID
+	}
+	shared, err := b.storage.GetTasksByChatIDs(ctx, ownerIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(tasks, shared...), shares, nil
+}
+
+// getTask loads a task of the chat or of a list the chat joined. It returns nil if there is none.
+func (b *Bot) getTask(ctx context.Context, chatID int64, taskID primitive.ObjectID) (*storage.Task, error) {
+	task, err := b.storage.GetTaskForChat(ctx, chatID, taskID)
+	if err != nil || task != nil {
+		return task, err
+	}
+
+	shares, err := b.storage.GetListShares(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	for _, share := range shares {
+		task, err := b.storage.GetTaskForChat(ctx, share.OwnerChatID, taskID)
+		if err != nil || task != nil {
+			return task, err
+		}
+	}
+	return nil, nil
+}
+
+// shareAccess returns the chat's access to another chat's tasks, or "" if it has none
+func (b *Bot) shareAccess(ctx context.Context, chatID, ownerChatID int64) (storage.ShareAccess, error) {
+	shares, err := b.storage.GetListShares(ctx, chatID)
+	if err != nil {
+		return "", err
+	}
+	for _, share := range shares {
+		if share.OwnerChatID == ownerChatID {
+			return share.Access, nil
+		}
+	}
+	return "", nil
+}
+
+// shareNames maps the owner chats of shared lists to the lists' names
+func shareNames(shares []storage.ListShare) map[int64]string {
+	if len(shares) == 0 {
+		return nil
+	}
+	names := make(map[int64]string, len(shares))
+	for _, share := range shares {
+		names[share.OwnerChatID] = share.Name
+	}
+	return names
+}
+
+func accessLabel(p *i18n.Printer, access storage.ShareAccess) string {
+	if access == storage.ShareAccessView {
+		return p.T("share.view_only")
+	}
+	return p.T("share.can_edit")
+}
+
+func newInviteToken() (string, error) {
+	buf := make([]byte, inviteTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
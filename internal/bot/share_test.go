@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+)
+
+func TestSharesText(t *testing.T) {
+	created := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	invites := []storage.ShareInvite{
+		{Token: "a", Access: storage.ShareAccessEdit, CreatedAt: created},
+		{Token: "b", Access: storage.ShareAccessView, CreatedAt: created},
+	}
+	subscribers := []storage.ListShare{{Token: "a", ChatID: 1}, {Token: "a", ChatID: 2}}
+	joined := []storage.ListShare{{OwnerChatID: -100, Name: "Family", Access: storage.ShareAccessView}}
+
+	p := i18n.For("en")
+	got := sharesText(p, invites, subscribers, joined)
+	for _, want := range []string{
+		"1. can complete and edit, created Tue, Mar 5, 2 chats joined",
+		"2. view only, created Tue, Mar 5, 0 chats joined",
+		"1. Family (view only)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("sharesText() = %q; want it to contain %q", got, want)
+		}
+	}
+
+	if got := sharesText(p, nil, nil, nil); !strings.Contains(got, "/share") {
+		t.Errorf("sharesText() without shares = %q; want a hint to /share", got)
+	}
+}
+
+func TestListTextSharedTasks(t *testing.T) {
+	tasks := []storage.Task{
+		{ChatID: 1, Description: "Own", Assignees: []int64{7}},
+		{ChatID: -100, Description: "Shared", Assignees: []int64{8}},
+	}
+	members := []storage.ChatMember{{UserID: 7, FirstName: "Ann"}}
+	shared := shareNames([]storage.ListShare{{OwnerChatID: -100, Name: "Family"}})
+
+	got := listText(i18n.For("en"), tasks, 0, time.UTC, members, shared)
+	if !strings.Contains(got, "1. Own\n   👤 Ann\n") {
+		t.Errorf("listText() = %q; want the own task with its assignee", got)
+	}
+	if !strings.Contains(got, "2. Shared\n   🔗 Family\n") {
+		t.Errorf("listText() = %q; want the shared task with its list", got)
+	}
+}
+
+func TestNewInviteToken(t *testing.T) {
+	token, err := newInviteToken()
+	if err != nil {
+		t.Fatalf("newInviteToken() error = %v", err)
+	}
+	// Deep link payloads allow up to 64 characters of A-Z, a-z, 0-9, _ and -
+	if len(joinPayloadPrefix+token) > 64 {
+		t.Errorf("payload %q is too long", joinPayloadPrefix+token)
+	}
+	if _, err := base64.RawURLEncoding.DecodeString(token); err != nil {
+		t.Errorf("token %q is not URL safe: %v", token, err)
+	}
+	if other, _ := newInviteToken(); other == token {
+		t.Error("tokens should be random")
+	}
+}
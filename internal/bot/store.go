@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"context"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// store is the part of storage.MongoDB used by the bot
+type store interface {
+	ActivateChat(ctx context.Context, chatID int64) (bool, error)
+	AddReminderMessage(ctx context.Context, reminder *storage.ReminderMessage) error
+	AddTask(ctx context.Context, task *storage.Task) error
+	ClaimOutboxMessage(ctx context.Context, lockFor time.Duration) (*storage.OutboxMessage, error)
+	CloseTask(ctx context.Context, taskID primitive.ObjectID) error
+	CompleteTask(ctx context.Context, taskID primitive.ObjectID) error
+	CompleteTaskForMember(ctx context.Context, taskID primitive.ObjectID, userID int64) (*storage.Task, error)
+	CountDeadOutboxMessages(ctx context.Context) (int64, error)
+	CreateShareInvite(ctx context.Context, invite *storage.ShareInvite) error
+	DeactivateChat(ctx context.Context, chatID int64, reason string) error
+	DeadLetterOutboxMessage(ctx context.Context, id primitive.ObjectID, lastError string) error
+	DeleteCapturedTasks(ctx context.Context, chatID int64, captureID string) (int64, error)
+	DeleteConversation(ctx context.Context, chatID, userID int64) (bool, error)
+	DeleteReminderMessage(ctx context.Context, chatID int64, messageID int) error
+	EnqueueOutboxMessage(ctx context.Context, msg *storage.OutboxMessage) error
+	GetChatMemberByUsername(ctx context.Context, chatID int64, username string) (*storage.ChatMember, error)
+	GetChatMembers(ctx context.Context, chatID int64) ([]storage.ChatMember, error)
+	GetChats(ctx context.Context, chatIDs []int64) (map[int64]storage.Chat, error)
+	GetConversation(ctx context.Context, chatID, userID int64) (*storage.Conversation, error)
+	GetDeadOutboxMessages(ctx context.Context, limit int64) ([]storage.OutboxMessage, error)
+	GetListShares(ctx context.Context, chatID int64) ([]storage.ListShare, error)
+	GetListSubscribers(ctx context.Context, ownerChatID int64) ([]storage.ListShare, error)
+	GetReminderMessages(ctx context.Context, chatID int64, day string) ([]storage.ReminderMessage, error)
+	GetShareInvite(ctx context.Context, token string) (*storage.ShareInvite, error)
+	GetShareInvites(ctx context.Context, chatID int64) ([]storage.ShareInvite, error)
+	GetTaskForChat(ctx context.Context, chatID int64, taskID primitive.ObjectID) (*storage.Task, error)
+	GetTasksByChatID(ctx context.Context, chatID int64) ([]storage.Task, error)
+	GetTasksByChatIDs(ctx context.Context, chatIDs []int64) ([]storage.Task, error)
+	GetTasksForUser(ctx context.Context, userID int64) ([]storage.Task, error)
+	GetUserSettings(ctx context.Context, chatID int64) (*storage.UserSettings, error)
+	JoinSharedList(ctx context.Context, share *storage.ListShare) error
+	LeaveSharedList(ctx context.Context, ownerChatID, chatID int64) (bool, error)
+	MarkOutboxMessageSent(ctx context.Context, id primitive.ObjectID) error
+	ReactivateTask(ctx context.Context, taskID primitive.ObjectID) error
+	ReactivateTaskForMember(ctx context.Context, taskID primitive.ObjectID, userID int64) error
+	RemoveChatMember(ctx context.Context, chatID, userID int64) error
+	ReplayOutboxMessages(ctx context.Context, id *primitive.ObjectID) (int64, error)
+	RetryOutboxMessage(ctx context.Context, id primitive.ObjectID, nextAttemptAt time.Time, lastError string, counted bool) error
+	RevokeShareInvite(ctx context.Context, chatID int64, token string) (int64, error)
+	SetChatMemberPaused(ctx context.Context, chatID, userID int64, paused bool) (bool, error)
+	SetChatName(ctx context.Context, chatID int64, name string) error
+	SetChatStreak(ctx context.Context, chatID int64, streak int, day string) error
+	SetChatTitle(ctx context.Context, chatID int64, title string) error
+	SetConversation(ctx context.Context, conv *storage.Conversation) error
+	SetConversationPrompt(ctx context.Context, chatID, userID int64, messageID int) error
+	SetTaskAssignees(ctx context.Context, taskID primitive.ObjectID, assignees []int64) error
+	SetTaskPriority(ctx context.Context, taskID primitive.ObjectID, priority storage.TaskPriority) error
+	SetUserSettings(ctx context.Context, settings *storage.UserSettings) error
+	SnoozeTask(ctx context.Context, taskID primitive.ObjectID, until *time.Time) error
+	UpdateReminderMessage(ctx context.Context, chatID int64, messageID, page int, keyboardHash string) error
+	UpdateTaskDescription(ctx context.Context, taskID primitive.ObjectID, description string) error
+	UpdateTaskRotation(ctx context.Context, taskID primitive.ObjectID, prev, next storage.TaskRotation, newTurn bool) (bool, error)
+	UpsertChatMember(ctx context.Context, member *storage.ChatMember) error
+}
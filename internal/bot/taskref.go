@@ -0,0 +1,416 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Commands refer to tasks by their numbers in /list ("1,3,5-7"), by ID ("#a1b2c3") or
+// by words from their descriptions ("milk").
+const (
+	maxTaskRefs   = 50 // Tasks one command may refer to
+	shortIDLength = 6  // Hex digits of an ID shown to users
+	taskPickLimit = 8  // Buttons offered when words match several tasks
+)
+
+// Actions a picker button performs on the chosen task
+const (
+	taskActionDone   = "d"
+	taskActionDelete = "x"
+)
+
+var (
+	errNoTaskRef    = errors.New("no task given")
+	errBadTaskRange = errors.New("invalid task range")
+	errTooManyTasks = errors.New("too many tasks")
+)
+
+var (
+	taskRangePattern = regexp.MustCompile(`^(\d+)-(\d+)$`)
+	taskIDPattern    = regexp.MustCompile(`^#([0-9a-fA-F]{4,24})$`)
+)
+
+// taskRefs is what a command's argument refers to: task numbers and IDs, or else
+// words to look for in the descriptions
+type taskRefs struct {
+	numbers []int
+	ids     []string // Lowercase hex digits, without the "#"
+	text    string
+}
+
+// parseTaskRefs parses a list of task numbers, ranges and IDs separated by commas or
+// spaces. An argument that isn't such a list is text to match.
+func parseTaskRefs(arg string) (taskRefs, error) {
+	arg = strings.TrimSpace(arg)
+	items := strings.FieldsFunc(arg, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(items) == 0 {
+		return taskRefs{}, errNoTaskRef
+	}
+
+	var refs taskRefs
+	seen := make(map[int]bool)
+	add := func(n int) {
+		if !seen[n] {
+			seen[n] = true
+			refs.numbers = append(refs.numbers, n)
+		}
+	}
+	for _, item := range items {
+		if m := taskIDPattern.FindStringSubmatch(item); m != nil {
+			refs.ids = append(refs.ids, strings.ToLower(m[1]))
+			continue
+		}
+		if m := taskRangePattern.FindStringSubmatch(item); m != nil {
+			from, err1 := strconv.Atoi(m[1])
+			to, err2 := strconv.Atoi(m[2])
+			if err1 != nil || err2 != nil || from > to {
+				return taskRefs{}, errBadTaskRange
+			}
+			if to-from >= maxTaskRefs {
+				return taskRefs{}, errTooManyTasks
+			}
+			for n := from; n <= to; n++ {
+				add(n)
+			}
+			continue
+		}
+		n, err := strconv.Atoi(item)
+		if err != nil || strings.HasPrefix(item, "+") || strings.HasPrefix(item, "-") {
+			// Not a list: look for the words instead
+			return taskRefs{text: arg}, nil
+		}
+		add(n)
+	}
+	if len(refs.numbers)+len(refs.ids) > maxTaskRefs {
+		return taskRefs{}, errTooManyTasks
+	}
+	return refs, nil
+}
+
+// shortID is the part of a task's ID shown to users. The start of an ID is its
+// creation time, so tasks differ in the end.
+func shortID(task storage.Task) string {
+	hex := task.ID.Hex()
+	return hex[len(hex)-shortIDLength:]
+}
+
+// taskIDHTML shows a task's ID the way users can type it
+func taskIDHTML(task storage.Task) safeHTML {
+	return safeHTML("<code>#" + shortID(task) + "</code>")
+}
+
+// resolveTaskRefs finds the tasks numbers and IDs refer to, in the order given.
+// If one of them refers to no task it returns the text that explains why.
+func resolveTaskRefs(p *i18n.Printer, tasks []storage.Task, refs taskRefs) ([]storage.Task, string) {
+	var found []storage.Task
+	seen := make(map[string]bool)
+	add := func(task storage.Task) {
+		if id := task.ID.Hex(); !seen[id] {
+			seen[id] = true
+			found = append(found, task)
+		}
+	}
+
+	for _, n := range refs.numbers {
+		if n < 1 || n > len(tasks) {
+			return nil, p.N("task.invalid_number", len(tasks))
+		}
+		add(tasks[n-1])
+	}
+	for _, id := range refs.ids {
+		var matches []storage.Task
+		for _, task := range tasks {
+			if strings.HasSuffix(task.ID.Hex(), id) {
+				matches = append(matches, task)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, p.T("task.unknown_id", "#"+id)
+		case 1:
+			add(matches[0])
+		default:
+			return nil, p.T("task.ambiguous_id", "#"+id)
+		}
+	}
+	return found, ""
+}
+
+// matchTasks returns the indexes of the tasks whose descriptions match the query best.
+// A description matches if it is the query, if every word of the query starts one of its
+// words or appears in it, or, failing that, if every long word of the query is at most
+// a typo or two away from one of its words.
+func matchTasks(tasks []storage.Task, query string) []int {
+	words := searchWords(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	var matches []int
+	best := 0
+	for i, task := range tasks {
+		score := matchScore(searchWords(task.Description), words)
+		if score == 0 || score < best {
+			continue
+		}
+		if score > best {
+			best, matches = score, nil
+		}
+		matches = append(matches, i)
+	}
+	return matches
+}
+
+// Scores of a match, from worst to best
+const (
+	matchTypo = iota + 1
+	matchInside
+	matchPrefix
+	matchExact
+)
+
+func matchScore(description, query []string) int {
+	if strings.Join(description, " ") == strings.Join(query, " ") {
+		return matchExact
+	}
+
+	score := matchExact
+	for _, q := range query {
+		switch {
+		case anyWord(description, func(w string) bool { return strings.HasPrefix(w, q) }):
+			score = min(score, matchPrefix)
+		case anyWord(description, func(w string) bool { return strings.Contains(w, q) }):
+			score = min(score, matchInside)
+		case anyWord(description, func(w string) bool { return withinTypos(w, q) }):
+			score = min(score, matchTypo)
+		default:
+			return 0
+		}
+	}
+	return score
+}
+
+func anyWord(words []string, match func(string) bool) bool {
+	for _, w := range words {
+		if match(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// withinTypos reports whether word is a typo away from the query word. Short words have
+// to be spelled right, long ones may have two typos.
+func withinTypos(word, query string) bool {
+	n := len([]rune(query))
+	allowed := 0
+	switch {
+	case n >= 8:
+		allowed = 2
+	case n >= 4:
+		allowed = 1
+	}
+	return allowed > 0 && editDistance(word, query) <= allowed
+}
+
+// searchWords splits text into lowercase words, ignoring punctuation
+func searchWords(text string) []string {
+	text = strings.NewReplacer("ё", "е", "Ё", "е").Replace(strings.ToLower(text))
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// editDistance is the Levenshtein distance between two words
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// findTasks looks up the tasks the argument of a command refers to. If words match
+// several tasks, it asks which one was meant with buttons performing action, or, if
+// action is empty, tells the user to pick a number.
+// It tells the user what went wrong and returns nil if there are no such tasks.
+func (b *Bot) findTasks(ctx context.Context, message *tgbotapi.Message, arg, usage, action string) []storage.Task {
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	refs, err := parseTaskRefs(arg)
+	if errors.Is(err, errTooManyTasks) {
+		b.sendMessage(message.Chat.ID, p.T("task.too_many", maxTaskRefs))
+		return nil
+	}
+	if err != nil {
+		b.sendMessage(message.Chat.ID, p.T("task.ref_usage", usage))
+		return nil
+	}
+
+	tasks, _, err := b.chatTasks(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting tasks: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("tasks.get_failed"))
+		return nil
+	}
+
+	if refs.text == "" {
+		found, problem := resolveTaskRefs(p, tasks, refs)
+		if problem != "" {
+			b.sendMessage(message.Chat.ID, problem)
+			return nil
+		}
+		return found
+	}
+
+	matches := matchTasks(tasks, refs.text)
+	switch {
+	case len(matches) == 0:
+		b.sendMessage(message.Chat.ID, p.T("task.no_match", refs.text))
+	case len(matches) == 1:
+		return []storage.Task{tasks[matches[0]]}
+	case action != "":
+		b.askWhichTask(ctx, message, p, tasks, matches, refs.text, action)
+	default:
+		text := p.N("task.ambiguous", len(matches), refs.text)
+		for _, i := range matches {
+			text += fmt.Sprintf("\n%d. %s #%s", i+1, truncate(tasks[i].Description, buttonTextLimit), shortID(tasks[i]))
+		}
+		b.sendMessage(message.Chat.ID, text)
+	}
+	return nil
+}
+
+// findTask is findTasks for commands that work on a single task
+func (b *Bot) findTask(ctx context.Context, message *tgbotapi.Message, arg, usage string) *storage.Task {
+	tasks := b.findTasks(ctx, message, arg, usage, "")
+	switch len(tasks) {
+	case 0:
+		return nil
+	case 1:
+		return &tasks[0]
+	}
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	b.sendMessage(message.Chat.ID, p.T("task.one_only", usage))
+	return nil
+}
+
+// askWhichTask sends buttons for the tasks that match the query. Pressing one performs
+// the action on it.
+func (b *Bot) askWhichTask(ctx context.Context, message *tgbotapi.Message, p *i18n.Printer, tasks []storage.Task, matches []int, query, action string) {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, i := range matches[:min(len(matches), taskPickLimit)] {
+		label := fmt.Sprintf("%d. %s", i+1, truncate(tasks[i].Description, buttonTextLimit))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, callbackData(callbackPickTask, action, tasks[i].ID.Hex())),
+		))
+	}
+
+	keyboard := b.callbacks.signKeyboard(message.Chat.ID, tgbotapi.NewInlineKeyboardMarkup(rows...))
+	if _, err := b.sendHTMLMarkup(ctx, message.Chat.ID, escapeHTML(p.N("task.pick", len(matches), query)), keyboard); err != nil {
+		log.Printf("Error sending task picker: %v", err)
+		b.handleSendError(ctx, message.Chat.ID, err)
+	}
+}
+
+// taskActionPermission returns the permission a picker action needs
+func taskActionPermission(action string) (permission, bool) {
+	switch action {
+	case taskActionDone:
+		return permComplete, true
+	case taskActionDelete:
+		return permClose, true
+	}
+	return "", false
+}
+
+// applyTaskAction performs the action on a task for the user and returns the reply in HTML
+func (b *Bot) applyTaskAction(ctx context.Context, p *i18n.Printer, task *storage.Task, userID int64, action string) string {
+	if action == taskActionDelete {
+		return b.closeTask(ctx, p, task)
+	}
+	return b.completeTask(ctx, p, task, userID)
+}
+
+// applyToTasks performs the action on the tasks for the sender of the message and
+// replies with a line per task
+func (b *Bot) applyToTasks(ctx context.Context, message *tgbotapi.Message, tasks []storage.Task, action string) {
+	if len(tasks) == 0 {
+		return
+	}
+	perm, _ := taskActionPermission(action)
+	p := b.printer(ctx, message.Chat.ID, message.From)
+	var userID int64
+	if message.From != nil {
+		userID = message.From.ID
+	}
+
+	var lines []string
+	for i := range tasks {
+		task := &tasks[i]
+		ok, denied := b.senderAllowed(ctx, message, perm, task)
+		switch {
+		case ok:
+			lines = append(lines, b.applyTaskAction(ctx, p, task, userID, action))
+		case denied == "":
+			// Nobody to answer
+			return
+		case len(tasks) > 1:
+			lines = append(lines, formatHTML("%s: %s", bold(truncate(task.Description, buttonTextLimit)), denied))
+		default:
+			lines = append(lines, escapeHTML(denied))
+		}
+	}
+	b.sendHTML(ctx, message.Chat.ID, strings.Join(lines, "\n"))
+}
+
+// handlePickCallback performs an action on the task chosen among several that matched.
+// Args: action, task ID.
+func (b *Bot) handlePickCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args []string) callbackAnswer {
+	if len(args) != 2 {
+		return answerStale
+	}
+	perm, ok := taskActionPermission(args[0])
+	if !ok {
+		return answerStale
+	}
+
+	task, answer := b.taskForCallback(ctx, query, args[1])
+	if task == nil {
+		return answer
+	}
+	if denied, ok := b.permitCallback(ctx, query, perm, task); !ok {
+		return denied
+	}
+
+	p := b.printer(ctx, query.Message.Chat.ID, query.From)
+	text := b.applyTaskAction(ctx, p, task, query.From.ID, args[0])
+	if err := b.editHTML(ctx, query.Message.Chat.ID, query.Message.MessageID, text, nil); err != nil {
+		log.Printf("Error updating task picker: %v", err)
+	}
+	return answerNone
+}
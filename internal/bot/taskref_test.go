@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseTaskRefs(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    taskRefs
+		wantErr error
+	}{
+		{arg: "3", want: taskRefs{numbers: []int{3}}},
+		{arg: " 1,3,5-7 ", want: taskRefs{numbers: []int{1, 3, 5, 6, 7}}},
+		{arg: "2 1, 2", want: taskRefs{numbers: []int{2, 1}}},
+		{arg: "#A1B2C3", want: taskRefs{ids: []string{"a1b2c3"}}},
+		{arg: "1,#a1b2", want: taskRefs{numbers: []int{1}, ids: []string{"a1b2"}}},
+		{arg: "milk", want: taskRefs{text: "milk"}},
+		{arg: "buy 2 milk", want: taskRefs{text: "buy 2 milk"}},
+		{arg: "#abc", want: taskRefs{text: "#abc"}},
+		{arg: "-1", want: taskRefs{text: "-1"}},
+		{arg: "", wantErr: errNoTaskRef},
+		{arg: " , ", wantErr: errNoTaskRef},
+		{arg: "7-5", wantErr: errBadTaskRange},
+		{arg: "1-1000", wantErr: errTooManyTasks},
+		{arg: "99999999999999999999-99999999999999999999", wantErr: errBadTaskRange},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTaskRefs(tt.arg)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("parseTaskRefs(%q) error = %v; want %v", tt.arg, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTaskRefs(%q) = %+v; want %+v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTasks(t *testing.T) {
+	tasks := []storage.Task{
+		{Description: "Buy milk"},
+		{Description: "Buy oat milk for Anna"},
+		{Description: "Call the plumber"},
+		{Description: "Ёлка: купить гирлянду"},
+		{Description: "Water plants"},
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"buy milk", []int{0}}, // Exact beats prefix
+		{"Milk", []int{0, 1}},  // Ambiguous
+		{"oat", []int{1}},      // Prefix of a word
+		{"plumb", []int{2}},    // Prefix of a word
+		{"lumber", []int{2}},   // Inside a word
+		{"plumbr", []int{2}},   // One typo
+		{"елка", []int{3}},     // ё is е
+		{"гирлянды", []int{3}}, // One typo in Russian
+		{"wter", []int{4}},     // A missing letter
+		{"cat", nil},           // Short words need the right spelling
+		{"milk plumber", nil},  // Every word has to match
+		{"!!!", nil},
+	}
+
+	for _, tt := range tests {
+		if got := matchTasks(tasks, tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("matchTasks(%q) = %v; want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestResolveTaskRefs(t *testing.T) {
+	id := func(hex string) primitive.ObjectID {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	tasks := []storage.Task{
+		{ID: id("650000000000000000a1b2c3"), Description: "One"},
+		{ID: id("650000000000000000d4e5f6"), Description: "Two"},
+		{ID: id("650000000000000000ffb2c3"), Description: "Three"},
+	}
+	p := i18n.For("en")
+
+	tests := []struct {
+		refs    taskRefs
+		want    []string
+		problem string
+	}{
+		{refs: taskRefs{numbers: []int{3, 1}}, want: []string{"Three", "One"}},
+		{refs: taskRefs{numbers: []int{2}, ids: []string{"d4e5f6"}}, want: []string{"Two"}},
+		{refs: taskRefs{ids: []string{"a1b2c3"}}, want: []string{"One"}},
+		{refs: taskRefs{numbers: []int{4}}, problem: "Invalid task number. You have 3 tasks."},
+		{refs: taskRefs{ids: []string{"0000"}}, problem: "No task has the ID #0000."},
+		{refs: taskRefs{ids: []string{"b2c3"}}, problem: "Several tasks have IDs ending in #b2c3. Please give more of the ID."},
+	}
+
+	for _, tt := range tests {
+		found, problem := resolveTaskRefs(p, tasks, tt.refs)
+		if problem != tt.problem {
+			t.Errorf("resolveTaskRefs(%+v) problem = %q; want %q", tt.refs, problem, tt.problem)
+			continue
+		}
+		var got []string
+		for _, task := range found {
+			got = append(got, task.Description)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("resolveTaskRefs(%+v) = %v; want %v", tt.refs, got, tt.want)
+		}
+	}
+
+	if got := shortID(tasks[0]); got != "a1b2c3" {
+		t.Errorf("shortID() = %q; want %q", got, "a1b2c3")
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"milk", "milk", 0},
+		{"milk", "mlik", 2},
+		{"plumber", "plumbr", 1},
+		{"гирлянду", "гирлянды", 1},
+		{"", "abc", 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d; want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// messageTextLimit is the most characters Telegram accepts in a message, after parsing HTML
+	messageTextLimit = 4096
+	// templateSourceLimit caps the length of a reminder template
+	templateSourceLimit = 2048
+)
+
+// reminderData are the variables of a reminder template. Strings are escaped for HTML.
+type reminderData struct {
+	Name      string // First name of the user in a private chat, title of a group
+	Date      string // Today in the chat's language, e.g. "Mon, Jan 2"
+	Pending   int    // Tasks not done today
+	Completed int    // Tasks done today
+	Overdue   int    // Pending tasks added before today
+	Total     int    // Pending and completed tasks
+	Streak    int    // Days in a row a task was completed, up to today or yesterday
+}
+
+// sampleReminderData fills in a template's variables for validation. The values are
+// large so that templates repeating them are checked against the length limit.
+var sampleReminderData = reminderData{
+	Name:      strings.Repeat("W", 64),
+	Date:      "Wed, Sep 30",
+	Pending:   99999,
+	Completed: 99999,
+	Overdue:   99999,
+	Total:     199998,
+	Streak:    99999,
+}
+
+// parseReminderTemplate parses a reminder template
+func parseReminderTemplate(source string) (*template.Template, error) {
+	if utf8.RuneCountInString(source) > templateSourceLimit {
+		return nil, fmt.Errorf("template is longer than %d characters", templateSourceLimit)
+	}
+	tmpl, err := template.New("reminder").Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("{{define}} and {{block}} are not supported")
+	}
+	if err := checkTemplateNode(tmpl.Tree.Root); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// templateFuncs are the functions templates may call. Templates are rendered while the
+// chat's other updates wait, so loops, nested templates and functions like printf, which
+// could run or allocate without bound, are left out. Without them rendering takes time
+// proportional to the template's length.
+var templateFuncs = map[string]bool{
+	"and": true, "or": true, "not": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+}
+
+// checkTemplateNode checks that a template only uses conditions, variables and templateFuncs
+func checkTemplateNode(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkTemplateNode(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.TextNode, *parse.CommentNode:
+		return nil
+	case *parse.ActionNode:
+		return checkTemplatePipe(n.Pipe)
+	case *parse.IfNode:
+		return checkTemplateBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkTemplateBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return fmt.Errorf("{{range}} is not supported")
+	case *parse.TemplateNode:
+		return fmt.Errorf("{{template}} is not supported")
+	}
+	return fmt.Errorf("%s is not supported", node)
+}
+
+func checkTemplateBranch(n *parse.BranchNode) error {
+	if err := checkTemplatePipe(n.Pipe); err != nil {
+		return err
+	}
+	if err := checkTemplateNode(n.List); err != nil {
+		return err
+	}
+	return checkTemplateNode(n.ElseList)
+}
+
+func checkTemplatePipe(pipe *parse.PipeNode) error {
+	if pipe == nil {
+		return nil
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			if err := checkTemplateArg(arg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkTemplateArg(arg parse.Node) error {
+	switch a := arg.(type) {
+	case *parse.IdentifierNode:
+		if !templateFuncs[a.Ident] {
+			return fmt.Errorf("%s is not supported; use and, or, not, eq, ne, lt, le, gt or ge", a.Ident)
+		}
+	case *parse.PipeNode:
+		return checkTemplatePipe(a)
+	case *parse.ChainNode:
+		return checkTemplateArg(a.Node)
+	}
+	return nil
+}
+
+// renderReminderTemplate renders a reminder template into Telegram HTML. It fails if the
+// result isn't valid HTML for Telegram, is empty or exceeds the message length limit.
+func renderReminderTemplate(tmpl *template.Template, data reminderData) (string, error) {
+	// Repeated variables can make the output much longer than the template
+	out := &limitedBuffer{limit: 4 * messageTextLimit}
+	if err := tmpl.Execute(out, data); err != nil {
+		if errors.Is(err, errTemplateTooLong) {
+			return "", fmt.Errorf("the message is longer than %d characters", messageTextLimit)
+		}
+		return "", err
+	}
+
+	text := strings.TrimSpace(out.String())
+	length, err := checkHTML(text)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(htmlPlainText(text)) == "" {
+		return "", fmt.Errorf("the message is empty")
+	}
+	if length > messageTextLimit {
+		return "", fmt.Errorf("the message is %d characters long; Telegram allows %d", length, messageTextLimit)
+	}
+	return text, nil
+}
+
+// validateReminderTemplate checks that a template parses and renders with any data
+func validateReminderTemplate(source string) error {
+	tmpl, err := parseReminderTemplate(source)
+	if err != nil {
+		return err
+	}
+	for _, data := range []reminderData{{}, sampleReminderData} {
+		if _, err := renderReminderTemplate(tmpl, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var errTemplateTooLong = errors.New("template output too long")
+
+// limitedBuffer is a buffer that fails writes beyond its limit
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errTemplateTooLong
+	}
+	return b.Buffer.Write(p)
+}
+
+// reminderTemplate returns the chat's reminder template, or the operator's default
+func (b *Bot) reminderTemplate(settings *storage.UserSettings) string {
+	if settings != nil && settings.ReminderTemplate != "" {
+		return settings.ReminderTemplate
+	}
+	return b.defaultTemplate
+}
+
+// renderReminder renders the chat's reminder template for the tasks. It returns false
+// if the chat uses the built-in reminder, or the template failed to render.
+func (b *Bot) renderReminder(ctx context.Context, chatID int64, tasks []storage.Task) (string, bool) {
+	settings, err := b.storage.GetUserSettings(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+	}
+	source := b.reminderTemplate(settings)
+	if source == "" {
+		return "", false
+	}
+
+	tmpl, err := parseReminderTemplate(source)
+	if err == nil {
+		var text string
+		text, err = renderReminderTemplate(tmpl, b.reminderData(ctx, chatID, tasks, time.Now()))
+		if err == nil {
+			return text, true
+		}
+	}
+	log.Printf("Error rendering reminder template of chat %d, using the default: %v", chatID, err)
+	return "", false
+}
+
+// reminderData collects the variables of the chat's reminder template
+func (b *Bot) reminderData(ctx context.Context, chatID int64, tasks []storage.Task, now time.Time) reminderData {
+	chats, err := b.storage.GetChats(ctx, []int64{chatID})
+	if err != nil {
+		log.Printf("Error getting chat %d: %v", chatID, err)
+	}
+	var chat *storage.Chat
+	if c, ok := chats[chatID]; ok {
+		chat = &c
+	}
+	return newReminderData(b.printer(ctx, chatID, nil), tasks, chat, now.In(b.chatLocation(ctx, chatID)))
+}
+
+// newReminderData works out the variables of a reminder template at now, in the chat's
+// timezone. chat is nil if the bot knows nothing about the chat yet.
+func newReminderData(p *i18n.Printer, tasks []storage.Task, chat *storage.Chat, now time.Time) reminderData {
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	data := reminderData{
+		Date:  escapeHTML(p.Date(now)),
+		Total: len(tasks),
+	}
+	for _, task := range tasks {
+		if task.Status == storage.TaskStatusCompletedToday {
+			data.Completed++
+			continue
+		}
+		data.Pending++
+		if task.CreatedAt.Before(today) {
+			data.Overdue++
+		}
+	}
+
+	if chat != nil {
+		data.Name = escapeHTML(chatName(*chat))
+		data.Streak = currentStreak(*chat, reminderDay(now, loc), reminderDay(now.AddDate(0, 0, -1), loc))
+	}
+	if data.Name == "" {
+		// The name isn't known until the chat sends /start or gets its first reminder
+		data.Name = escapeHTML(p.T("reminder.name_fallback"))
+	}
+	return data
+}
+
+// rememberChatName stores the first name of the user of a private chat for {{.Name}}
+func (b *Bot) rememberChatName(ctx context.Context, chat *tgbotapi.Chat) {
+	if chat == nil || !chat.IsPrivate() || chat.FirstName == "" {
+		return
+	}
+	if err := b.storage.SetChatName(ctx, chat.ID, chat.FirstName); err != nil {
+		log.Printf("Error saving name of chat %d: %v", chat.ID, err)
+	}
+}
+
+// chatName is the group's title, or the first name of the user of a private chat
+func chatName(chat storage.Chat) string {
+	if chat.Title != "" {
+		return chat.Title
+	}
+	return chat.Name
+}
+
+// currentStreak returns the chat's streak on day. A streak that ended before
+// yesterday is broken.
+func currentStreak(chat storage.Chat, day, yesterday string) int {
+	if chat.StreakDay == day || chat.StreakDay == yesterday {
+		return chat.Streak
+	}
+	return 0
+}
+
+// nextStreak returns the chat's streak after completing a task on day
+func nextStreak(chat storage.Chat, day, yesterday string) int {
+	switch chat.StreakDay {
+	case day:
+		return chat.Streak
+	case yesterday:
+		return chat.Streak + 1
+	default:
+		return 1
+	}
+}
+
+// recordStreak counts today towards the chat's streak after a task was completed
+func (b *Bot) recordStreak(ctx context.Context, chatID int64) {
+	loc := b.chatLocation(ctx, chatID)
+	now := time.Now().In(loc)
+	day, yesterday := reminderDay(now, loc), reminderDay(now.AddDate(0, 0, -1), loc)
+
+	chats, err := b.storage.GetChats(ctx, []int64{chatID})
+	if err != nil {
+		log.Printf("Error getting chat %d: %v", chatID, err)
+		return
+	}
+	chat := chats[chatID]
+	if chat.StreakDay == day {
+		return
+	}
+	if err := b.storage.SetChatStreak(ctx, chatID, nextStreak(chat, day, yesterday), day); err != nil {
+		log.Printf("Error saving streak of chat %d: %v", chatID, err)
+	}
+}
+
+func init() {
+	registerCommands(helpTemplates, (*Bot).templateCommands)
+}
+
+// templateCommands are the commands for the chat's reminder template
+func (b *Bot) templateCommands() []*command {
+	return []*command{
+		{name: "template", usage: "[set <template>|preview <template>|reset]", handler: b.handleTemplate},
+	}
+}
+
+// handleTemplate shows or changes the chat's reminder template:
+// /template [set <template>|preview <template>|reset]
+func (b *Bot) handleTemplate(ctx context.Context, message *tgbotapi.Message) {
+	args := strings.TrimSpace(message.CommandArguments())
+	// The template may span several lines
+	sub, source := args, ""
+	if i := strings.IndexAny(args, " \t\n"); i >= 0 {
+		sub, source = args[:i], strings.TrimSpace(args[i:])
+	}
+	sub = strings.ToLower(sub)
+	p := b.printer(ctx, message.Chat.ID, message.From)
+
+	b.rememberChatName(ctx, message.Chat)
+
+	switch sub {
+	case "":
+		b.sendTemplate(ctx, p, message)
+	case "preview":
+		if source == "" {
+			b.sendMessage(message.Chat.ID, p.T("template.preview_usage"))
+			return
+		}
+		b.previewTemplate(ctx, p, message.Chat.ID, source)
+	case "set":
+		if source == "" {
+			b.sendMessage(message.Chat.ID, p.T("template.set_usage"))
+			return
+		}
+		if !b.permit(ctx, message, permSettings, nil) {
+			return
+		}
+		b.saveTemplate(ctx, p, message, source)
+	case "reset":
+		if !b.permit(ctx, message, permSettings, nil) {
+			return
+		}
+		b.saveTemplate(ctx, p, message, "")
+	default:
+		b.sendMessage(message.Chat.ID, p.T("template.usage"))
+	}
+}
+
+// sendTemplate shows the chat's template and the variables it can use
+func (b *Bot) sendTemplate(ctx context.Context, p *i18n.Printer, message *tgbotapi.Message) {
+	settings, err := b.storage.GetUserSettings(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("template.get_failed"))
+		return
+	}
+
+	var text strings.Builder
+	switch source := b.reminderTemplate(settings); {
+	case settings != nil && settings.ReminderTemplate != "":
+		text.WriteString(p.T("template.own") + "\n\n" + source)
+	case source != "":
+		text.WriteString(p.T("template.default") + "\n\n" + source)
+	default:
+		text.WriteString(p.T("template.builtin"))
+	}
+	text.WriteString("\n\n" + p.T("template.help"))
+	b.sendMessage(message.Chat.ID, text.String())
+}
+
+// previewTemplate sends a template rendered with the chat's tasks, or why it is invalid
+func (b *Bot) previewTemplate(ctx context.Context, p *i18n.Printer, chatID int64, source string) {
+	text, err := b.renderTemplateFor(ctx, chatID, source)
+	if err != nil {
+		b.sendMessage(chatID, p.T("template.invalid", err))
+		return
+	}
+	b.sendHTML(ctx, chatID, text)
+}
+
+// saveTemplate validates and stores the chat's template, or clears it if source is empty
+func (b *Bot) saveTemplate(ctx context.Context, p *i18n.Printer, message *tgbotapi.Message, source string) {
+	var preview string
+	if source != "" {
+		var err error
+		if preview, err = b.renderTemplateFor(ctx, message.Chat.ID, source); err != nil {
+			b.sendMessage(message.Chat.ID, p.T("template.invalid", err))
+			return
+		}
+	}
+
+	settings, err := b.getSettings(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error getting user settings: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("template.save_failed"))
+		return
+	}
+	settings.UserID = message.From.ID
+	settings.ReminderTemplate = source
+	if err := b.storage.SetUserSettings(ctx, settings); err != nil {
+		log.Printf("Error setting user settings: %v", err)
+		b.sendMessage(message.Chat.ID, p.T("template.save_failed"))
+		return
+	}
+
+	if source == "" {
+		b.sendMessage(message.Chat.ID, p.T("template.reset"))
+		return
+	}
+	b.sendMessage(message.Chat.ID, p.T("template.saved"))
+	b.sendHTML(ctx, message.Chat.ID, preview)
+}
+
+// renderTemplateFor validates a template and renders it with the chat's tasks
+func (b *Bot) renderTemplateFor(ctx context.Context, chatID int64, source string) (string, error) {
+	if err := validateReminderTemplate(source); err != nil {
+		return "", err
+	}
+	tasks, _, err := b.chatTasks(ctx, chatID)
+	if err != nil {
+		log.Printf("Error getting tasks: %v", err)
+	}
+	tmpl, err := parseReminderTemplate(source)
+	if err != nil {
+		return "", err
+	}
+	return renderReminderTemplate(tmpl, b.reminderData(ctx, chatID, tasks, time.Now()))
+}
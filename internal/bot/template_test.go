@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dm-popov-sdg/nagger/internal/i18n"
+	"github.com/dm-popov-sdg/nagger/internal/storage"
+)
+
+func TestRenderReminderTemplate(t *testing.T) {
+	data := reminderData{Name: "Tom &amp; Jerry", Date: "Mon, Mar 4", Pending: 3, Completed: 1, Overdue: 2, Total: 4, Streak: 5}
+
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "variables",
+			source: "<b>Hi, {{.Name}}!</b> {{.Date}}: {{.Pending}}/{{.Total}} to do, {{.Overdue}} overdue, {{.Completed}} done. 🔥 {{.Streak}}",
+			want:   "<b>Hi, Tom &amp; Jerry!</b> Mon, Mar 4: 3/4 to do, 2 overdue, 1 done. 🔥 5",
+		},
+		{
+			name:   "conditions",
+			source: "{{if eq .Pending 1}}1 task{{else}}{{.Pending}} tasks{{end}}\n",
+			want:   "3 tasks",
+		},
+		{name: "unknown variable", source: "{{.Nope}}", wantErr: true},
+		{name: "syntax error", source: "{{if .Pending}}", wantErr: true},
+		{name: "invalid html", source: "<b>{{.Pending}}", wantErr: true},
+		{name: "empty", source: "{{if false}}x{{end}} <b> </b>", wantErr: true},
+		{name: "too long", source: strings.Repeat("x", messageTextLimit+1), wantErr: true},
+		{name: "loop", source: "{{range 1000000}}{{$.Name}}{{end}}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseReminderTemplate(tt.source)
+			var got string
+			if err == nil {
+				got, err = renderReminderTemplate(tmpl, data)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v; wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewReminderData(t *testing.T) {
+	now := time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC)
+	yesterday := now.AddDate(0, 0, -1)
+	tasks := []storage.Task{
+		{Status: storage.TaskStatusActive, CreatedAt: yesterday},
+		{Status: storage.TaskStatusActive, CreatedAt: now},
+		{Status: storage.TaskStatusCompletedToday, CreatedAt: yesterday},
+	}
+	// The operator's default template is used by chats that never set their own
+	tmpl, err := parseReminderTemplate("Good morning, {{.Name}}! {{.Pending}}/{{.Total}}, {{.Overdue}} overdue, 🔥 {{.Streak}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		language string
+		chat     *storage.Chat
+		want     string
+	}{
+		{"unknown chat", "en", nil, "Good morning, there! 2/3, 1 overdue, 🔥 0"},
+		{"unknown chat in Russian", "ru", nil, "Good morning, друг! 2/3, 1 overdue, 🔥 0"},
+		{"chat without a name", "en", &storage.Chat{ChatID: 1}, "Good morning, there! 2/3, 1 overdue, 🔥 0"},
+		{"private chat", "en", &storage.Chat{ChatID: 1, Name: "Tom & Jerry", Streak: 3, StreakDay: "2024-03-03"}, "Good morning, Tom &amp; Jerry! 2/3, 1 overdue, 🔥 3"},
+		{"group", "en", &storage.Chat{ChatID: -1, Title: "Team"}, "Good morning, Team! 2/3, 1 overdue, 🔥 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := newReminderData(i18n.For(tt.language), tasks, tt.chat, now)
+			got, err := renderReminderTemplate(tmpl, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReminderTemplate(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr bool
+	}{
+		{"🔔 {{.Pending}} tasks", false},
+		{strings.Repeat("x", templateSourceLimit+1), true},
+		// Fits with small numbers, but not with every value the variables can take
+		{strings.Repeat("x", messageTextLimit-2) + "{{.Pending}}", true},
+		{"{{range .Pending}}{{.}}{{end}}", true},
+		// Loops and recursion could keep the chat's updates waiting for a long time
+		{"{{range 300000000}}{{end}}hi", true},
+		{`{{define "a"}}x{{end}}{{template "a"}}`, true},
+		{`{{block "a" .}}x{{end}}`, true},
+		{`{{printf "%999999999d" 1}}`, true},
+		{`{{(printf "%s" .Name)}}`, true},
+		{`{{if and .Pending (gt .Overdue 0)}}{{.Overdue}} overdue{{else}}ok{{end}}`, false},
+		{`{{with $n := .Name}}Hi, {{$n}}{{else}}Hi{{end}}`, false},
+	}
+
+	for _, tt := range tests {
+		if err := validateReminderTemplate(tt.source); (err != nil) != tt.wantErr {
+			t.Errorf("validateReminderTemplate(%.40q) error = %v; wantErr %v", tt.source, err, tt.wantErr)
+		}
+	}
+}
+
+func TestStreak(t *testing.T) {
+	const day, yesterday = "2024-03-04", "2024-03-03"
+
+	tests := []struct {
+		name        string
+		chat        storage.Chat
+		wantCurrent int
+		wantNext    int
+	}{
+		{"no streak", storage.Chat{}, 0, 1},
+		{"completed today", storage.Chat{Streak: 3, StreakDay: day}, 3, 3},
+		{"completed yesterday", storage.Chat{Streak: 3, StreakDay: yesterday}, 3, 4},
+		{"broken", storage.Chat{Streak: 3, StreakDay: "2024-03-01"}, 0, 1},
+	}
+
+	for _, tt := range tests {
+		if got := currentStreak(tt.chat, day, yesterday); got != tt.wantCurrent {
+			t.Errorf("%s: currentStreak() = %d; want %d", tt.name, got, tt.wantCurrent)
+		}
+		if got := nextStreak(tt.chat, day, yesterday); got != tt.wantNext {
+			t.Errorf("%s: nextStreak() = %d; want %d", tt.name, got, tt.wantNext)
+		}
+	}
+}
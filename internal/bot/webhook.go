@@ -0,0 +1,198 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	secretTokenHeader      = "X-Telegram-Bot-Api-Secret-Token"
+	maxUpdateSize          = 1 << 20
+	webhookShutdownTimeout = 10 * time.Second
+)
+
+// allowedUpdates are the update types the bot handles
+var allowedUpdates = []string{"message", "callback_query"}
+
+// WebhookOptions configures webhook mode
+type WebhookOptions struct {
+	URL              string // Public HTTPS URL Telegram posts updates to
+	Listen           string // Address the HTTP server listens on, e.g. ":8443"
+	SecretToken      string // Sent by Telegram in every request, see secretTokenHeader
+	CertFile         string // TLS certificate; without it the server speaks plain HTTP behind a proxy
+	KeyFile          string
+	UploadCert       bool // Send CertFile to Telegram, needed for self-signed certificates
+	MaxConnections   int  // Concurrent connections Telegram may open, 0 for its default
+	DeleteOnShutdown bool // Remove the webhook when the bot stops
+}
+
+// webhookHandler accepts updates posted by Telegram and passes them to submit
+func webhookHandler(secretToken string, submit func(ctx context.Context, update tgbotapi.Update) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := r.Header.Get(secretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secretToken)) != 1 {
+			log.Printf("Rejected webhook request from %s: invalid secret token", r.RemoteAddr)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		var update tgbotapi.Update
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxUpdateSize)).Decode(&update); err != nil {
+			log.Printf("Error decoding webhook update: %v", err)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		// Blocks while the chat's queue is full; Telegram retries if we give up
+		if err := submit(r.Context(), update); err != nil {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// serveWebhook receives updates over HTTP until ctx is cancelled
+func (b *Bot) serveWebhook(ctx context.Context, d *dispatcher) error {
+	opts := b.webhook
+	webhookURL, err := url.Parse(opts.URL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	path := webhookURL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	// The dispatcher's queues are closed once this returns, so wait for every submit
+	var inFlight sync.WaitGroup
+	submit := func(ctx context.Context, update tgbotapi.Update) error {
+		inFlight.Add(1)
+		defer inFlight.Done()
+		return d.submit(ctx, update)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, webhookHandler(opts.SecretToken, submit))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := &http.Server{
+		Addr:              opts.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		var err error
+		if opts.CertFile != "" {
+			err = server.ListenAndServeTLS(opts.CertFile, opts.KeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+	log.Printf("Listening for webhook updates on %s%s", opts.Listen, path)
+
+	if err := b.setWebhook(); err != nil {
+		server.Close()
+		return err
+	}
+	log.Printf("Webhook registered at %s", opts.URL)
+
+	select {
+	case <-ctx.Done():
+	case err := <-serverErr:
+		server.Close()
+		inFlight.Wait()
+		return fmt.Errorf("webhook server failed: %w", err)
+	}
+
+	if opts.DeleteOnShutdown {
+		if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+			log.Printf("Error deleting webhook: %v", err)
+		} else {
+			log.Println("Webhook deleted")
+		}
+	}
+
+	// Let requests that are waiting for queue room finish
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down webhook server: %v", err)
+		// Closing the connections cancels the requests still waiting in submit
+		server.Close()
+	}
+	inFlight.Wait()
+	return nil
+}
+
+// setWebhook registers the webhook. tgbotapi's WebhookConfig has no secret token, so
+// the request is built by hand.
+func (b *Bot) setWebhook() error {
+	opts := b.webhook
+	params := tgbotapi.Params{"url": opts.URL}
+	params.AddNonEmpty("secret_token", opts.SecretToken)
+	params.AddNonZero("max_connections", opts.MaxConnections)
+	if err := params.AddInterface("allowed_updates", allowedUpdates); err != nil {
+		return fmt.Errorf("failed to encode allowed updates: %w", err)
+	}
+
+	var err error
+	if opts.UploadCert {
+		files := []tgbotapi.RequestFile{{Name: "certificate", Data: tgbotapi.FilePath(opts.CertFile)}}
+		_, err = b.api.UploadFiles("setWebhook", params, files)
+	} else {
+		_, err = b.api.MakeRequest("setWebhook", params)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+	return nil
+}
+
+// poll receives updates with long polling until ctx is cancelled
+func (b *Bot) poll(ctx context.Context, d *dispatcher) {
+	// getUpdates is refused while a webhook is set, e.g. after switching modes
+	if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("Error deleting webhook: %v", err)
+	}
+
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+	u.AllowedUpdates = allowedUpdates
+
+	updates := b.api.GetUpdatesChan(u)
+	for {
+		select {
+		case <-ctx.Done():
+			b.api.StopReceivingUpdates()
+			return
+		case update := <-updates:
+			if err := d.submit(ctx, update); err != nil {
+				log.Printf("Dropped update %d during shutdown", update.UpdateID)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	const secret = "s3cret_token"
+	const body = `{"update_id":7,"message":{"message_id":1,"chat":{"id":42,"type":"private"},"text":"hi"}}`
+
+	tests := []struct {
+		name      string
+		method    string
+		token     string
+		body      string
+		submitErr error
+		want      int
+		submitted bool
+	}{
+		{name: "valid update", method: http.MethodPost, token: secret, body: body, want: http.StatusOK, submitted: true},
+		{name: "wrong secret", method: http.MethodPost, token: "guess", body: body, want: http.StatusForbidden},
+		{name: "missing secret", method: http.MethodPost, body: body, want: http.StatusForbidden},
+		{name: "GET", method: http.MethodGet, token: secret, want: http.StatusMethodNotAllowed},
+		{name: "malformed body", method: http.MethodPost, token: secret, body: "{", want: http.StatusBadRequest},
+		{name: "queue full", method: http.MethodPost, token: secret, body: body, submitErr: context.Canceled, want: http.StatusServiceUnavailable, submitted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *tgbotapi.Update
+			h := webhookHandler(secret, func(ctx context.Context, update tgbotapi.Update) error {
+				got = &update
+				return tt.submitErr
+			})
+
+			req := httptest.NewRequest(tt.method, "/telegram", strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set(secretTokenHeader, tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d; want %d", rec.Code, tt.want)
+			}
+			if (got != nil) != tt.submitted {
+				t.Fatalf("submitted = %v; want %v", got != nil, tt.submitted)
+			}
+			if got != nil && (got.UpdateID != 7 || updateChatID(*got) != 42) {
+				t.Errorf("submitted update %d for chat %d; want 7 for chat 42", got.UpdateID, updateChatID(*got))
+			}
+		})
+	}
+}
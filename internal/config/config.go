@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config holds the application configuration
@@ -13,8 +15,31 @@ type Config struct {
 	MongoDB          string
 	ReminderTime     string // Format: "HH:MM" (24-hour format)
 	ReminderTimezone string
+	ReminderTemplate string // text/template for reminders of chats without their own
+	InstanceID       string // Identifies this replica when several bots share one database
+	LeaderLeaseTTL   int    // Seconds a replica keeps the scheduler lease without renewing it
+	AdminIDs         []int64
+	CallbackSecret   string // Signs inline button payloads when set
+	UpdateWorkers    int    // Updates handled concurrently, each chat by one worker
+	UpdateQueueSize  int    // Updates buffered per worker before fetching pauses
+
+	UpdateMode              string // "polling" or "webhook"
+	WebhookURL              string // Public HTTPS URL Telegram posts updates to
+	WebhookListen           string // Address the webhook server listens on
+	WebhookSecret           string // Secret token Telegram sends with every update
+	WebhookCertFile         string // TLS certificate; leave empty behind a TLS-terminating proxy
+	WebhookKeyFile          string
+	WebhookSelfSigned       bool // Upload WebhookCertFile to Telegram
+	WebhookMaxConnections   int
+	WebhookDeleteOnShutdown bool // Remove the webhook on shutdown; disable when running several replicas
 }
 
+// Update modes
+const (
+	UpdateModePolling = "polling"
+	UpdateModeWebhook = "webhook"
+)
+
 // Load reads configuration from environment variables
 func Load() (*Config, error) {
 	cfg := &Config{
@@ -23,8 +48,30 @@ func Load() (*Config, error) {
 		MongoDB:          getEnvOrDefault("MONGO_DB", "nagger"),
 		ReminderTime:     getEnvOrDefault("REMINDER_TIME", "09:00"),
 		ReminderTimezone: getEnvOrDefault("REMINDER_TIMEZONE", "UTC"),
+		ReminderTemplate: os.Getenv("REMINDER_TEMPLATE"),
+		InstanceID:       getEnvOrDefault("INSTANCE_ID", defaultInstanceID()),
+		LeaderLeaseTTL:   getEnvAsIntOrDefault("LEADER_LEASE_TTL", 30),
+		CallbackSecret:   os.Getenv("CALLBACK_SECRET"),
+		UpdateWorkers:    getEnvAsIntOrDefault("UPDATE_WORKERS", 16),
+		UpdateQueueSize:  getEnvAsIntOrDefault("UPDATE_QUEUE_SIZE", 64),
+
+		UpdateMode:              getEnvOrDefault("UPDATE_MODE", UpdateModePolling),
+		WebhookURL:              os.Getenv("WEBHOOK_URL"),
+		WebhookListen:           getEnvOrDefault("WEBHOOK_LISTEN", ":8443"),
+		WebhookSecret:           os.Getenv("WEBHOOK_SECRET"),
+		WebhookCertFile:         os.Getenv("WEBHOOK_CERT_FILE"),
+		WebhookKeyFile:          os.Getenv("WEBHOOK_KEY_FILE"),
+		WebhookSelfSigned:       getEnvAsBoolOrDefault("WEBHOOK_SELF_SIGNED", false),
+		WebhookMaxConnections:   getEnvAsIntOrDefault("WEBHOOK_MAX_CONNECTIONS", 40),
+		WebhookDeleteOnShutdown: getEnvAsBoolOrDefault("WEBHOOK_DELETE_ON_SHUTDOWN", true),
 	}
 
+	adminIDs, err := parseInt64List(os.Getenv("ADMIN_IDS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ADMIN_IDS: %w", err)
+	}
+	cfg.AdminIDs = adminIDs
+
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
@@ -40,9 +87,59 @@ func (c *Config) Validate() error {
 	if c.MongoURI == "" {
 		return fmt.Errorf("MONGO_URI is required")
 	}
+	if c.LeaderLeaseTTL < 3 {
+		return fmt.Errorf("LEADER_LEASE_TTL must be at least 3 seconds")
+	}
+	if c.UpdateWorkers < 1 {
+		return fmt.Errorf("UPDATE_WORKERS must be at least 1")
+	}
+	if c.UpdateQueueSize < 1 {
+		return fmt.Errorf("UPDATE_QUEUE_SIZE must be at least 1")
+	}
+
+	switch c.UpdateMode {
+	case UpdateModePolling:
+	case UpdateModeWebhook:
+		if err := c.validateWebhook(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("UPDATE_MODE must be %q or %q", UpdateModePolling, UpdateModeWebhook)
+	}
+	return nil
+}
+
+func (c *Config) validateWebhook() error {
+	webhookURL, err := url.Parse(c.WebhookURL)
+	if c.WebhookURL == "" || err != nil || webhookURL.Scheme != "https" || webhookURL.Host == "" {
+		return fmt.Errorf("WEBHOOK_URL must be an https:// URL in webhook mode")
+	}
+	// Telegram allows 1-256 characters: letters, digits, _ and -
+	if len(c.WebhookSecret) == 0 || len(c.WebhookSecret) > 256 ||
+		strings.Trim(c.WebhookSecret, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-") != "" {
+		return fmt.Errorf("WEBHOOK_SECRET is required in webhook mode (up to 256 letters, digits, _ or -)")
+	}
+	if (c.WebhookCertFile == "") != (c.WebhookKeyFile == "") {
+		return fmt.Errorf("WEBHOOK_CERT_FILE and WEBHOOK_KEY_FILE must be set together")
+	}
+	if c.WebhookSelfSigned && c.WebhookCertFile == "" {
+		return fmt.Errorf("WEBHOOK_SELF_SIGNED requires WEBHOOK_CERT_FILE")
+	}
+	if c.WebhookMaxConnections < 1 || c.WebhookMaxConnections > 100 {
+		return fmt.Errorf("WEBHOOK_MAX_CONNECTIONS must be between 1 and 100")
+	}
 	return nil
 }
 
+// defaultInstanceID builds a replica identifier that is unique per container
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "nagger"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -58,3 +155,29 @@ func getEnvAsIntOrDefault(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsBoolOrDefault(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// parseInt64List parses a comma-separated list of integers
+func parseInt64List(value string) ([]int64, error) {
+	var result []int64
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, id)
+	}
+	return result, nil
+}
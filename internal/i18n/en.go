@@ -0,0 +1,449 @@
+package i18n
+
+import "time"
+
+var english = &catalog{
+	language: Language{Code: "en", Label: "🇬🇧 English"},
+	form: func(n int) Form {
+		if n == 1 {
+			return One
+		}
+		return Other
+	},
+	date: func(t time.Time) string {
+		return t.Format("Mon, Jan 2")
+	},
+	clock: func(t time.Time) string {
+		return t.Format("3:04 PM")
+	},
+	messages: map[string]string{
+		// Commands, as shown in /help and the command menu
+		"command.add":          "Add a new task; in groups, mentioned members are assigned",
+		"command.addeach":      "Add a group task every member (or every mentioned member) completes individually",
+		"command.mytasks":      "Show your tasks from all chats (private chat)",
+		"command.list":         "Show all active tasks with buttons to manage them",
+		"command.done":         "Mark a task as completed for today",
+		"command.edit":         "Change the text of a task",
+		"command.assign":       "Assign a group task to members, or unassign it",
+		"command.rotate":       "Add a group chore members take turns on",
+		"command.rotation":     "Show upcoming turns, swap them or pause members",
+		"command.delete":       "Close a task permanently (no more reminders)",
+		"command.setreminder":  "Set your daily reminder time (24-hour format)",
+		"command.settings":     "Change your reminder time, timezone and preferences",
+		"command.language":     "Choose the language I speak to you",
+		"command.template":     "Show, preview or change the text of your daily reminder",
+		"command.share":        "Share this chat's tasks with other chats, or stop sharing",
+		"command.permissions":  "Show or change who may complete, edit and close tasks and change settings in a group",
+		"command.quickcapture": "Turn every message you send me into a task (private chats)",
+		"command.cancel":       "Cancel the current question",
+		"command.start":        "Set up the bot",
+		"command.help":         "Show this help message",
+		"command.outbox":       "Show messages that could not be delivered",
+		"command.replay":       "Queue dead-lettered messages for delivery again",
+		"command.stats":        "Show request counts and timings",
+		"command.unknown":      "Unknown command. Use /help to see available commands.",
+
+		"help.title": "Available commands:",
+		"help.body": `Commands that need more details, like /add or /edit, ask for them if you leave them out.
+
+I'll send you a reminder about your tasks every day at your configured time.
+You can also share your location to set your timezone.
+
+Examples:
+/setreminder 09:00 - Set reminder to 9:00 AM in your current timezone
+/setreminder 14:30 America/New_York - Set reminder to 2:30 PM EST/EDT
+/setreminder 08:00 moscow - Timezones can be city names
+/setreminder 07:30 UTC+3 - ...or UTC offsets`,
+		"help.admin": "Admin commands:",
+
+		"conversation.text_only": "Please answer with a text message, or /cancel.",
+
+		"task.gone":          "This task no longer exists.",
+		"task.ref_usage":     "Please say which task: its number from /list, its ID like #a1b2c3 or words from it. Usage: %s",
+		"task.number_hint":   "a task number from /list or words from the task",
+		"task.no_match":      "No task matches \"%s\". Send /list to see your tasks.",
+		"task.unknown_id":    "No task has the ID %s.",
+		"task.ambiguous_id":  "Several tasks have IDs ending in %s. Please give more of the ID.",
+		"task.too_many":      "Please pick at most %d tasks at once.",
+		"task.one_only":      "Please pick a single task. Usage: %s",
+		"tasks.get_failed":   "Failed to get tasks. Please try again.",
+		"add.prompt":         "What's the task?",
+		"add.placeholder":    "Task description",
+		"add.text_only":      "Please send the task as a text message, or /cancel.",
+		"add.failed":         "Failed to add task. Please try again.",
+		"add.added":          "✅ Task added: %s",
+		"add.each_of":        "👥 Each of %s completes it",
+		"add.every_member":   "👥 Every member completes it",
+		"add.assigned":       "👤 Assigned to %s",
+		"done.failed":        "Failed to complete task. Please try again.",
+		"done.completed":     "✅ Task completed: %s",
+		"done.next_turn":     "✅ Task completed: %s\n🔄 Next turn: %s",
+		"done.for_assignees": "This task is for %s.",
+		"done.by_everyone":   "✅ Task completed by everyone: %s",
+		"done.for_you":       "✅ Done for you: %s (%d/%d done)",
+		"edit.prompt_number": "Which task do you want to edit? Send its number from /list or words from it.",
+		"edit.placeholder":   "Task number",
+		"edit.prompt_text":   "Send the new text for: %s",
+		"edit.failed":        "Failed to update task. Please try again.",
+		"edit.updated":       "✏️ Task updated: %s",
+		"delete.failed":      "Failed to close task. Please try again.",
+		"delete.closed":      "🗑️ Task closed: %s",
+
+		"reminder.header":               "🔔 Daily Reminder!",
+		"reminder.name_fallback":        "there",
+		"reminder.prompt_time":          "At what time should I remind you every day? Use 24-hour format HH:MM (e.g., 09:00).",
+		"reminder.placeholder_time":     "HH:MM",
+		"reminder.invalid_time":         "Invalid time format. Please use 24-hour format HH:MM (e.g., 09:00, 14:30)",
+		"reminder.invalid_time_cancel":  "Invalid time format. Please use 24-hour format HH:MM (e.g., 09:00, 14:30), or /cancel.",
+		"reminder.prompt_timezone":      "Which timezone? Send a city or timezone (e.g., Berlin, UTC+3), or \"-\" to keep %s.",
+		"reminder.placeholder_timezone": "Timezone",
+		"reminder.save_failed":          "Failed to save reminder settings. Please try again.",
+		"reminder.set":                  "✅ Reminder time set to %s %s",
+
+		"timezone.save_failed":   "Failed to save your timezone. Please try again.",
+		"timezone.set":           "🌍 Timezone set to %s. Reminders will arrive at %s.",
+		"timezone.set_local":     "🌍 Timezone set to %s (local time %s). Reminders will arrive at %s.",
+		"timezone.unknown":       "Unknown timezone: %s. Use a city (e.g., Berlin), an IANA name (e.g., America/New_York) or a UTC offset (e.g., UTC+3). You can also share your location.",
+		"timezone.did_you_mean":  "Unknown timezone: %s. Did you mean: %s?",
+		"timezone.guess":         "📍 Your location is outside the bot's timezone map. The nearest timezone it knows is %s (local time %s). Is that right?",
+		"timezone.guess_confirm": "✅ Use %s",
+		"timezone.guess_list":    "🗺 Choose from list",
+
+		"language.choose":      "🌐 Language: %s\n\nChoose the language I speak in this chat:",
+		"language.from_app":    "%s (from your Telegram app)",
+		"language.auto_button": "🔄 Like my Telegram app",
+		"language.set":         "✅ Language set to %s.",
+		"language.set_auto":    "✅ I'll speak the language of your Telegram app.",
+		"language.unknown":     "Unknown language: %s. Use one of: %s, or auto.",
+		"language.failed":      "Failed to save the language. Please try again.",
+
+		"error.generic":                  "Something went wrong. Please try again.",
+		"callback.stale":                 "This button is out of date. Send the command again to get a fresh one.",
+		"admin.only":                     "This command is only available to bot administrators.",
+		"rate.limited":                   "You're sending requests too fast. Please wait a moment.",
+		"conversation.cancel_hint":       "Send /cancel to stop.",
+		"conversation.nothing_to_cancel": "Nothing to cancel.",
+		"conversation.cancelled":         "❌ Cancelled.",
+		"stats.header":                   "📊 Requests since start (count, average and max time):",
+		"stats.none":                     "No requests yet.",
+		"stats.route":                    "%s: %d, avg %s, max %s",
+
+		"onboarding.step_done": "This step is already done. Send /start to continue the setup.",
+
+		"onboarding.start_failed": "Failed to start. Please try again.",
+		"onboarding.welcome_back": `Welcome back to Nagger Bot! 🤖
+
+Use /help to see available commands or /settings to change your preferences.`,
+		"onboarding.welcome": `Welcome to Nagger Bot! 🤖
+
+I'll help you manage your tasks and remind you about them every day. Let's set things up, it only takes a minute.`,
+		"onboarding.resume":           "Welcome back! Let's continue where you left off.",
+		"onboarding.language":         "1️⃣ Choose your language:",
+		"onboarding.location":         "2️⃣ Where are you? Share your location so I can pick your timezone.",
+		"onboarding.location_button":  "📍 Share my location",
+		"onboarding.timezone_or_list": "You can also type a city or timezone (e.g., Berlin, UTC+3) or pick it from the list:",
+		"onboarding.timezone":         "2️⃣ Which timezone should reminders use? Type a city or timezone (e.g., Berlin, UTC+3) or pick it from the list:",
+		"onboarding.timezone_retry":   "2️⃣ Type a city or timezone (e.g., Berlin, UTC+3) or pick it from the list:",
+		"onboarding.list_button":      "🗺 Choose from list",
+		"onboarding.keep_button":      "Keep %s",
+		"onboarding.region":           "2️⃣ Choose your region:",
+		"onboarding.city":             "2️⃣ Choose a city in %s:",
+		"onboarding.timezone_done":    "2️⃣ Timezone: %s",
+		"onboarding.timezone_set":     "🌍 Timezone set to %s",
+		"onboarding.hour":             "3️⃣ When should I remind you every day? Choose the hour (%s):",
+		"onboarding.minute":           "3️⃣ Reminder at %02d:__ — now choose the minute:",
+		"onboarding.time_done":        "3️⃣ Reminder time: %s %s",
+		"onboarding.task":             "4️⃣ What's your first task? Just send it to me as a message.",
+		"onboarding.skip_button":      "Skip",
+		"onboarding.task_skipped":     "4️⃣ First task: skipped",
+		"onboarding.language_done":    "1️⃣ Language: %s",
+		"onboarding.failed_continue":  "Something went wrong. Send /start to continue.",
+		"onboarding.text_only":        "Please answer with a text message, or send /start to see the question again.",
+		"onboarding.save_failed":      "Failed to save your settings. Send /start to try again.",
+		"onboarding.done": `🎉 You're all set! I'll remind you every day at %s (%s).
+
+Use /add to add more tasks, /list to see them and /settings to change your preferences. /help shows all commands.`,
+		"settings.get_failed":        "Failed to get settings. Please try again.",
+		"settings.save_failed":       "Failed to save settings. Please try again.",
+		"settings.saved_short":       "✅ Saved",
+		"settings.saved":             "✅ Settings saved.",
+		"settings.title":             "Settings",
+		"settings.reminder_time":     "⏰ Reminder time: %s",
+		"settings.timezone":          "🌍 Timezone: %s%s",
+		"settings.local_time":        " (now %s)",
+		"settings.daily_reminders":   "🔔 Daily reminders: %s",
+		"settings.weekend_reminders": "📅 Weekend reminders: %s",
+		"settings.quick_capture":     "⚡ Quick capture: %s",
+		"settings.digest":            "📬 Daily digest of all chats: %s",
+		"settings.choose_hour":       "Choose the hour:",
+		"settings.choose_minute":     "Choose the minute:",
+		"settings.choose_region":     "Choose your region, or share your location:",
+		"settings.choose_city":       "Choose a city in %s:",
+		"settings.hour_button":       "⏰ Hour: %s",
+		"settings.minute_button":     "Minute: %s",
+		"settings.timezone_button":   "🌍 Timezone: %s",
+		"settings.reminders_button":  "🔔 Reminders: %s",
+		"settings.weekends_button":   "📅 Weekends: %s",
+		"settings.capture_button":    "⚡ Quick capture: %s",
+		"settings.digest_button":     "📬 Digest: %s",
+		"settings.close_button":      "✖️ Close",
+		"settings.back_button":       "⬅️ Back",
+		"settings.on":                "On",
+		"settings.off":               "Off",
+
+		"list.empty":              "You have no active tasks. Great job! 🎉",
+		"list.header":             "Your tasks (%d):",
+		"list.progress":           "👥 %d/%d done",
+		"list.snoozed_until":      "😴 until %s",
+		"list.hint":               "Tap a task to manage it.",
+		"list.edit_prompt":        "Send the new text for: %s",
+		"list.edit_answer":        "✏️ Send the new text",
+		"list.resumed":            "⏰ Reminders resumed",
+		"list.snoozed":            "😴 Snoozed until %s",
+		"list.priority":           "Priority: %s",
+		"list.closed":             "🗑 Task closed",
+		"list.done_next":          "✅ Done! Next: %s",
+		"list.done_today":         "✅ Done for today",
+		"list.undone":             "↩️ Marked as not done",
+		"list.not_assigned":       "This task isn't assigned to you.",
+		"list.done_member":        "✅ Done for you (%d/%d)",
+		"list.snooze_tomorrow":    "Tomorrow",
+		"list.snooze_3_days":      "In 3 days",
+		"list.snooze_week":        "Next week",
+		"list.wake_button":        "⏰ Wake up",
+		"list.back_button":        "◀️ Back",
+		"list.done_button":        "✅ Done",
+		"list.my_part_button":     "✅ My part",
+		"list.undo_button":        "↩️ Undo",
+		"list.priority_high":      "🔺 High",
+		"list.priority_low":       "🔻 Low",
+		"list.priority_normal":    "▪️ Normal",
+		"reminder.waiting_for":    "👤 Waiting for:",
+		"rotation.groups_only":    "Rotations work in group chats.",
+		"rotation.need_members":   "Please name the task and mention at least two members who take turns.",
+		"rotation.need_different": "Please mention at least two different members.",
+		"rotation.usage": `Usage: /rotate <task> @user @user... [daily|weekly|done]
+Example: /rotate dishes @alice @bob @carol daily`,
+		"rotation.command_usage":  "Usage: /rotation, /rotation swap <task> @user @user, /rotation pause @user... or /rotation resume @user...",
+		"rotation.swap_members":   "Please mention the two members who swap. Usage: %s",
+		"rotation.not_rotating":   "%s is not a rotating task. Use /rotate to create one.",
+		"rotation.shared":         "Turns of a shared list's tasks can be changed in their own chat.",
+		"rotation.not_members":    "Both members need to take part in the rotation.",
+		"rotation.swapped":        "🔁 Swapped turns on %s. Up next: %s",
+		"rotation.pause_usage":    "Please mention the members. Usage: /rotation pause @user... or /rotation resume @user...",
+		"rotation.update_failed":  "Failed to update the rotation. Please try again.",
+		"rotation.paused":         "⏸ %s will be skipped in rotations until /rotation resume.",
+		"rotation.resumed":        "▶️ %s takes turns again.",
+		"rotation.paused_members": "⏸ Paused: %s",
+		"rotation.none":           "There are no rotating tasks in this chat. Create one with /rotate <task> @user @user... [daily|weekly|done].",
+		"rotation.title":          "Rotations",
+		"rotation.hint":           "Swap two members with /rotation swap <task> @user @user.",
+		"rotation.skip_button":    "⏭ Skip %s",
+		"rotation.goes_first":     "🔁 %s goes first",
+		"rotation.turn_now":       "⏭ %s's turn now",
+		"rotation.period_weekly":  "changes every Monday",
+		"rotation.period_done":    "changes when done",
+		"rotation.period_daily":   "changes every day",
+		"rotation.turn_now_label": "Now",
+		"rotation.turn_then":      "Then",
+		"rotation.turn_this_week": "This week",
+		"rotation.turn_week_of":   "Week of %s",
+		"rotation.turn_today":     "Today",
+		"rotation.turn_tomorrow":  "Tomorrow",
+		"rotation.line":           "🔄 %s's turn",
+		"rotation.line_then":      "🔄 %s's turn, then %s",
+		"rotation.takes_turns":    "🔄 Takes turns (%s): %s. %s starts.",
+
+		"share.number_usage":  "Please provide a number from /share list. Usage: /share %s <number>",
+		"share.usage":         "Usage: /share [edit|view], /share list, /share revoke <number> or /share leave <number>",
+		"share.invite_failed": "Failed to create the invite. Please try again.",
+		"share.invite": `🔗 Invite to the tasks of %s (%s)
+
+For a person: https://t.me/%s?start=%s
+For a group: https://t.me/%s?startgroup=%s
+
+Anyone with a link can join. Chats that join see these tasks in /list and their own reminders. Revoke the invite with /share list.`,
+		"share.join_failed":    "Failed to join the list. Please try again.",
+		"share.invite_invalid": "This invite link is no longer valid. Ask for a new one.",
+		"share.invite_own":     "This invite is for this chat's own tasks. Send the link to someone else.",
+		"share.joined":         "🔗 You joined the tasks of %s (%s). They now appear in /list and in your daily reminders.",
+		"share.get_failed":     "Failed to get shared lists. Please try again.",
+		"share.none":           "This chat doesn't share its tasks or use anyone else's. Create an invite with /share.",
+		"share.title":          "🔗 Sharing",
+		"share.invites":        "Invites to this chat's tasks:",
+		"share.invite_line":    "%d. %s, created %s, %s",
+		"share.revoke_hint":    "Revoke an invite and remove the chats that joined with it: /share revoke <number>",
+		"share.joined_lists":   "Tasks this chat joined:",
+		"share.leave_hint":     "Stop seeing a list: /share leave <number>",
+		"share.revoke_failed":  "Failed to revoke the invite. Please try again.",
+		"share.leave_failed":   "Failed to leave the list. Please try again.",
+		"share.left":           "👋 Left the tasks of %s.",
+		"share.view_only":      "view only",
+		"share.can_edit":       "can complete and edit",
+
+		"group.former_members":      "former members",
+		"group.addeach_groups_only": "/addeach works in group chats. Use /add for your own tasks.",
+		"group.addeach_prompt":      "What should everyone do?",
+		"group.assign_groups_only":  "Tasks can be assigned in group chats only.",
+		"group.assign_mentions":     "Please mention the members to assign. Usage: %s",
+		"group.assign_shared":       "Tasks from a shared list can be assigned in their own chat.",
+		"group.assign_rotation":     "%s rotates between members. Change its turns with /rotation.",
+		"group.assign_failed":       "Failed to assign task. Please try again.",
+		"group.unassigned":          "Task unassigned: %s",
+		"group.assigned":            "👤 %s assigned to %s",
+		"group.lookup_failed":       "Failed to look up chat members. Please try again.",
+		"group.unknown_members":     "I don't know %s yet. Members can be assigned once they have sent a command or pressed a button in this chat.",
+		"digest.private_only":       "Send /mytasks to me in a private chat to see your tasks from all chats.",
+		"digest.empty":              "You have no tasks in any chat. Great job! 🎉",
+		"digest.header":             "Your tasks in all chats (%d):",
+		"digest.daily_title":        "Daily Digest!",
+		"digest.daily_summary":      "You have %s in %s. Click on a task to mark it as done:",
+		"digest.private_chat":       "Private chat",
+		"digest.group_chat":         "Group chat",
+		"digest.not_yours":          "This task is no longer yours.",
+		"digest.not_member":         "You're no longer a member of that chat.",
+
+		"capture.private_only":      "Quick capture works in private chats only.",
+		"capture.failed":            "Failed to change quick capture. Please try again.",
+		"capture.usage":             "Usage: /quickcapture [on|off]",
+		"capture.on":                "⚡ Quick capture is on. Every message you send me becomes a task, one per line. Forwarded messages become tasks too.",
+		"capture.off":               "Quick capture is off. Use /add to add tasks.",
+		"capture.nothing_to_undo":   "↩️ Nothing to undo, these tasks are already gone.",
+		"capture.from":              "from %s",
+		"capture.forwarded_message": "a forwarded message",
+		"capture.added_one":         "✅ Task added: %s",
+		"capture.more":              "…and %d more",
+
+		"outbox.get_failed": "Failed to get outbox messages. Please try again.",
+		"outbox.empty":      "📭 No dead-lettered messages.",
+		"outbox.header":     "📮 Dead-lettered messages: %d (showing %d)",
+		"outbox.entry": `%s
+chat %d, %s, %s, %s
+%s`,
+		"outbox.hint":          "Use /replay <id> or /replay all to queue them again.",
+		"outbox.replay_usage":  "Please provide a message ID. Usage: /replay <id|all>",
+		"outbox.invalid_id":    "Invalid message ID. Use /outbox to see dead-lettered messages.",
+		"outbox.replay_failed": "Failed to replay messages. Please try again.",
+		"outbox.no_match":      "No dead-lettered messages matched.",
+
+		"template.preview_usage": "Usage: /template preview <template>",
+		"template.set_usage":     "Usage: /template set <template>",
+		"template.usage":         "Usage: /template [set <template>|preview <template>|reset]",
+		"template.get_failed":    "Failed to get the reminder template. Please try again.",
+		"template.own":           "📝 Your reminder template:",
+		"template.default":       "📝 Reminders use the bot's default template:",
+		"template.builtin":       "📝 Reminders use the built-in text.",
+		"template.help": `Change it with /template set followed by the template, e.g.:
+/template set <b>Good morning, {{.Name}}!</b> {{.Pending}} to do, {{.Overdue}} overdue. 🔥 {{.Streak}}
+
+Variables:
+{{.Name}} - your first name, or the group's title
+{{.Date}} - today's date
+{{.Pending}} - tasks not done today
+{{.Completed}} - tasks done today
+{{.Overdue}} - pending tasks added before today
+{{.Total}} - all tasks in the reminder
+{{.Streak}} - days in a row you completed a task
+
+Templates use Go's text/template syntax and Telegram's HTML tags, e.g. <b>, <i> and <code>. Write &lt; &gt; and &amp; for literal < > and &. Try one with /template preview, or go back to the default with /template reset.`,
+		"template.invalid": `❌ The template can't be used: %v
+
+Send /template for help.`,
+		"template.save_failed": "Failed to save the reminder template. Please try again.",
+		"template.reset":       "✅ Reminders use the default text again.",
+		"template.saved":       "✅ Reminder template saved. Your reminders will start like this:",
+
+		"permissions.everyone":        "Everyone",
+		"permissions.owners":          "Creator, assignees and admins",
+		"permissions.admins":          "Admins only",
+		"permissions.complete":        "✅ Complete tasks",
+		"permissions.edit":            "✏️ Edit tasks",
+		"permissions.close":           "🗑 Close tasks",
+		"permissions.settings":        "⚙️ Change settings",
+		"permissions.action_complete": "complete this task",
+		"permissions.action_edit":     "change this task",
+		"permissions.action_close":    "close this task",
+		"permissions.action_settings": "change this chat's settings",
+		"permissions.denied_owners":   "⛔ Only the task's creator, its assignees and chat admins can %s.",
+		"permissions.denied_admins":   "⛔ Only chat admins can %s.",
+		"permissions.view_only":       "🔗 This task is from a shared list you can only view.",
+		"permissions.usage":           "Usage: /permissions [complete|edit|close|settings] [everyone|owners|admins]",
+		"permissions.groups_only":     "Permissions apply to group chats. In private chats you can do everything.",
+		"permissions.admins_only":     "⛔ Only chat admins can change permissions.",
+		"permissions.title":           "Permissions",
+		"permissions.hint":            "Chat admins can tap a permission to change who may use it.",
+	},
+	plurals: map[string]Plural{
+		"task.invalid_number": {
+			One:   "Invalid task number. You have %d task.",
+			Other: "Invalid task number. You have %d tasks.",
+		},
+		"task.pick": {
+			One:   "%d task matches \"%s\". Is this the one?",
+			Other: "%d tasks match \"%s\". Which one did you mean?",
+		},
+		"task.ambiguous": {
+			One:   "%d task matches \"%s\". Please use its number:",
+			Other: "%d tasks match \"%s\". Please use one of their numbers:",
+		},
+		"reminder.active": {
+			One:   "You have %d active task:",
+			Other: "You have %d active tasks:",
+		},
+		"reminder.active_tap": {
+			One:   "You have %d active task. Click on a task to mark it as done:",
+			Other: "You have %d active tasks. Click on a task to mark it as done:",
+		},
+		"stats.panics": {
+			One:   ", %d panic",
+			Other: ", %d panics",
+		},
+		"stats.limited": {
+			Other: ", %d rate limited",
+		},
+		"share.chats_joined": {
+			One:   "%d chat joined",
+			Other: "%d chats joined",
+		},
+		"share.invalid_invite": {
+			One:   "Invalid invite number. This chat has %d invite, see /share list.",
+			Other: "Invalid invite number. This chat has %d invites, see /share list.",
+		},
+		"share.invalid_list": {
+			One:   "Invalid list number. This chat joined %d list, see /share list.",
+			Other: "Invalid list number. This chat joined %d lists, see /share list.",
+		},
+		"share.revoked": {
+			One:   "🚫 Invite revoked. %d chat no longer sees this chat's tasks.",
+			Other: "🚫 Invite revoked. %d chats no longer see this chat's tasks.",
+		},
+		"digest.tasks": {
+			One:   "%d task",
+			Other: "%d tasks",
+		},
+		"digest.chats": {
+			One:   "%d chat",
+			Other: "%d chats",
+		},
+		"digest.more": {
+			One:   "…and %d more task.",
+			Other: "…and %d more tasks.",
+		},
+		"capture.removed": {
+			One:   "↩️ Removed %d task.",
+			Other: "↩️ Removed %d tasks.",
+		},
+		"capture.added": {
+			One:   "✅ Added %d task:",
+			Other: "✅ Added %d tasks:",
+		},
+		"outbox.attempts": {
+			One:   "%d attempt",
+			Other: "%d attempts",
+		},
+		"outbox.queued": {
+			One:   "🔁 Queued %d message for delivery.",
+			Other: "🔁 Queued %d messages for delivery.",
+		},
+	},
+}
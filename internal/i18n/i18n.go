@@ -0,0 +1,147 @@
+// Package i18n translates the bot's messages and formats dates in the user's language.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Default is the language used when the user's language isn't supported
+const Default = "en"
+
+// Language is an interface language the bot speaks
+type Language struct {
+	Code  string // ISO 639-1 code, as in Telegram's language_code
+	Label string // Name of the language in itself, for buttons
+}
+
+// Form is a plural category, as defined by the Unicode CLDR
+type Form int
+
+const (
+	Other Form = iota
+	One
+	Few
+	Many
+)
+
+// Plural is a message with a text per plural category. Categories a language
+// doesn't use are left out.
+type Plural map[Form]string
+
+// catalog holds the messages of one language
+type catalog struct {
+	language Language
+	messages map[string]string
+	plurals  map[string]Plural
+	form     func(n int) Form         // Plural rule
+	date     func(t time.Time) string // Weekday, day and month, e.g. "Mon, Jan 2"
+	clock    func(t time.Time) string // Time of day, e.g. "3:04 PM"
+}
+
+// catalogs are the supported languages, in the order they are offered
+var catalogs = []*catalog{english, russian}
+
+// Languages returns the supported languages
+func Languages() []Language {
+	languages := make([]Language, len(catalogs))
+	for i, c := range catalogs {
+		languages[i] = c.language
+	}
+	return languages
+}
+
+// Match returns the supported language for a code such as "ru" or Telegram's "en-US",
+// or "" if the language isn't supported
+func Match(code string) string {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(code)), "-")
+	base, _, _ = strings.Cut(base, "_")
+	for _, c := range catalogs {
+		if c.language.Code == base {
+			return base
+		}
+	}
+	return ""
+}
+
+// Label returns the name of a supported language in itself, or the code if it isn't supported
+func Label(code string) string {
+	if c := find(code); c != nil {
+		return c.language.Label
+	}
+	return code
+}
+
+func find(code string) *catalog {
+	code = Match(code)
+	for _, c := range catalogs {
+		if c.language.Code == code {
+			return c
+		}
+	}
+	return nil
+}
+
+// Printer translates messages into one language
+type Printer struct {
+	cat *catalog
+}
+
+// For returns the printer for a language code, falling back to the default language
+func For(code string) *Printer {
+	if c := find(code); c != nil {
+		return &Printer{cat: c}
+	}
+	return &Printer{cat: english}
+}
+
+// Language returns the code of the printer's language
+func (p *Printer) Language() string {
+	return p.cat.language.Code
+}
+
+// T translates a message and formats it with args like fmt.Sprintf. Messages missing
+// from the language are printed in English, and unknown keys as the key itself.
+func (p *Printer) T(key string, args ...any) string {
+	format, ok := p.cat.messages[key]
+	if !ok {
+		format, ok = english.messages[key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// N translates a message that depends on the count n, picking the plural form of the
+// language. The message is formatted with n followed by args.
+func (p *Printer) N(key string, n int, args ...any) string {
+	plural, ok := p.cat.plurals[key]
+	form := p.cat.form(n)
+	if !ok {
+		plural, form = english.plurals[key], english.form(n)
+	}
+	if plural == nil {
+		return key
+	}
+
+	format, ok := plural[form]
+	if !ok {
+		format = plural[Other]
+	}
+	return fmt.Sprintf(format, append([]any{n}, args...)...)
+}
+
+// Date formats the weekday, day and month of t, e.g. "Mon, Jan 2" or "пн, 2 янв."
+func (p *Printer) Date(t time.Time) string {
+	return p.cat.date(t)
+}
+
+// Time formats the time of day of t the way the language writes it, e.g. "3:04 PM" or "15:04"
+func (p *Printer) Time(t time.Time) string {
+	return p.cat.clock(t)
+}
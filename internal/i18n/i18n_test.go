@@ -0,0 +1,148 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"en", "en"},
+		{"ru", "ru"},
+		{"RU", "ru"},
+		{"en-US", "en"},
+		{"pt_BR", ""},
+		{"de", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.code); got != tt.want {
+			t.Errorf("Match(%q) = %q; want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestForFallsBack(t *testing.T) {
+	if got := For("de").Language(); got != Default {
+		t.Errorf("For(de).Language() = %q; want %q", got, Default)
+	}
+	if got := For("ru-RU").T("task.gone"); got != "Этой задачи больше нет." {
+		t.Errorf("T(task.gone) = %q", got)
+	}
+	if got := For("ru").T("no.such.key"); got != "no.such.key" {
+		t.Errorf("T(unknown) = %q; want the key", got)
+	}
+}
+
+func TestRussianPlurals(t *testing.T) {
+	p := For("ru")
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{1, "У вас 1 задача."},
+		{2, "У вас 2 задачи."},
+		{4, "У вас 4 задачи."},
+		{5, "У вас 5 задач."},
+		{11, "У вас 11 задач."},
+		{12, "У вас 12 задач."},
+		{21, "У вас 21 задача."},
+		{22, "У вас 22 задачи."},
+		{111, "У вас 111 задач."},
+		{0, "У вас 0 задач."},
+	}
+
+	for _, tt := range tests {
+		if got := p.N("task.invalid_number", tt.n); !strings.HasSuffix(got, tt.want) {
+			t.Errorf("N(%d) = %q; want it to end with %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestEnglishPlurals(t *testing.T) {
+	p := For("en")
+	if got := p.N("reminder.active", 1); got != "You have 1 active task:" {
+		t.Errorf("N(1) = %q", got)
+	}
+	if got := p.N("reminder.active", 3); got != "You have 3 active tasks:" {
+		t.Errorf("N(3) = %q", got)
+	}
+}
+
+func TestDate(t *testing.T) {
+	day := time.Date(2024, time.March, 4, 12, 0, 0, 0, time.UTC)
+	if got := For("en").Date(day); got != "Mon, Mar 4" {
+		t.Errorf("en Date() = %q", got)
+	}
+	if got := For("ru").Date(day); got != "пн, 4 мар." {
+		t.Errorf("ru Date() = %q", got)
+	}
+}
+
+func TestTime(t *testing.T) {
+	evening := time.Date(2024, time.March, 4, 18, 5, 0, 0, time.UTC)
+	if got := For("en").Time(evening); got != "6:05 PM" {
+		t.Errorf("en Time() = %q", got)
+	}
+	if got := For("ru").Time(evening); got != "18:05" {
+		t.Errorf("ru Time() = %q", got)
+	}
+}
+
+// TestCatalogsComplete makes sure every language translates every message with the
+// same placeholders, so a translation can't break formatting
+func TestCatalogsComplete(t *testing.T) {
+	for _, c := range catalogs {
+		for key, text := range english.messages {
+			translated, ok := c.messages[key]
+			if !ok {
+				t.Errorf("%s: missing %q", c.language.Code, key)
+				continue
+			}
+			if verbs(translated) != verbs(text) {
+				t.Errorf("%s: %q has placeholders %q; want %q", c.language.Code, key, verbs(translated), verbs(text))
+			}
+		}
+		for key := range c.messages {
+			if _, ok := english.messages[key]; !ok {
+				t.Errorf("%s: %q is not an English message", c.language.Code, key)
+			}
+		}
+		for key, plural := range english.plurals {
+			translated, ok := c.plurals[key]
+			if !ok {
+				t.Errorf("%s: missing plural %q", c.language.Code, key)
+				continue
+			}
+			for n := 0; n < 200; n++ {
+				form := c.form(n)
+				if _, ok := translated[form]; !ok && translated[Other] == "" {
+					t.Errorf("%s: plural %q has no form for %d", c.language.Code, key, n)
+					break
+				}
+			}
+			for _, text := range translated {
+				if verbs(text) != verbs(plural[Other]) {
+					t.Errorf("%s: plural %q has placeholders %q; want %q", c.language.Code, key, verbs(text), verbs(plural[Other]))
+				}
+			}
+		}
+	}
+}
+
+// verbs lists the fmt verbs of a format string
+func verbs(format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format)-1; i++ {
+		if format[i] == '%' {
+			b.WriteByte(format[i+1])
+			i++
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,480 @@
+package i18n
+
+import (
+	"fmt"
+	"time"
+)
+
+var (
+	russianWeekdays = [...]string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"}
+	russianMonths   = [...]string{"янв.", "февр.", "мар.", "апр.", "мая", "июн.", "июл.", "авг.", "сент.", "окт.", "нояб.", "дек."}
+)
+
+var russian = &catalog{
+	language: Language{Code: "ru", Label: "🇷🇺 Русский"},
+	form: func(n int) Form {
+		if n < 0 {
+			n = -n
+		}
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return One
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return Few
+		default:
+			return Many
+		}
+	},
+	date: func(t time.Time) string {
+		return fmt.Sprintf("%s, %d %s", russianWeekdays[t.Weekday()], t.Day(), russianMonths[t.Month()-1])
+	},
+	clock: func(t time.Time) string {
+		return t.Format("15:04")
+	},
+	messages: map[string]string{
+		"command.add":          "Добавить задачу; в группах упомянутые участники становятся исполнителями",
+		"command.addeach":      "Добавить групповую задачу, которую каждый участник (или каждый упомянутый) выполняет сам",
+		"command.mytasks":      "Показать ваши задачи из всех чатов (в личном чате)",
+		"command.list":         "Показать все активные задачи с кнопками для управления",
+		"command.done":         "Отметить задачу выполненной на сегодня",
+		"command.edit":         "Изменить текст задачи",
+		"command.assign":       "Назначить групповую задачу участникам или снять назначение",
+		"command.rotate":       "Добавить групповое дело, которое участники делают по очереди",
+		"command.rotation":     "Показать очередь, поменять очередь или поставить участников на паузу",
+		"command.delete":       "Закрыть задачу навсегда (без напоминаний)",
+		"command.setreminder":  "Задать время ежедневного напоминания (24-часовой формат)",
+		"command.settings":     "Изменить время напоминания, часовой пояс и настройки",
+		"command.language":     "Выбрать язык, на котором я с вами говорю",
+		"command.template":     "Показать, проверить или изменить текст ежедневного напоминания",
+		"command.share":        "Поделиться задачами этого чата с другими чатами или закрыть доступ",
+		"command.permissions":  "Показать или изменить, кто в группе может выполнять, изменять и закрывать задачи и менять настройки",
+		"command.quickcapture": "Превращать каждое ваше сообщение в задачу (в личных чатах)",
+		"command.cancel":       "Отменить текущий вопрос",
+		"command.start":        "Настроить бота",
+		"command.help":         "Показать эту справку",
+		"command.outbox":       "Показать сообщения, которые не удалось доставить",
+		"command.replay":       "Снова поставить недоставленные сообщения в очередь",
+		"command.stats":        "Показать число запросов и время их обработки",
+		"command.unknown":      "Неизвестная команда. Список команд: /help",
+
+		"help.title": "Доступные команды:",
+		"help.body": `Если не указать подробности для команд вроде /add или /edit, я о них спрошу.
+
+Каждый день в выбранное время я напомню вам о задачах.
+Чтобы задать часовой пояс, можно отправить свою геопозицию.
+
+Примеры:
+/setreminder 09:00 - Напоминать в 9:00 в вашем текущем часовом поясе
+/setreminder 14:30 America/New_York - Напоминать в 14:30 по Нью-Йорку
+/setreminder 08:00 москва - Часовой пояс можно указать городом
+/setreminder 07:30 UTC+3 - ...или смещением от UTC`,
+		"help.admin": "Команды администратора:",
+
+		"conversation.text_only": "Пожалуйста, ответьте текстовым сообщением или отправьте /cancel.",
+
+		"task.gone":          "Этой задачи больше нет.",
+		"task.ref_usage":     "Укажите задачу: её номер из /list, ID вида #a1b2c3 или слова из неё. Использование: %s",
+		"task.number_hint":   "номер задачи из /list или слова из неё",
+		"task.no_match":      "Нет задач, подходящих под «%s». Отправьте /list, чтобы увидеть задачи.",
+		"task.unknown_id":    "Нет задачи с ID %s.",
+		"task.ambiguous_id":  "ID нескольких задач заканчиваются на %s. Укажите ID подробнее.",
+		"task.too_many":      "Можно выбрать не больше %d задач за раз.",
+		"task.one_only":      "Выберите одну задачу. Использование: %s",
+		"tasks.get_failed":   "Не удалось получить задачи. Попробуйте ещё раз.",
+		"add.prompt":         "Какая задача?",
+		"add.placeholder":    "Описание задачи",
+		"add.text_only":      "Пожалуйста, отправьте задачу текстовым сообщением или отправьте /cancel.",
+		"add.failed":         "Не удалось добавить задачу. Попробуйте ещё раз.",
+		"add.added":          "✅ Задача добавлена: %s",
+		"add.each_of":        "👥 Выполняет каждый: %s",
+		"add.every_member":   "👥 Выполняет каждый участник",
+		"add.assigned":       "👤 Исполнители: %s",
+		"done.failed":        "Не удалось выполнить задачу. Попробуйте ещё раз.",
+		"done.completed":     "✅ Задача выполнена: %s",
+		"done.next_turn":     "✅ Задача выполнена: %s\n🔄 Следующая очередь: %s",
+		"done.for_assignees": "Эта задача для: %s.",
+		"done.by_everyone":   "✅ Задачу выполнили все: %s",
+		"done.for_you":       "✅ Вы выполнили: %s (выполнено %d из %d)",
+		"edit.prompt_number": "Какую задачу изменить? Отправьте её номер из /list или слова из неё.",
+		"edit.placeholder":   "Номер задачи",
+		"edit.prompt_text":   "Отправьте новый текст для: %s",
+		"edit.failed":        "Не удалось изменить задачу. Попробуйте ещё раз.",
+		"edit.updated":       "✏️ Задача изменена: %s",
+		"delete.failed":      "Не удалось закрыть задачу. Попробуйте ещё раз.",
+		"delete.closed":      "🗑️ Задача закрыта: %s",
+
+		"reminder.header":               "🔔 Ежедневное напоминание!",
+		"reminder.name_fallback":        "друг",
+		"reminder.prompt_time":          "Во сколько напоминать вам каждый день? Используйте 24-часовой формат ЧЧ:ММ (например, 09:00).",
+		"reminder.placeholder_time":     "ЧЧ:ММ",
+		"reminder.invalid_time":         "Неверный формат времени. Используйте 24-часовой формат ЧЧ:ММ (например, 09:00, 14:30)",
+		"reminder.invalid_time_cancel":  "Неверный формат времени. Используйте 24-часовой формат ЧЧ:ММ (например, 09:00, 14:30) или отправьте /cancel.",
+		"reminder.prompt_timezone":      "Какой часовой пояс? Отправьте город или часовой пояс (например, Москва, UTC+3) или «-», чтобы оставить %s.",
+		"reminder.placeholder_timezone": "Часовой пояс",
+		"reminder.save_failed":          "Не удалось сохранить настройки напоминания. Попробуйте ещё раз.",
+		"reminder.set":                  "✅ Время напоминания: %s %s",
+
+		"timezone.save_failed":   "Не удалось сохранить часовой пояс. Попробуйте ещё раз.",
+		"timezone.set":           "🌍 Часовой пояс: %s. Напоминания будут приходить в %s.",
+		"timezone.set_local":     "🌍 Часовой пояс: %s (местное время %s). Напоминания будут приходить в %s.",
+		"timezone.unknown":       "Неизвестный часовой пояс: %s. Укажите город (например, Берлин), название IANA (например, America/New_York) или смещение от UTC (например, UTC+3). Можно также отправить геопозицию.",
+		"timezone.did_you_mean":  "Неизвестный часовой пояс: %s. Может быть, вы имели в виду: %s?",
+		"timezone.guess":         "📍 Ваше местоположение за пределами карты часовых поясов бота. Ближайший известный ей часовой пояс — %s (местное время %s). Всё верно?",
+		"timezone.guess_confirm": "✅ Использовать %s",
+		"timezone.guess_list":    "🗺 Выбрать из списка",
+
+		"language.choose":      "🌐 Язык: %s\n\nВыберите язык, на котором я говорю в этом чате:",
+		"language.from_app":    "%s (как в вашем приложении Telegram)",
+		"language.auto_button": "🔄 Как в приложении Telegram",
+		"language.set":         "✅ Язык: %s.",
+		"language.set_auto":    "✅ Я буду говорить на языке вашего приложения Telegram.",
+		"language.unknown":     "Неизвестный язык: %s. Доступны: %s или auto.",
+		"language.failed":      "Не удалось сохранить язык. Попробуйте ещё раз.",
+
+		"error.generic":                  "Что-то пошло не так. Попробуйте ещё раз.",
+		"callback.stale":                 "Эта кнопка устарела. Отправьте команду ещё раз, чтобы получить новую.",
+		"admin.only":                     "Эта команда доступна только администраторам бота.",
+		"rate.limited":                   "Вы отправляете запросы слишком часто. Подождите немного.",
+		"conversation.cancel_hint":       "Отправьте /cancel, чтобы прекратить.",
+		"conversation.nothing_to_cancel": "Нечего отменять.",
+		"conversation.cancelled":         "❌ Отменено.",
+		"stats.header":                   "📊 Запросы с момента запуска (количество, среднее и максимальное время):",
+		"stats.none":                     "Запросов пока не было.",
+		"stats.route":                    "%s: %d, в среднем %s, максимум %s",
+
+		"onboarding.step_done": "Этот шаг уже пройден. Отправьте /start, чтобы продолжить настройку.",
+
+		"onboarding.start_failed": "Не удалось начать. Попробуйте ещё раз.",
+		"onboarding.welcome_back": `С возвращением в Nagger Bot! 🤖
+
+Отправьте /help, чтобы увидеть список команд, или /settings, чтобы изменить настройки.`,
+		"onboarding.welcome": `Добро пожаловать в Nagger Bot! 🤖
+
+Я помогу вести список задач и буду каждый день о них напоминать. Давайте всё настроим, это займёт минуту.`,
+		"onboarding.resume":           "С возвращением! Продолжим с того места, где вы остановились.",
+		"onboarding.language":         "1️⃣ Выберите язык:",
+		"onboarding.location":         "2️⃣ Где вы находитесь? Поделитесь геопозицией, и я подберу ваш часовой пояс.",
+		"onboarding.location_button":  "📍 Отправить геопозицию",
+		"onboarding.timezone_or_list": "Можно также написать город или часовой пояс (например, Москва, UTC+3) или выбрать его из списка:",
+		"onboarding.timezone":         "2️⃣ В каком часовом поясе присылать напоминания? Напишите город или часовой пояс (например, Москва, UTC+3) или выберите его из списка:",
+		"onboarding.timezone_retry":   "2️⃣ Напишите город или часовой пояс (например, Москва, UTC+3) или выберите его из списка:",
+		"onboarding.list_button":      "🗺 Выбрать из списка",
+		"onboarding.keep_button":      "Оставить %s",
+		"onboarding.region":           "2️⃣ Выберите регион:",
+		"onboarding.city":             "2️⃣ Выберите город в регионе %s:",
+		"onboarding.timezone_done":    "2️⃣ Часовой пояс: %s",
+		"onboarding.timezone_set":     "🌍 Часовой пояс: %s",
+		"onboarding.hour":             "3️⃣ Когда напоминать вам каждый день? Выберите час (%s):",
+		"onboarding.minute":           "3️⃣ Напоминание в %02d:__ — теперь выберите минуты:",
+		"onboarding.time_done":        "3️⃣ Время напоминания: %s %s",
+		"onboarding.task":             "4️⃣ Какая у вас первая задача? Просто отправьте её сообщением.",
+		"onboarding.skip_button":      "Пропустить",
+		"onboarding.task_skipped":     "4️⃣ Первая задача: пропущено",
+		"onboarding.language_done":    "1️⃣ Язык: %s",
+		"onboarding.failed_continue":  "Что-то пошло не так. Отправьте /start, чтобы продолжить.",
+		"onboarding.text_only":        "Ответьте, пожалуйста, текстовым сообщением или отправьте /start, чтобы увидеть вопрос ещё раз.",
+		"onboarding.save_failed":      "Не удалось сохранить настройки. Отправьте /start, чтобы попробовать ещё раз.",
+		"onboarding.done": `🎉 Всё готово! Я буду напоминать вам каждый день в %s (%s).
+
+Добавляйте задачи командой /add, смотрите их в /list, а настройки меняйте в /settings. /help покажет все команды.`,
+		"settings.get_failed":        "Не удалось загрузить настройки. Попробуйте ещё раз.",
+		"settings.save_failed":       "Не удалось сохранить настройки. Попробуйте ещё раз.",
+		"settings.saved_short":       "✅ Сохранено",
+		"settings.saved":             "✅ Настройки сохранены.",
+		"settings.title":             "Настройки",
+		"settings.reminder_time":     "⏰ Время напоминания: %s",
+		"settings.timezone":          "🌍 Часовой пояс: %s%s",
+		"settings.local_time":        " (сейчас %s)",
+		"settings.daily_reminders":   "🔔 Ежедневные напоминания: %s",
+		"settings.weekend_reminders": "📅 Напоминания в выходные: %s",
+		"settings.quick_capture":     "⚡ Быстрое добавление: %s",
+		"settings.digest":            "📬 Ежедневная сводка по всем чатам: %s",
+		"settings.choose_hour":       "Выберите час:",
+		"settings.choose_minute":     "Выберите минуты:",
+		"settings.choose_region":     "Выберите регион или поделитесь геопозицией:",
+		"settings.choose_city":       "Выберите город в регионе %s:",
+		"settings.hour_button":       "⏰ Час: %s",
+		"settings.minute_button":     "Минуты: %s",
+		"settings.timezone_button":   "🌍 Пояс: %s",
+		"settings.reminders_button":  "🔔 Напоминания: %s",
+		"settings.weekends_button":   "📅 Выходные: %s",
+		"settings.capture_button":    "⚡ Быстрое добавление: %s",
+		"settings.digest_button":     "📬 Сводка: %s",
+		"settings.close_button":      "✖️ Закрыть",
+		"settings.back_button":       "⬅️ Назад",
+		"settings.on":                "Вкл",
+		"settings.off":               "Выкл",
+
+		"list.empty":              "У вас нет активных задач. Отличная работа! 🎉",
+		"list.header":             "Ваши задачи (%d):",
+		"list.progress":           "👥 готово %d/%d",
+		"list.snoozed_until":      "😴 до %s",
+		"list.hint":               "Нажмите на задачу, чтобы управлять ей.",
+		"list.edit_prompt":        "Отправьте новый текст для задачи: %s",
+		"list.edit_answer":        "✏️ Отправьте новый текст",
+		"list.resumed":            "⏰ Напоминания возобновлены",
+		"list.snoozed":            "😴 Отложено до %s",
+		"list.priority":           "Приоритет: %s",
+		"list.closed":             "🗑 Задача закрыта",
+		"list.done_next":          "✅ Готово! Следующий: %s",
+		"list.done_today":         "✅ Выполнено на сегодня",
+		"list.undone":             "↩️ Отмечено как невыполненное",
+		"list.not_assigned":       "Эта задача назначена не вам.",
+		"list.done_member":        "✅ Выполнено вами (%d/%d)",
+		"list.snooze_tomorrow":    "Завтра",
+		"list.snooze_3_days":      "Через 3 дня",
+		"list.snooze_week":        "Через неделю",
+		"list.wake_button":        "⏰ Вернуть",
+		"list.back_button":        "◀️ Назад",
+		"list.done_button":        "✅ Готово",
+		"list.my_part_button":     "✅ Моя часть",
+		"list.undo_button":        "↩️ Отменить",
+		"list.priority_high":      "🔺 Высокий",
+		"list.priority_low":       "🔻 Низкий",
+		"list.priority_normal":    "▪️ Обычный",
+		"reminder.waiting_for":    "👤 Ждём:",
+		"rotation.groups_only":    "Очереди работают в групповых чатах.",
+		"rotation.need_members":   "Назовите задачу и упомяните хотя бы двух участников, которые будут выполнять её по очереди.",
+		"rotation.need_different": "Упомяните хотя бы двух разных участников.",
+		"rotation.usage": `Использование: /rotate <задача> @user @user... [daily|weekly|done]
+Пример: /rotate посуда @alice @bob @carol daily`,
+		"rotation.command_usage":  "Использование: /rotation, /rotation swap <задача> @user @user, /rotation pause @user... или /rotation resume @user...",
+		"rotation.swap_members":   "Упомяните двух участников, которые меняются местами. Использование: %s",
+		"rotation.not_rotating":   "%s — не задача с очередью. Создайте такую командой /rotate.",
+		"rotation.shared":         "Очередь задач из общего списка можно менять только в их собственном чате.",
+		"rotation.not_members":    "Оба участника должны быть в очереди.",
+		"rotation.swapped":        "🔁 Очередь в задаче %s изменена. Дальше: %s",
+		"rotation.pause_usage":    "Упомяните участников. Использование: /rotation pause @user... или /rotation resume @user...",
+		"rotation.update_failed":  "Не удалось изменить очередь. Попробуйте ещё раз.",
+		"rotation.paused":         "⏸ Пропускаем в очередях: %s — до /rotation resume.",
+		"rotation.resumed":        "▶️ Снова в очереди: %s.",
+		"rotation.paused_members": "⏸ На паузе: %s",
+		"rotation.none":           "В этом чате нет задач с очередью. Создайте такую командой /rotate <задача> @user @user... [daily|weekly|done].",
+		"rotation.title":          "Очереди",
+		"rotation.hint":           "Поменять двух участников местами: /rotation swap <задача> @user @user.",
+		"rotation.skip_button":    "⏭ Пропустить %s",
+		"rotation.goes_first":     "🔁 Первым будет %s",
+		"rotation.turn_now":       "⏭ Теперь очередь: %s",
+		"rotation.period_weekly":  "меняется каждый понедельник",
+		"rotation.period_done":    "меняется после выполнения",
+		"rotation.period_daily":   "меняется каждый день",
+		"rotation.turn_now_label": "Сейчас",
+		"rotation.turn_then":      "Потом",
+		"rotation.turn_this_week": "На этой неделе",
+		"rotation.turn_week_of":   "Неделя с %s",
+		"rotation.turn_today":     "Сегодня",
+		"rotation.turn_tomorrow":  "Завтра",
+		"rotation.line":           "🔄 Очередь: %s",
+		"rotation.line_then":      "🔄 Очередь: %s, затем %s",
+		"rotation.takes_turns":    "🔄 По очереди (%s): %s. Начинает %s.",
+
+		"share.number_usage":  "Укажите номер из /share list. Использование: /share %s <номер>",
+		"share.usage":         "Использование: /share [edit|view], /share list, /share revoke <номер> или /share leave <номер>",
+		"share.invite_failed": "Не удалось создать приглашение. Попробуйте ещё раз.",
+		"share.invite": `🔗 Приглашение к задачам %s (%s)
+
+Для человека: https://t.me/%s?start=%s
+Для группы: https://t.me/%s?startgroup=%s
+
+Присоединиться может любой, у кого есть ссылка. Присоединившиеся чаты видят эти задачи в /list и в своих напоминаниях. Отозвать приглашение можно через /share list.`,
+		"share.join_failed":    "Не удалось присоединиться к списку. Попробуйте ещё раз.",
+		"share.invite_invalid": "Эта ссылка-приглашение больше не действует. Попросите новую.",
+		"share.invite_own":     "Это приглашение к задачам этого же чата. Отправьте ссылку кому-нибудь другому.",
+		"share.joined":         "🔗 Вы присоединились к задачам %s (%s). Теперь они видны в /list и в ваших ежедневных напоминаниях.",
+		"share.get_failed":     "Не удалось загрузить общие списки. Попробуйте ещё раз.",
+		"share.none":           "Этот чат не делится своими задачами и не видит чужих. Создайте приглашение командой /share.",
+		"share.title":          "🔗 Общий доступ",
+		"share.invites":        "Приглашения к задачам этого чата:",
+		"share.invite_line":    "%d. %s, создано %s, %s",
+		"share.revoke_hint":    "Отозвать приглашение и отключить присоединившиеся по нему чаты: /share revoke <номер>",
+		"share.joined_lists":   "Задачи, к которым присоединился этот чат:",
+		"share.leave_hint":     "Перестать видеть список: /share leave <номер>",
+		"share.revoke_failed":  "Не удалось отозвать приглашение. Попробуйте ещё раз.",
+		"share.leave_failed":   "Не удалось покинуть список. Попробуйте ещё раз.",
+		"share.left":           "👋 Вы больше не видите задачи %s.",
+		"share.view_only":      "только просмотр",
+		"share.can_edit":       "можно выполнять и изменять",
+
+		"group.former_members":      "бывшие участники",
+		"group.addeach_groups_only": "/addeach работает в групповых чатах. Для своих задач используйте /add.",
+		"group.addeach_prompt":      "Что должен сделать каждый?",
+		"group.assign_groups_only":  "Назначать задачи можно только в групповых чатах.",
+		"group.assign_mentions":     "Упомяните участников, которым назначить задачу. Использование: %s",
+		"group.assign_shared":       "Задачи из общего списка можно назначать только в их собственном чате.",
+		"group.assign_rotation":     "%s выполняется по очереди. Очередь меняется командой /rotation.",
+		"group.assign_failed":       "Не удалось назначить задачу. Попробуйте ещё раз.",
+		"group.unassigned":          "Задача больше никому не назначена: %s",
+		"group.assigned":            "👤 %s — исполнители: %s",
+		"group.lookup_failed":       "Не удалось найти участников чата. Попробуйте ещё раз.",
+		"group.unknown_members":     "Я пока не знаю %s. Участнику можно назначить задачу после того, как он отправит команду или нажмёт кнопку в этом чате.",
+		"digest.private_only":       "Отправьте мне /mytasks в личном чате, чтобы увидеть свои задачи из всех чатов.",
+		"digest.empty":              "У вас нет задач ни в одном чате. Отличная работа! 🎉",
+		"digest.header":             "Ваши задачи во всех чатах (%d):",
+		"digest.daily_title":        "Ежедневная сводка!",
+		"digest.daily_summary":      "У вас %s в %s. Нажмите на задачу, чтобы отметить её выполненной:",
+		"digest.private_chat":       "Личный чат",
+		"digest.group_chat":         "Групповой чат",
+		"digest.not_yours":          "Эта задача больше не ваша.",
+		"digest.not_member":         "Вы больше не состоите в этом чате.",
+
+		"capture.private_only":      "Быстрое добавление работает только в личных чатах.",
+		"capture.failed":            "Не удалось изменить быстрое добавление. Попробуйте ещё раз.",
+		"capture.usage":             "Использование: /quickcapture [on|off]",
+		"capture.on":                "⚡ Быстрое добавление включено. Каждое ваше сообщение становится задачей, по одной на строку. Пересланные сообщения тоже становятся задачами.",
+		"capture.off":               "Быстрое добавление выключено. Добавляйте задачи командой /add.",
+		"capture.nothing_to_undo":   "↩️ Отменять нечего, этих задач уже нет.",
+		"capture.from":              "источник: %s",
+		"capture.forwarded_message": "пересланное сообщение",
+		"capture.added_one":         "✅ Задача добавлена: %s",
+		"capture.more":              "…и ещё %d",
+
+		"outbox.get_failed": "Не удалось загрузить сообщения из очереди отправки. Попробуйте ещё раз.",
+		"outbox.empty":      "📭 Недоставленных сообщений нет.",
+		"outbox.header":     "📮 Недоставленные сообщения: %d (показано %d)",
+		"outbox.entry": `%s
+чат %d, %s, %s, %s
+%s`,
+		"outbox.hint":          "Отправьте /replay <id> или /replay all, чтобы поставить их в очередь снова.",
+		"outbox.replay_usage":  "Укажите ID сообщения. Использование: /replay <id|all>",
+		"outbox.invalid_id":    "Неверный ID сообщения. Недоставленные сообщения показывает /outbox.",
+		"outbox.replay_failed": "Не удалось повторить отправку. Попробуйте ещё раз.",
+		"outbox.no_match":      "Подходящих недоставленных сообщений нет.",
+
+		"template.preview_usage": "Использование: /template preview <шаблон>",
+		"template.set_usage":     "Использование: /template set <шаблон>",
+		"template.usage":         "Использование: /template [set <шаблон>|preview <шаблон>|reset]",
+		"template.get_failed":    "Не удалось загрузить шаблон напоминания. Попробуйте ещё раз.",
+		"template.own":           "📝 Ваш шаблон напоминания:",
+		"template.default":       "📝 Напоминания используют шаблон бота по умолчанию:",
+		"template.builtin":       "📝 Напоминания используют встроенный текст.",
+		"template.help": `Чтобы изменить его, отправьте /template set и шаблон, например:
+/template set <b>Доброе утро, {{.Name}}!</b> Осталось {{.Pending}}, просрочено {{.Overdue}}. 🔥 {{.Streak}}
+
+Переменные:
+{{.Name}} - ваше имя или название группы
+{{.Date}} - сегодняшняя дата
+{{.Pending}} - задачи, не выполненные сегодня
+{{.Completed}} - задачи, выполненные сегодня
+{{.Overdue}} - невыполненные задачи, добавленные до сегодняшнего дня
+{{.Total}} - все задачи напоминания
+{{.Streak}} - сколько дней подряд вы выполняли задачи
+
+Шаблоны используют синтаксис text/template из Go и HTML-теги Telegram, например <b>, <i> и <code>. Пишите &lt; &gt; и &amp; вместо символов < > и &. Попробуйте шаблон командой /template preview или вернитесь к шаблону по умолчанию командой /template reset.`,
+		"template.invalid": `❌ Этот шаблон нельзя использовать: %v
+
+Отправьте /template, чтобы увидеть справку.`,
+		"template.save_failed": "Не удалось сохранить шаблон напоминания. Попробуйте ещё раз.",
+		"template.reset":       "✅ Напоминания снова используют текст по умолчанию.",
+		"template.saved":       "✅ Шаблон напоминания сохранён. Ваши напоминания будут начинаться так:",
+
+		"permissions.everyone":        "Все",
+		"permissions.owners":          "Автор, исполнители и админы",
+		"permissions.admins":          "Только админы",
+		"permissions.complete":        "✅ Выполнение задач",
+		"permissions.edit":            "✏️ Изменение задач",
+		"permissions.close":           "🗑 Закрытие задач",
+		"permissions.settings":        "⚙️ Изменение настроек",
+		"permissions.action_complete": "выполнить эту задачу",
+		"permissions.action_edit":     "изменить эту задачу",
+		"permissions.action_close":    "закрыть эту задачу",
+		"permissions.action_settings": "изменить настройки этого чата",
+		"permissions.denied_owners":   "⛔ Только автор задачи, её исполнители и админы чата могут %s.",
+		"permissions.denied_admins":   "⛔ Только админы чата могут %s.",
+		"permissions.view_only":       "🔗 Эта задача из общего списка, который можно только просматривать.",
+		"permissions.usage":           "Использование: /permissions [complete|edit|close|settings] [everyone|owners|admins]",
+		"permissions.groups_only":     "Права действуют в групповых чатах. В личном чате вам доступно всё.",
+		"permissions.admins_only":     "⛔ Только админы чата могут менять права.",
+		"permissions.title":           "Права",
+		"permissions.hint":            "Админы чата могут нажать на право, чтобы изменить, кому оно доступно.",
+	},
+	plurals: map[string]Plural{
+		"task.invalid_number": {
+			One:  "Неверный номер задачи. У вас %d задача.",
+			Few:  "Неверный номер задачи. У вас %d задачи.",
+			Many: "Неверный номер задачи. У вас %d задач.",
+		},
+		"task.pick": {
+			One:  "%d задача подходит под «%s». Эта?",
+			Few:  "%d задачи подходят под «%s». Какую вы имели в виду?",
+			Many: "%d задач подходят под «%s». Какую вы имели в виду?",
+		},
+		"task.ambiguous": {
+			One:  "%d задача подходит под «%s». Укажите её номер:",
+			Few:  "%d задачи подходят под «%s». Укажите номер одной из них:",
+			Many: "%d задач подходят под «%s». Укажите номер одной из них:",
+		},
+		"reminder.active": {
+			One:  "У вас %d активная задача:",
+			Few:  "У вас %d активные задачи:",
+			Many: "У вас %d активных задач:",
+		},
+		"reminder.active_tap": {
+			One:  "У вас %d активная задача. Нажмите на задачу, чтобы отметить её выполненной:",
+			Few:  "У вас %d активные задачи. Нажмите на задачу, чтобы отметить её выполненной:",
+			Many: "У вас %d активных задач. Нажмите на задачу, чтобы отметить её выполненной:",
+		},
+		"stats.panics": {
+			One:  ", %d паника",
+			Few:  ", %d паники",
+			Many: ", %d паник",
+		},
+		"stats.limited": {
+			Other: ", ограничено: %d",
+		},
+		"share.chats_joined": {
+			One:  "присоединился %d чат",
+			Few:  "присоединились %d чата",
+			Many: "присоединились %d чатов",
+		},
+		"share.invalid_invite": {
+			One:  "Неверный номер приглашения. У этого чата %d приглашение, см. /share list.",
+			Few:  "Неверный номер приглашения. У этого чата %d приглашения, см. /share list.",
+			Many: "Неверный номер приглашения. У этого чата %d приглашений, см. /share list.",
+		},
+		"share.invalid_list": {
+			One:  "Неверный номер списка. Этот чат присоединился к %d списку, см. /share list.",
+			Few:  "Неверный номер списка. Этот чат присоединился к %d спискам, см. /share list.",
+			Many: "Неверный номер списка. Этот чат присоединился к %d спискам, см. /share list.",
+		},
+		"share.revoked": {
+			One:  "🚫 Приглашение отозвано. %d чат больше не видит задачи этого чата.",
+			Few:  "🚫 Приглашение отозвано. %d чата больше не видят задачи этого чата.",
+			Many: "🚫 Приглашение отозвано. %d чатов больше не видят задачи этого чата.",
+		},
+		"digest.tasks": {
+			One:  "%d задача",
+			Few:  "%d задачи",
+			Many: "%d задач",
+		},
+		"digest.chats": {
+			One:  "%d чате",
+			Few:  "%d чатах",
+			Many: "%d чатах",
+		},
+		"digest.more": {
+			One:  "…и ещё %d задача.",
+			Few:  "…и ещё %d задачи.",
+			Many: "…и ещё %d задач.",
+		},
+		"capture.removed": {
+			One:  "↩️ Удалена %d задача.",
+			Few:  "↩️ Удалены %d задачи.",
+			Many: "↩️ Удалено %d задач.",
+		},
+		"capture.added": {
+			One:  "✅ Добавлена %d задача:",
+			Few:  "✅ Добавлены %d задачи:",
+			Many: "✅ Добавлено %d задач:",
+		},
+		"outbox.attempts": {
+			One:  "%d попытка",
+			Few:  "%d попытки",
+			Many: "%d попыток",
+		},
+		"outbox.queued": {
+			One:  "🔁 %d сообщение поставлено в очередь на отправку.",
+			Few:  "🔁 %d сообщения поставлены в очередь на отправку.",
+			Many: "🔁 %d сообщений поставлено в очередь на отправку.",
+		},
+	},
+}
@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// leaderLockName is the name of the lease shared by all scheduler replicas
+const leaderLockName = "scheduler_leader"
+
+// Locker defines the interface for coordinating scheduling between bot replicas
+type Locker interface {
+	AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLock(ctx context.Context, name, owner string) error
+	ClaimReminder(ctx context.Context, chatID int64, slot, owner string) (bool, error)
+}
+
+// leaderElection keeps track of whether this replica currently holds the scheduler lease
+type leaderElection struct {
+	locker     Locker
+	instanceID string
+	ttl        time.Duration
+
+	mu          sync.Mutex
+	leaseExpiry time.Time
+}
+
+func newLeaderElection(locker Locker, instanceID string, ttl time.Duration) *leaderElection {
+	return &leaderElection{
+		locker:     locker,
+		instanceID: instanceID,
+		ttl:        ttl,
+	}
+}
+
+// isLeader reports whether the lease is held and has not expired locally.
+// The local expiry is measured from the moment the renewal was started, so
+// a replica stops acting as leader before any other replica can take over.
+func (l *leaderElection) isLeader() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return time.Now().Before(l.leaseExpiry)
+}
+
+// run renews the lease periodically until ctx is done or stop is closed
+func (l *leaderElection) run(ctx context.Context, stop <-chan struct{}) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	defer l.release()
+
+	l.renew(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+			l.renew(ctx)
+		}
+	}
+}
+
+func (l *leaderElection) renew(ctx context.Context) {
+	started := time.Now()
+	acquired, err := l.locker.AcquireLock(ctx, leaderLockName, l.instanceID, l.ttl)
+	if err != nil {
+		log.Printf("Error renewing scheduler lease: %v", err)
+		// Keep the current local expiry: if renewals keep failing we step down
+		// once it passes, which is no later than the lease expires in MongoDB
+		return
+	}
+
+	wasLeader := l.isLeader()
+
+	l.mu.Lock()
+	if acquired {
+		l.leaseExpiry = started.Add(l.ttl)
+	} else {
+		l.leaseExpiry = time.Time{}
+	}
+	l.mu.Unlock()
+
+	if acquired && !wasLeader {
+		log.Printf("Instance %s became scheduler leader", l.instanceID)
+	} else if !acquired && wasLeader {
+		log.Printf("Instance %s lost scheduler leadership", l.instanceID)
+	}
+}
+
+func (l *leaderElection) release() {
+	if !l.isLeader() {
+		return
+	}
+
+	l.mu.Lock()
+	l.leaseExpiry = time.Time{}
+	l.mu.Unlock()
+
+	// The parent context is usually cancelled by now, use a fresh one
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := l.locker.ReleaseLock(ctx, leaderLockName, l.instanceID); err != nil {
+		log.Printf("Error releasing scheduler lease: %v", err)
+		return
+	}
+	log.Printf("Instance %s released scheduler leadership", l.instanceID)
+}
@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+)
+
+// reminderOccurrence returns the instant at which a wall-clock reminder time
+// fires on the given local date in loc. Daylight saving transitions are
+// handled explicitly instead of relying on time.Date, whose choice is
+// unspecified for such times:
+//   - a time skipped by a spring-forward gap is shifted forward by the length
+//     of the gap (02:30 becomes 03:30 when clocks jump from 02:00 to 03:00);
+//   - a time that occurs twice on fall-back fires once, at its first occurrence.
+func reminderOccurrence(year int, month time.Month, day, hour, minute int, loc *time.Location) time.Time {
+	// The wall-clock time expressed as if it were UTC
+	wall := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+
+	// Offsets in effect around the wall time; transitions are never closer than a day apart
+	before := offsetAt(wall.Add(-24*time.Hour), loc)
+	after := offsetAt(wall.Add(24*time.Hour), loc)
+
+	var earliest time.Time
+	for _, offset := range []int{before, after} {
+		candidate := wall.Add(-time.Duration(offset) * time.Second)
+		// The candidate is valid only if loc actually uses this offset at that instant
+		if offsetAt(candidate, loc) != offset {
+			continue
+		}
+		if earliest.IsZero() || candidate.Before(earliest) {
+			earliest = candidate
+		}
+	}
+
+	if earliest.IsZero() {
+		// The wall time falls into a gap: applying the offset from before the
+		// transition lands after it, shifted forward by the gap length
+		earliest = wall.Add(-time.Duration(before) * time.Second)
+	}
+
+	return earliest.In(loc)
+}
+
+// dueOccurrence returns the occurrence of the reminder time that falls into (from, to], if any
+func dueOccurrence(reminderTime string, loc *time.Location, from, to time.Time) (time.Time, bool, error) {
+	hour, minute, err := parseReminderTime(reminderTime)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	// The window is only minutes long, so an occurrence within it belongs to
+	// the local date of one of its ends
+	for _, t := range []time.Time{from.In(loc), to.In(loc)} {
+		occurrence := reminderOccurrence(t.Year(), t.Month(), t.Day(), hour, minute, loc)
+		if occurrence.After(from) && !occurrence.After(to) {
+			return occurrence, true, nil
+		}
+	}
+
+	return time.Time{}, false, nil
+}
+
+func parseReminderTime(reminderTime string) (int, int, error) {
+	t, err := time.Parse("15:04", reminderTime)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid reminder time %q: %w", reminderTime, err)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+func offsetAt(t time.Time, loc *time.Location) int {
+	_, offset := t.In(loc).Zone()
+	return offset
+}
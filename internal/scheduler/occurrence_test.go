@@ -0,0 +1,205 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone data for %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestReminderOccurrence(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		date     string // YYYY-MM-DD
+		time     string // HH:MM
+		wantUTC  string // RFC3339
+	}{
+		{
+			name:     "Berlin regular day",
+			timezone: "Europe/Berlin",
+			date:     "2024-03-30",
+			time:     "02:30",
+			wantUTC:  "2024-03-30T01:30:00Z",
+		},
+		{
+			name:     "Berlin spring-forward gap shifts forward",
+			timezone: "Europe/Berlin",
+			date:     "2024-03-31",
+			time:     "02:30",
+			wantUTC:  "2024-03-31T01:30:00Z", // 03:30 CEST
+		},
+		{
+			name:     "Berlin time after the gap is unaffected",
+			timezone: "Europe/Berlin",
+			date:     "2024-03-31",
+			time:     "03:30",
+			wantUTC:  "2024-03-31T01:30:00Z",
+		},
+		{
+			name:     "Berlin fall-back overlap fires at first occurrence",
+			timezone: "Europe/Berlin",
+			date:     "2024-10-27",
+			time:     "02:30",
+			wantUTC:  "2024-10-27T00:30:00Z", // 02:30 CEST, not 02:30 CET
+		},
+		{
+			name:     "New York spring-forward gap",
+			timezone: "America/New_York",
+			date:     "2024-03-10",
+			time:     "02:15",
+			wantUTC:  "2024-03-10T07:15:00Z", // 03:15 EDT
+		},
+		{
+			name:     "New York fall-back overlap",
+			timezone: "America/New_York",
+			date:     "2024-11-03",
+			time:     "01:30",
+			wantUTC:  "2024-11-03T05:30:00Z", // 01:30 EDT
+		},
+		{
+			name:     "Sydney southern hemisphere fall-back overlap",
+			timezone: "Australia/Sydney",
+			date:     "2024-04-07",
+			time:     "02:30",
+			wantUTC:  "2024-04-06T15:30:00Z", // 02:30 AEDT
+		},
+		{
+			name:     "Sydney southern hemisphere spring-forward gap",
+			timezone: "Australia/Sydney",
+			date:     "2024-10-06",
+			time:     "02:30",
+			wantUTC:  "2024-10-05T16:30:00Z", // 03:30 AEDT
+		},
+		{
+			name:     "Lord Howe half-hour gap",
+			timezone: "Australia/Lord_Howe",
+			date:     "2024-10-06",
+			time:     "02:15",
+			wantUTC:  "2024-10-05T15:45:00Z", // 02:45 +11
+		},
+		{
+			name:     "Lord Howe half-hour overlap",
+			timezone: "Australia/Lord_Howe",
+			date:     "2024-04-07",
+			time:     "01:45",
+			wantUTC:  "2024-04-06T14:45:00Z", // 01:45 +11
+		},
+		{
+			name:     "Zone without DST",
+			timezone: "Asia/Kolkata",
+			date:     "2024-03-31",
+			time:     "09:00",
+			wantUTC:  "2024-03-31T03:30:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLoadLocation(t, tt.timezone)
+			date, err := time.Parse("2006-01-02", tt.date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hour, minute, err := parseReminderTime(tt.time)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := reminderOccurrence(date.Year(), date.Month(), date.Day(), hour, minute, loc)
+			if got.UTC().Format(time.RFC3339) != tt.wantUTC {
+				t.Errorf("reminderOccurrence() = %s (%s), want %s", got.UTC().Format(time.RFC3339), got, tt.wantUTC)
+			}
+		})
+	}
+}
+
+// TestDueOccurrenceFiresOncePerDay simulates the scheduler's one-minute checks
+// across DST transition days and counts how often each reminder fires
+func TestDueOccurrenceFiresOncePerDay(t *testing.T) {
+	tests := []struct {
+		timezone string
+		day      string // Local date of a DST transition
+		times    []string
+	}{
+		{"Europe/Berlin", "2024-03-31", []string{"01:59", "02:00", "02:30", "02:59", "03:00", "09:00"}},
+		{"Europe/Berlin", "2024-10-27", []string{"01:59", "02:00", "02:30", "02:59", "03:00", "09:00"}},
+		{"America/New_York", "2024-03-10", []string{"02:00", "02:30", "03:00"}},
+		{"America/New_York", "2024-11-03", []string{"00:59", "01:00", "01:30", "02:00"}},
+		{"Australia/Sydney", "2024-04-07", []string{"02:00", "02:30", "03:00"}},
+		{"Australia/Lord_Howe", "2024-10-06", []string{"02:00", "02:15", "02:30"}},
+		{"Asia/Kolkata", "2024-03-31", []string{"00:00", "09:00", "23:59"}},
+	}
+
+	for _, tt := range tests {
+		loc := mustLoadLocation(t, tt.timezone)
+		date, err := time.ParseInLocation("2006-01-02", tt.day, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, reminderTime := range tt.times {
+			t.Run(tt.timezone+" "+tt.day+" "+reminderTime, func(t *testing.T) {
+				start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc).Add(-time.Hour)
+				end := start.Add(26 * time.Hour)
+
+				// The simulated range overlaps the neighbouring days, count only this day's reminders
+				fired := 0
+				for from := start; from.Before(end); from = from.Add(time.Minute) {
+					occurrence, due, err := dueOccurrence(reminderTime, loc, from, from.Add(time.Minute))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if due && occurrence.Format("2006-01-02") == tt.day {
+						fired++
+					}
+				}
+
+				if fired != 1 {
+					t.Errorf("reminder at %s fired %d times on %s, want 1", reminderTime, fired, tt.day)
+				}
+			})
+		}
+	}
+}
+
+func TestDueOccurrenceWindow(t *testing.T) {
+	loc := mustLoadLocation(t, "Europe/Berlin")
+	occurrence := time.Date(2024, 6, 1, 9, 0, 0, 0, loc)
+
+	tests := []struct {
+		name    string
+		from    time.Time
+		to      time.Time
+		wantDue bool
+	}{
+		{"Window ends at occurrence", occurrence.Add(-time.Minute), occurrence, true},
+		{"Window starts at occurrence", occurrence, occurrence.Add(time.Minute), false},
+		{"Delayed check catches up", occurrence.Add(-time.Minute), occurrence.Add(3 * time.Minute), true},
+		{"Window before occurrence", occurrence.Add(-2 * time.Minute), occurrence.Add(-time.Minute), false},
+		{"Window spanning midnight", time.Date(2024, 6, 1, 23, 59, 0, 0, loc), time.Date(2024, 6, 2, 0, 1, 0, 0, loc), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, due, err := dueOccurrence("09:00", loc, tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if due != tt.wantDue {
+				t.Errorf("dueOccurrence() due = %v, want %v", due, tt.wantDue)
+			}
+		})
+	}
+
+	if _, _, err := dueOccurrence("25:00", loc, occurrence, occurrence); err == nil {
+		t.Error("dueOccurrence() accepted an invalid reminder time")
+	}
+}
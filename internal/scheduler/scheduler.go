@@ -6,6 +6,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/dm-popov-sdg/nagger/internal/timezone"
 	"github.com/dm-popov-sdg/nagger/internal/types"
 )
 
@@ -13,6 +14,7 @@ import (
 type TaskSender interface {
 	SendDailyReminder(ctx context.Context, chatID int64, tasks []string) error
 	SendDailyReminderWithTasks(ctx context.Context, chatID int64, tasks []types.TaskWithID) error
+	SendDigest(ctx context.Context, userID int64) error
 }
 
 // TaskGetter defines the interface for getting tasks
@@ -31,6 +33,9 @@ type UserSettings struct {
 	ChatID       int64
 	ReminderTime string
 	Timezone     string
+	Paused       bool
+	SkipWeekends bool
+	Digest       bool // The private chat gets a digest of the user's tasks from all chats instead of its reminder
 }
 
 // Task represents a task (simplified interface)
@@ -47,28 +52,50 @@ type Scheduler struct {
 	bot             TaskSender
 	defaultTime     string
 	defaultTimezone *time.Location
+	locker          Locker
+	instanceID      string
+	leader          *leaderElection
+	lastCheck       time.Time
 	stopChan        chan struct{}
 }
 
-// NewScheduler creates a new scheduler instance
-func NewScheduler(storage TaskGetter, settingsStorage SettingsGetter, bot TaskSender, defaultTime, defaultTimezone string) (*Scheduler, error) {
-	loc, err := time.LoadLocation(defaultTimezone)
+// maxCatchUp limits how far back a delayed check looks for missed reminders
+const maxCatchUp = 5 * time.Minute
+
+// NewScheduler creates a new scheduler instance.
+// When locker is nil the scheduler assumes it is the only running replica.
+func NewScheduler(storage TaskGetter, settingsStorage SettingsGetter, bot TaskSender, defaultTime, defaultTimezone string, locker Locker, instanceID string, leaseTTL time.Duration) (*Scheduler, error) {
+	loc, err := timezone.Load(defaultTimezone)
 	if err != nil {
 		return nil, fmt.Errorf("invalid timezone %s: %w", defaultTimezone, err)
 	}
 
-	return &Scheduler{
+	s := &Scheduler{
 		storage:         storage,
 		settingsStorage: settingsStorage,
 		bot:             bot,
 		defaultTime:     defaultTime,
 		defaultTimezone: loc,
+		locker:          locker,
+		instanceID:      instanceID,
 		stopChan:        make(chan struct{}),
-	}, nil
+	}
+
+	if locker != nil {
+		if leaseTTL <= 0 {
+			return nil, fmt.Errorf("invalid leader lease TTL %s", leaseTTL)
+		}
+		s.leader = newLeaderElection(locker, instanceID, leaseTTL)
+	}
+
+	return s, nil
 }
 
 // Start begins the scheduler
 func (s *Scheduler) Start(ctx context.Context) {
+	if s.leader != nil {
+		go s.leader.run(ctx, s.stopChan)
+	}
 	go s.run(ctx)
 }
 
@@ -83,31 +110,64 @@ func (s *Scheduler) run(ctx context.Context) {
 
 	log.Printf("Scheduler started. Default reminder time: %s %s", s.defaultTime, s.defaultTimezone)
 
+	s.lastCheck = time.Now()
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-s.stopChan:
 			return
-		case <-ticker.C:
-			s.sendReminders(ctx)
+		case now := <-ticker.C:
+			s.sendReminders(ctx, now)
 		}
 	}
 }
 
-func (s *Scheduler) shouldSendReminderForUser(reminderTime, timezone string) bool {
-	loc, err := time.LoadLocation(timezone)
+// shouldSendReminderForUser reports whether the reminder fell due since the
+// previous check and returns that occurrence in the user's timezone
+func (s *Scheduler) shouldSendReminderForUser(reminderTime, tzName string, from, to time.Time) (time.Time, bool) {
+	loc, err := timezone.Load(tzName)
 	if err != nil {
-		log.Printf("Invalid timezone %s, using default: %v", timezone, err)
+		log.Printf("Invalid timezone %s, using default: %v", tzName, err)
 		loc = s.defaultTimezone
 	}
 
-	now := time.Now().In(loc)
-	currentTime := now.Format("15:04")
-	return currentTime == reminderTime
+	occurrence, due, err := dueOccurrence(reminderTime, loc, from, to)
+	if err != nil {
+		log.Printf("Invalid reminder time %s: %v", reminderTime, err)
+		return time.Time{}, false
+	}
+	return occurrence, due
+}
+
+// claimReminder makes sure only one replica sends the reminder for a chat and slot
+func (s *Scheduler) claimReminder(ctx context.Context, chatID int64, slot string) bool {
+	if s.locker == nil {
+		return true
+	}
+
+	claimed, err := s.locker.ClaimReminder(ctx, chatID, slot, s.instanceID)
+	if err != nil {
+		log.Printf("Error claiming reminder for chat %d: %v", chatID, err)
+		return false
+	}
+	return claimed
 }
 
-func (s *Scheduler) sendReminders(ctx context.Context) {
+func (s *Scheduler) sendReminders(ctx context.Context, now time.Time) {
+	// Only the leader replica sends reminders
+	if s.leader != nil && !s.leader.isLeader() {
+		return
+	}
+
+	// Check the whole interval since the previous check, so a delayed tick or a
+	// leadership handover does not skip reminders; claims prevent duplicates
+	from := s.lastCheck
+	if earliest := now.Add(-maxCatchUp); from.Before(earliest) {
+		from = earliest
+	}
+	s.lastCheck = now
+
 	tasks, err := s.storage.GetAllActiveTasks(ctx)
 	if err != nil {
 		log.Printf("Error getting tasks: %v", err)
@@ -122,24 +182,57 @@ func (s *Scheduler) sendReminders(ctx context.Context) {
 		userSettings = make(map[int64]*UserSettings)
 	}
 
-	// Check each chat with tasks
+	// Check each chat with tasks, and private chats with a digest even if they have no tasks of their own
+	chatIDs := make([]int64, 0, len(tasks))
 	for chatID, chatTasks := range tasks {
-		if len(chatTasks) == 0 {
-			continue
+		if len(chatTasks) > 0 {
+			chatIDs = append(chatIDs, chatID)
+		}
+	}
+	for chatID, settings := range userSettings {
+		if settings.Digest && len(tasks[chatID]) == 0 {
+			chatIDs = append(chatIDs, chatID)
 		}
+	}
+
+	for _, chatID := range chatIDs {
+		chatTasks := tasks[chatID]
 
 		// Get user settings or use defaults
 		settings := userSettings[chatID]
 		reminderTime := s.defaultTime
-		timezone := s.defaultTimezone.String()
+		tzName := s.defaultTimezone.String()
 
 		if settings != nil {
+			if settings.Paused {
+				continue
+			}
 			reminderTime = settings.ReminderTime
-			timezone = settings.Timezone
+			tzName = settings.Timezone
 		}
 
 		// Check if it's time to send reminder for this user
-		if !s.shouldSendReminderForUser(reminderTime, timezone) {
+		occurrence, due := s.shouldSendReminderForUser(reminderTime, tzName, from, now)
+		if !due {
+			continue
+		}
+
+		if settings != nil && settings.SkipWeekends && isWeekend(occurrence) {
+			continue
+		}
+
+		// Another replica may have sent this reminder during a leadership handover.
+		// The UTC instant is unique even for wall-clock times that occur twice.
+		if !s.claimReminder(ctx, chatID, occurrence.UTC().Format(time.RFC3339)) {
+			continue
+		}
+
+		if settings != nil && settings.Digest {
+			if err := s.bot.SendDigest(ctx, chatID); err != nil {
+				log.Printf("Error queueing digest for chat %d: %v", chatID, err)
+			} else {
+				log.Printf("Queued digest for chat %d at %s %s", chatID, reminderTime, tzName)
+			}
 			continue
 		}
 
@@ -149,11 +242,16 @@ func (s *Scheduler) sendReminders(ctx context.Context) {
 			taskInterfaces[i] = task
 		}
 
-		// Send reminder with interactive task list
+		// Queue reminder with interactive task list, delivery is retried by the bot's outbox
 		if err := s.bot.SendDailyReminderWithTasks(ctx, chatID, taskInterfaces); err != nil {
-			log.Printf("Error sending reminder to chat %d: %v", chatID, err)
+			log.Printf("Error queueing reminder for chat %d: %v", chatID, err)
 		} else {
-			log.Printf("Sent reminder to chat %d at %s %s", chatID, reminderTime, timezone)
+			log.Printf("Queued reminder for chat %d at %s %s", chatID, reminderTime, tzName)
 		}
 	}
 }
+
+// isWeekend reports whether t falls on a Saturday or Sunday in its own location
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
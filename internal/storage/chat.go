@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reasons a chat can become unreachable for the bot
+const (
+	ChatInactiveBlocked     = "blocked"
+	ChatInactiveNotFound    = "chat_not_found"
+	ChatInactiveKicked      = "kicked"
+	ChatInactiveDeactivated = "user_deactivated"
+)
+
+// Chat represents the delivery state of a chat the bot talks to
+type Chat struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID         int64              `bson:"chat_id"`
+	Active         bool               `bson:"active"`
+	InactiveReason string             `bson:"inactive_reason,omitempty"` // One of the ChatInactive* reasons
+	DeactivatedAt  *time.Time         `bson:"deactivated_at,omitempty"`
+	Title          string             `bson:"title,omitempty"`      // Group title, empty for private chats
+	Name           string             `bson:"name,omitempty"`       // First name of the user of a private chat
+	Streak         int                `bson:"streak,omitempty"`     // Days in a row the chat completed a task
+	StreakDay      string             `bson:"streak_day,omitempty"` // Last day of the streak, "YYYY-MM-DD" in the chat's timezone
+	UpdatedAt      time.Time          `bson:"updated_at"`
+}
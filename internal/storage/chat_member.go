@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChatMember represents a group member the bot has seen, so tasks can be assigned by @username
+type ChatMember struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID    int64              `bson:"chat_id"`
+	UserID    int64              `bson:"user_id"`
+	Username  string             `bson:"username,omitempty"` // Lowercase, without the @
+	FirstName string             `bson:"first_name"`
+	LastName  string             `bson:"last_name,omitempty"`
+	Paused    bool               `bson:"paused,omitempty"` // Skipped in rotations, e.g. while on vacation
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
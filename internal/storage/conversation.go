@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Conversation represents a multi-step command waiting for the user's next message
+type Conversation struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID    int64              `bson:"chat_id"`
+	UserID    int64              `bson:"user_id"` // Only this member's messages continue the conversation
+	Flow      string             `bson:"flow"`    // The command being answered, e.g., "add"
+	Step      string             `bson:"step"`    // The question the user was asked
+	Data      map[string]string  `bson:"data,omitempty"`
+	PromptID  int                `bson:"prompt_id,omitempty"` // The question's message; in groups only replies to it are answers
+	ExpiresAt time.Time          `bson:"expires_at"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
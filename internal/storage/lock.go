@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"time"
+)
+
+// Lock represents a lease held by a single bot replica
+type Lock struct {
+	Name      string    `bson:"_id"`
+	Owner     string    `bson:"owner"`
+	ExpiresAt time.Time `bson:"expires_at"` // The lease is free to take over after this moment
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// ReminderClaim records that a replica took responsibility for sending a reminder slot
+type ReminderClaim struct {
+	ID        string    `bson:"_id"` // Format: "<chat_id>:<slot>"
+	ChatID    int64     `bson:"chat_id"`
+	Slot      string    `bson:"slot"`
+	Owner     string    `bson:"owner"`
+	CreatedAt time.Time `bson:"created_at"`
+}
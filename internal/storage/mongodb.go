@@ -13,11 +13,29 @@ import (
 
 // MongoDB implements task storage using MongoDB
 type MongoDB struct {
-	client             *mongo.Client
-	collection         *mongo.Collection
-	settingsCollection *mongo.Collection
+	client              *mongo.Client
+	collection          *mongo.Collection
+	settingsCollection  *mongo.Collection
+	locksCollection     *mongo.Collection
+	claimsCollection    *mongo.Collection
+	outboxCollection    *mongo.Collection
+	chatsCollection     *mongo.Collection
+	convCollection      *mongo.Collection
+	membersCollection   *mongo.Collection
+	invitesCollection   *mongo.Collection
+	sharesCollection    *mongo.Collection
+	remindersCollection *mongo.Collection
 }
 
+const (
+	// reminderClaimRetention is how long reminder claims are kept before MongoDB expires them
+	reminderClaimRetention = 48 * time.Hour
+	// outboxSentRetention is how long delivered outbox messages are kept
+	outboxSentRetention = 7 * 24 * time.Hour
+	// reminderMessageRetention is how long sent reminders are tracked; only today's are re-rendered
+	reminderMessageRetention = 48 * time.Hour
+)
+
 // NewMongoDB creates a new MongoDB storage instance
 func NewMongoDB(ctx context.Context, uri, dbName string) (*MongoDB, error) {
 	clientOptions := options.Client().ApplyURI(uri)
@@ -33,12 +51,150 @@ func NewMongoDB(ctx context.Context, uri, dbName string) (*MongoDB, error) {
 
 	collection := client.Database(dbName).Collection("tasks")
 	settingsCollection := client.Database(dbName).Collection("user_settings")
+	locksCollection := client.Database(dbName).Collection("locks")
+	claimsCollection := client.Database(dbName).Collection("reminder_claims")
+	outboxCollection := client.Database(dbName).Collection("outbox")
+	chatsCollection := client.Database(dbName).Collection("chats")
+	convCollection := client.Database(dbName).Collection("conversations")
+	membersCollection := client.Database(dbName).Collection("chat_members")
+	invitesCollection := client.Database(dbName).Collection("share_invites")
+	sharesCollection := client.Database(dbName).Collection("list_shares")
+	remindersCollection := client.Database(dbName).Collection("reminder_messages")
+
+	m := &MongoDB{
+		client:              client,
+		collection:          collection,
+		settingsCollection:  settingsCollection,
+		locksCollection:     locksCollection,
+		claimsCollection:    claimsCollection,
+		outboxCollection:    outboxCollection,
+		chatsCollection:     chatsCollection,
+		convCollection:      convCollection,
+		membersCollection:   membersCollection,
+		invitesCollection:   invitesCollection,
+		sharesCollection:    sharesCollection,
+		remindersCollection: remindersCollection,
+	}
+
+	if err := m.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ensureIndexes creates the indexes the storage relies on
+func (m *MongoDB) ensureIndexes(ctx context.Context) error {
+	// Expired leases are removed by MongoDB, so a dead replica never blocks failover
+	_, err := m.locksCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create locks index: %w", err)
+	}
+
+	_, err = m.claimsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(reminderClaimRetention.Seconds())),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create reminder claims index: %w", err)
+	}
+
+	_, err = m.outboxCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{
+			// Only delivered messages have sent_at, so pending and dead ones are kept
+			Keys:    bson.D{{Key: "sent_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(outboxSentRetention.Seconds())),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create outbox indexes: %w", err)
+	}
+
+	_, err = m.chatsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "chat_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create chats index: %w", err)
+	}
+
+	_, err = m.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "assignees", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create tasks indexes: %w", err)
+	}
+
+	_, err = m.convCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "chat_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create conversations indexes: %w", err)
+	}
+
+	_, err = m.membersCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "chat_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "chat_id", Value: 1}, {Key: "username", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create chat members indexes: %w", err)
+	}
+
+	_, err = m.invitesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "chat_id", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create share invites indexes: %w", err)
+	}
+
+	_, err = m.sharesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "owner_chat_id", Value: 1}, {Key: "chat_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "chat_id", Value: 1}}},
+		{Keys: bson.D{{Key: "token", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create list shares indexes: %w", err)
+	}
+
+	_, err = m.remindersCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "chat_id", Value: 1}, {Key: "message_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "chat_id", Value: 1}, {Key: "day", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "sent_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(reminderMessageRetention.Seconds())),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create reminder messages indexes: %w", err)
+	}
 
-	return &MongoDB{
-		client:             client,
-		collection:         collection,
-		settingsCollection: settingsCollection,
-	}, nil
+	return nil
 }
 
 // Close closes the MongoDB connection
@@ -83,14 +239,14 @@ func (m *MongoDB) GetTasksByChatID(ctx context.Context, chatID int64) ([]Task, e
 		return nil, fmt.Errorf("failed to decode tasks: %w", err)
 	}
 
+	sortTasks(tasks)
 	return tasks, nil
 }
 
-// GetAllActiveTasks retrieves all active tasks across all chats
-// This excludes only closed tasks - includes both active and completed_today tasks
-func (m *MongoDB) GetAllActiveTasks(ctx context.Context) (map[int64][]Task, error) {
-	// Get tasks that are not closed (includes active and completed_today)
+// GetTasksByChatIDs retrieves the active tasks of several chats, ordered by chat
+func (m *MongoDB) GetTasksByChatIDs(ctx context.Context, chatIDs []int64) ([]Task, error) {
 	filter := bson.M{
+		"chat_id": bson.M{"$in": chatIDs},
 		"$or": []bson.M{
 			{"status": bson.M{"$ne": TaskStatusClosed}},
 			{"status": bson.M{"$exists": false}}, // For backward compatibility with old documents
@@ -108,15 +264,135 @@ func (m *MongoDB) GetAllActiveTasks(ctx context.Context) (map[int64][]Task, erro
 		return nil, fmt.Errorf("failed to decode tasks: %w", err)
 	}
 
+	sortTasksByChat(tasks)
+	return tasks, nil
+}
+
+// GetTaskForChat retrieves a task that is not closed, only if it belongs to the chat.
+// It returns nil if there is no such task.
+func (m *MongoDB) GetTaskForChat(ctx context.Context, chatID int64, taskID primitive.ObjectID) (*Task, error) {
+	filter := bson.M{
+		"_id":     taskID,
+		"chat_id": chatID,
+		"status":  bson.M{"$ne": TaskStatusClosed},
+	}
+
+	var task Task
+	err := m.collection.FindOne(ctx, filter).Decode(&task)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	return &task, nil
+}
+
+// GetAllActiveTasks retrieves all active tasks across all chats
+// This excludes closed tasks and chats the bot can no longer reach - includes both active and completed_today tasks
+func (m *MongoDB) GetAllActiveTasks(ctx context.Context) (map[int64][]Task, error) {
+	inactiveChats, err := m.getInactiveChatIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get tasks that are not closed (includes active and completed_today) and not snoozed
+	filter := bson.M{
+		"chat_id": bson.M{"$nin": inactiveChats},
+		"$and": []bson.M{
+			{"$or": []bson.M{
+				{"status": bson.M{"$ne": TaskStatusClosed}},
+				{"status": bson.M{"$exists": false}}, // For backward compatibility with old documents
+			}},
+			{"$or": []bson.M{
+				{"snoozed_until": bson.M{"$exists": false}},
+				{"snoozed_until": bson.M{"$lte": time.Now()}},
+			}},
+		},
+	}
+
+	cursor, err := m.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tasks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var tasks []Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to decode tasks: %w", err)
+	}
+
+	sortTasks(tasks)
+
 	// Group tasks by chat ID
 	tasksByChat := make(map[int64][]Task)
 	for _, task := range tasks {
 		tasksByChat[task.ChatID] = append(tasksByChat[task.ChatID], task)
 	}
 
+	// Chats that joined a shared list are reminded of its tasks too, after their own
+	shares, err := m.findListShares(ctx, bson.M{"chat_id": bson.M{"$nin": inactiveChats}})
+	if err != nil {
+		return nil, err
+	}
+	sharedTasks := make(map[int64][]Task)
+	for _, share := range shares {
+		sharedTasks[share.ChatID] = append(sharedTasks[share.ChatID], tasksByChat[share.OwnerChatID]...)
+	}
+	for chatID, shared := range sharedTasks {
+		tasksByChat[chatID] = append(tasksByChat[chatID], shared...)
+	}
+
 	return tasksByChat, nil
 }
 
+// GetTasksForUser retrieves the tasks a user created or is assigned to, in private chats
+// and the groups they are still a member of, as long as the bot can still reach them.
+// Tasks are ordered by chat, then as in GetTasksByChatID.
+func (m *MongoDB) GetTasksForUser(ctx context.Context, userID int64) ([]Task, error) {
+	inactiveChats, err := m.getInactiveChatIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	memberChats, err := m.getMemberChatIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{
+		"chat_id": bson.M{"$nin": inactiveChats},
+		"$and": []bson.M{
+			{"$or": []bson.M{
+				{"user_id": userID},
+				{"assignees": userID},
+			}},
+			{"$or": []bson.M{
+				{"chat_id": bson.M{"$gt": 0}}, // Only group chats have negative IDs
+				{"chat_id": bson.M{"$in": memberChats}},
+			}},
+			{"$or": []bson.M{
+				{"status": bson.M{"$ne": TaskStatusClosed}},
+				{"status": bson.M{"$exists": false}}, // For backward compatibility with old documents
+			}},
+		},
+	}
+
+	cursor, err := m.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tasks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var tasks []Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to decode tasks: %w", err)
+	}
+
+	sortTasksByChat(tasks)
+	return tasks, nil
+}
+
 // CompleteTask marks a task as completed today
 func (m *MongoDB) CompleteTask(ctx context.Context, taskID primitive.ObjectID) error {
 	filter := bson.M{"_id": taskID}
@@ -188,6 +464,163 @@ func (m *MongoDB) CloseTask(ctx context.Context, taskID primitive.ObjectID) erro
 	return nil
 }
 
+// UpdateTaskDescription changes the text of a task
+func (m *MongoDB) UpdateTaskDescription(ctx context.Context, taskID primitive.ObjectID, description string) error {
+	filter := bson.M{"_id": taskID}
+	update := bson.M{
+		"$set": bson.M{
+			"description": description,
+		},
+	}
+
+	result, err := m.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("task not found")
+	}
+
+	return nil
+}
+
+// SetTaskPriority changes the priority of a task
+func (m *MongoDB) SetTaskPriority(ctx context.Context, taskID primitive.ObjectID, priority TaskPriority) error {
+	filter := bson.M{"_id": taskID}
+	update := bson.M{
+		"$set": bson.M{
+			"priority": priority,
+		},
+	}
+
+	result, err := m.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update task priority: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("task not found")
+	}
+
+	return nil
+}
+
+// SnoozeTask puts off reminders about a task until the given time, or wakes it up when until is nil
+func (m *MongoDB) SnoozeTask(ctx context.Context, taskID primitive.ObjectID, until *time.Time) error {
+	filter := bson.M{"_id": taskID}
+	update := bson.M{"$unset": bson.M{"snoozed_until": ""}}
+	if until != nil {
+		update = bson.M{"$set": bson.M{"snoozed_until": *until}}
+	}
+
+	result, err := m.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to snooze task: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("task not found")
+	}
+
+	return nil
+}
+
+// SetTaskAssignees replaces the members responsible for a task; an empty list unassigns it
+func (m *MongoDB) SetTaskAssignees(ctx context.Context, taskID primitive.ObjectID, assignees []int64) error {
+	filter := bson.M{"_id": taskID}
+	update := bson.M{"$unset": bson.M{"assignees": ""}}
+	if len(assignees) > 0 {
+		update = bson.M{"$set": bson.M{"assignees": assignees}}
+	}
+
+	result, err := m.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update task assignees: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("task not found")
+	}
+
+	return nil
+}
+
+// CompleteTaskForMember records that a member completed a per-member task and returns
+// the updated task. The task itself stays active until every member has completed it.
+func (m *MongoDB) CompleteTaskForMember(ctx context.Context, taskID primitive.ObjectID, userID int64) (*Task, error) {
+	filter := bson.M{"_id": taskID}
+	update := bson.M{"$addToSet": bson.M{"completed_by": userID}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var task Task
+	err := m.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&task)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("task not found")
+		}
+		return nil, fmt.Errorf("failed to update task: %w", err)
+	}
+
+	return &task, nil
+}
+
+// ReactivateTaskForMember withdraws a member's completion of a per-member task,
+// which makes the task active again
+func (m *MongoDB) ReactivateTaskForMember(ctx context.Context, taskID primitive.ObjectID, userID int64) error {
+	filter := bson.M{"_id": taskID}
+	update := bson.M{
+		"$pull": bson.M{"completed_by": userID},
+		"$set": bson.M{
+			"completed": false,
+			"status":    TaskStatusActive,
+		},
+		"$unset": bson.M{
+			"completed_at": "",
+		},
+	}
+
+	result, err := m.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("task not found")
+	}
+
+	return nil
+}
+
+// UpdateTaskRotation replaces the rotation of a task and assigns it to the member on duty.
+// It only applies if the rotation still equals prev, and reports whether it did. A new
+// turn makes the task active again.
+func (m *MongoDB) UpdateTaskRotation(ctx context.Context, taskID primitive.ObjectID, prev, next TaskRotation, newTurn bool) (bool, error) {
+	filter := bson.M{
+		"_id":              taskID,
+		"rotation.members": prev.Members,
+		"rotation.current": prev.Current,
+		"rotation.since":   prev.Since,
+	}
+	set := bson.M{
+		"rotation":  next,
+		"assignees": []int64{next.OnDuty()},
+	}
+	update := bson.M{"$set": set}
+	if newTurn {
+		set["completed"] = false
+		set["status"] = TaskStatusActive
+		update["$unset"] = bson.M{"completed_at": ""}
+	}
+
+	result, err := m.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to update task rotation: %w", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
 // DeleteTask removes a task from storage
 func (m *MongoDB) DeleteTask(ctx context.Context, taskID primitive.ObjectID) error {
 	filter := bson.M{"_id": taskID}
@@ -204,6 +637,18 @@ func (m *MongoDB) DeleteTask(ctx context.Context, taskID primitive.ObjectID) err
 	return nil
 }
 
+// DeleteCapturedTasks removes the tasks created from one quick-capture message
+func (m *MongoDB) DeleteCapturedTasks(ctx context.Context, chatID int64, captureID string) (int64, error) {
+	filter := bson.M{"chat_id": chatID, "capture_id": captureID}
+
+	result, err := m.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete captured tasks: %w", err)
+	}
+
+	return result.DeletedCount, nil
+}
+
 // GetUserSettings retrieves user settings for a specific chat
 func (m *MongoDB) GetUserSettings(ctx context.Context, chatID int64) (*UserSettings, error) {
 	filter := bson.M{"chat_id": chatID}
@@ -227,10 +672,18 @@ func (m *MongoDB) SetUserSettings(ctx context.Context, settings *UserSettings) e
 	filter := bson.M{"chat_id": settings.ChatID}
 	update := bson.M{
 		"$set": bson.M{
-			"user_id":       settings.UserID,
-			"reminder_time": settings.ReminderTime,
-			"timezone":      settings.Timezone,
-			"updated_at":    settings.UpdatedAt,
+			"user_id":           settings.UserID,
+			"reminder_time":     settings.ReminderTime,
+			"timezone":          settings.Timezone,
+			"paused":            settings.Paused,
+			"skip_weekends":     settings.SkipWeekends,
+			"quick_capture":     settings.QuickCapture,
+			"digest":            settings.Digest,
+			"language":          settings.Language,
+			"onboarding_step":   settings.Onboarding,
+			"permissions":       settings.Permissions,
+			"reminder_template": settings.ReminderTemplate,
+			"updated_at":        settings.UpdatedAt,
 		},
 		"$setOnInsert": bson.M{
 			"created_at": time.Now(),
@@ -272,3 +725,767 @@ func (m *MongoDB) GetAllUserSettings(ctx context.Context) (map[int64]*UserSettin
 
 	return settingsByChat, nil
 }
+
+// AcquireLock takes or renews the named lease for the given owner.
+// It returns false if another owner holds a lease that has not expired yet.
+func (m *MongoDB) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": name,
+		"$or": []bson.M{
+			{"owner": owner},
+			{"expires_at": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"owner":      owner,
+			"expires_at": now.Add(ttl),
+			"updated_at": now,
+		},
+	}
+
+	// If the lease is held by someone else the filter does not match and the
+	// upsert collides with the existing document on _id
+	opts := options.Update().SetUpsert(true)
+	if _, err := m.locksCollection.UpdateOne(ctx, filter, update, opts); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+
+	return true, nil
+}
+
+// ReleaseLock gives up the named lease if it is held by the given owner
+func (m *MongoDB) ReleaseLock(ctx context.Context, name, owner string) error {
+	filter := bson.M{"_id": name, "owner": owner}
+	if _, err := m.locksCollection.DeleteOne(ctx, filter); err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", name, err)
+	}
+	return nil
+}
+
+// ClaimReminder records that owner is sending the reminder for a chat and slot.
+// It returns false if the slot has already been claimed by any replica.
+func (m *MongoDB) ClaimReminder(ctx context.Context, chatID int64, slot, owner string) (bool, error) {
+	claim := ReminderClaim{
+		ID:        fmt.Sprintf("%d:%s", chatID, slot),
+		ChatID:    chatID,
+		Slot:      slot,
+		Owner:     owner,
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := m.claimsCollection.InsertOne(ctx, claim); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim reminder: %w", err)
+	}
+
+	return true, nil
+}
+
+// EnqueueOutboxMessage stores a message for asynchronous delivery
+func (m *MongoDB) EnqueueOutboxMessage(ctx context.Context, msg *OutboxMessage) error {
+	now := time.Now()
+	msg.Status = OutboxStatusPending
+	msg.Attempts = 0
+	msg.NextAttemptAt = now
+	msg.CreatedAt = now
+	msg.UpdatedAt = now
+
+	result, err := m.outboxCollection.InsertOne(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue outbox message: %w", err)
+	}
+
+	msg.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// ClaimOutboxMessage atomically picks the next due message and locks it for delivery.
+// Messages claimed by a worker that died are picked up again once their lock expires.
+// It returns nil if nothing is due.
+func (m *MongoDB) ClaimOutboxMessage(ctx context.Context, lockFor time.Duration) (*OutboxMessage, error) {
+	now := time.Now()
+	filter := bson.M{
+		"$or": []bson.M{
+			{"status": OutboxStatusPending, "next_attempt_at": bson.M{"$lte": now}},
+			{"status": OutboxStatusSending, "locked_until": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       OutboxStatusSending,
+			"locked_until": now.Add(lockFor),
+			"updated_at":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var msg OutboxMessage
+	err := m.outboxCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&msg)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim outbox message: %w", err)
+	}
+
+	return &msg, nil
+}
+
+// MarkOutboxMessageSent records a successful delivery
+func (m *MongoDB) MarkOutboxMessageSent(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":     OutboxStatusSent,
+			"sent_at":    now,
+			"updated_at": now,
+		},
+		"$unset": bson.M{
+			"locked_until": "",
+			"last_error":   "",
+		},
+	}
+	return m.updateOutboxMessage(ctx, id, update)
+}
+
+// RetryOutboxMessage schedules another delivery attempt after a transient failure.
+// If counted is false, the attempt counted when the message was claimed is taken back.
+func (m *MongoDB) RetryOutboxMessage(ctx context.Context, id primitive.ObjectID, nextAttemptAt time.Time, lastError string, counted bool) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":          OutboxStatusPending,
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+			"updated_at":      time.Now(),
+		},
+		"$unset": bson.M{
+			"locked_until": "",
+		},
+	}
+	if !counted {
+		update["$inc"] = bson.M{"attempts": -1}
+	}
+	return m.updateOutboxMessage(ctx, id, update)
+}
+
+// DeadLetterOutboxMessage moves a permanently failing message to the dead-letter state
+func (m *MongoDB) DeadLetterOutboxMessage(ctx context.Context, id primitive.ObjectID, lastError string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":     OutboxStatusDead,
+			"last_error": lastError,
+			"updated_at": time.Now(),
+		},
+		"$unset": bson.M{
+			"locked_until": "",
+		},
+	}
+	return m.updateOutboxMessage(ctx, id, update)
+}
+
+func (m *MongoDB) updateOutboxMessage(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	result, err := m.outboxCollection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox message: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("outbox message not found")
+	}
+
+	return nil
+}
+
+// GetDeadOutboxMessages retrieves the most recent dead-lettered messages
+func (m *MongoDB) GetDeadOutboxMessages(ctx context.Context, limit int64) ([]OutboxMessage, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := m.outboxCollection.Find(ctx, bson.M{"status": OutboxStatusDead}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find outbox messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var messages []OutboxMessage
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// CountDeadOutboxMessages returns the number of dead-lettered messages
+func (m *MongoDB) CountDeadOutboxMessages(ctx context.Context) (int64, error) {
+	count, err := m.outboxCollection.CountDocuments(ctx, bson.M{"status": OutboxStatusDead})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count outbox messages: %w", err)
+	}
+	return count, nil
+}
+
+// ReplayOutboxMessages puts dead-lettered messages back into the delivery queue.
+// If id is nil all dead-lettered messages are replayed. It returns the number of replayed messages.
+func (m *MongoDB) ReplayOutboxMessages(ctx context.Context, id *primitive.ObjectID) (int64, error) {
+	filter := bson.M{"status": OutboxStatusDead}
+	if id != nil {
+		filter["_id"] = *id
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":          OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"updated_at":      now,
+		},
+	}
+
+	result, err := m.outboxCollection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, fmt.Errorf("failed to replay outbox messages: %w", err)
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// DeactivateChat marks a chat as unreachable so the bot stops messaging it
+func (m *MongoDB) DeactivateChat(ctx context.Context, chatID int64, reason string) error {
+	now := time.Now()
+	filter := bson.M{"chat_id": chatID}
+	update := bson.M{
+		"$set": bson.M{
+			"active":          false,
+			"inactive_reason": reason,
+			"deactivated_at":  now,
+			"updated_at":      now,
+		},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	if _, err := m.chatsCollection.UpdateOne(ctx, filter, update, opts); err != nil {
+		return fmt.Errorf("failed to deactivate chat: %w", err)
+	}
+
+	return nil
+}
+
+// ActivateChat marks a chat as reachable again.
+// It returns true if the chat was inactive before.
+func (m *MongoDB) ActivateChat(ctx context.Context, chatID int64) (bool, error) {
+	filter := bson.M{"chat_id": chatID, "active": false}
+	update := bson.M{
+		"$set": bson.M{
+			"active":     true,
+			"updated_at": time.Now(),
+		},
+		"$unset": bson.M{
+			"inactive_reason": "",
+			"deactivated_at":  "",
+		},
+	}
+
+	result, err := m.chatsCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to activate chat: %w", err)
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
+// SetChatTitle remembers the title of a group chat
+func (m *MongoDB) SetChatTitle(ctx context.Context, chatID int64, title string) error {
+	filter := bson.M{"chat_id": chatID}
+	update := bson.M{
+		"$set": bson.M{
+			"title":      title,
+			"updated_at": time.Now(),
+		},
+		"$setOnInsert": bson.M{"active": true},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	if _, err := m.chatsCollection.UpdateOne(ctx, filter, update, opts); err != nil {
+		return fmt.Errorf("failed to update chat: %w", err)
+	}
+
+	return nil
+}
+
+// SetChatName remembers the first name of the user of a private chat
+func (m *MongoDB) SetChatName(ctx context.Context, chatID int64, name string) error {
+	filter := bson.M{"chat_id": chatID}
+	update := bson.M{
+		"$set": bson.M{
+			"name":       name,
+			"updated_at": time.Now(),
+		},
+		"$setOnInsert": bson.M{"active": true},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	if _, err := m.chatsCollection.UpdateOne(ctx, filter, update, opts); err != nil {
+		return fmt.Errorf("failed to update chat: %w", err)
+	}
+
+	return nil
+}
+
+// SetChatStreak stores the chat's streak of days with completed tasks, ending on day
+func (m *MongoDB) SetChatStreak(ctx context.Context, chatID int64, streak int, day string) error {
+	filter := bson.M{"chat_id": chatID}
+	update := bson.M{
+		"$set": bson.M{
+			"streak":     streak,
+			"streak_day": day,
+			"updated_at": time.Now(),
+		},
+		"$setOnInsert": bson.M{"active": true},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	if _, err := m.chatsCollection.UpdateOne(ctx, filter, update, opts); err != nil {
+		return fmt.Errorf("failed to update chat: %w", err)
+	}
+
+	return nil
+}
+
+// GetChats retrieves the chats with the given IDs, keyed by chat ID.
+// Chats the bot knows nothing about are missing from the result.
+func (m *MongoDB) GetChats(ctx context.Context, chatIDs []int64) (map[int64]Chat, error) {
+	cursor, err := m.chatsCollection.Find(ctx, bson.M{"chat_id": bson.M{"$in": chatIDs}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find chats: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var chats []Chat
+	if err := cursor.All(ctx, &chats); err != nil {
+		return nil, fmt.Errorf("failed to decode chats: %w", err)
+	}
+
+	result := make(map[int64]Chat, len(chats))
+	for _, chat := range chats {
+		result[chat.ChatID] = chat
+	}
+
+	return result, nil
+}
+
+// getInactiveChatIDs returns the IDs of all chats the bot can no longer reach
+func (m *MongoDB) getInactiveChatIDs(ctx context.Context) ([]int64, error) {
+	cursor, err := m.chatsCollection.Find(ctx, bson.M{"active": false})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find chats: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var chats []Chat
+	if err := cursor.All(ctx, &chats); err != nil {
+		return nil, fmt.Errorf("failed to decode chats: %w", err)
+	}
+
+	chatIDs := make([]int64, 0, len(chats))
+	for _, chat := range chats {
+		chatIDs = append(chatIDs, chat.ChatID)
+	}
+
+	return chatIDs, nil
+}
+
+// getMemberChatIDs returns the IDs of the groups the bot has the user on record as a member of
+func (m *MongoDB) getMemberChatIDs(ctx context.Context, userID int64) ([]int64, error) {
+	cursor, err := m.membersCollection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find chat members: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var members []ChatMember
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, fmt.Errorf("failed to decode chat members: %w", err)
+	}
+
+	chatIDs := make([]int64, 0, len(members))
+	for _, member := range members {
+		chatIDs = append(chatIDs, member.ChatID)
+	}
+
+	return chatIDs, nil
+}
+
+// SetConversation creates or replaces the conversation of a chat member
+func (m *MongoDB) SetConversation(ctx context.Context, conv *Conversation) error {
+	conv.UpdatedAt = time.Now()
+
+	filter := bson.M{"chat_id": conv.ChatID, "user_id": conv.UserID}
+	update := bson.M{
+		"$set": bson.M{
+			"flow":       conv.Flow,
+			"step":       conv.Step,
+			"data":       conv.Data,
+			"prompt_id":  conv.PromptID,
+			"expires_at": conv.ExpiresAt,
+			"updated_at": conv.UpdatedAt,
+		},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	result, err := m.convCollection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation: %w", err)
+	}
+
+	if result.UpsertedID != nil {
+		conv.ID = result.UpsertedID.(primitive.ObjectID)
+	}
+
+	return nil
+}
+
+// SetConversationPrompt remembers the message that asked the member's pending question
+func (m *MongoDB) SetConversationPrompt(ctx context.Context, chatID, userID int64, messageID int) error {
+	filter := bson.M{"chat_id": chatID, "user_id": userID}
+	update := bson.M{"$set": bson.M{"prompt_id": messageID}}
+	if _, err := m.convCollection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to update conversation: %w", err)
+	}
+	return nil
+}
+
+// GetConversation retrieves the unexpired conversation of a chat member, or nil if there is none
+func (m *MongoDB) GetConversation(ctx context.Context, chatID, userID int64) (*Conversation, error) {
+	// MongoDB removes expired documents only about once a minute, so check the expiry too
+	filter := bson.M{
+		"chat_id":    chatID,
+		"user_id":    userID,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	var conv Conversation
+	err := m.convCollection.FindOne(ctx, filter).Decode(&conv)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find conversation: %w", err)
+	}
+
+	return &conv, nil
+}
+
+// DeleteConversation ends the conversation of a chat member.
+// It returns true if there was an unexpired conversation.
+func (m *MongoDB) DeleteConversation(ctx context.Context, chatID, userID int64) (bool, error) {
+	filter := bson.M{"chat_id": chatID, "user_id": userID}
+
+	var conv Conversation
+	err := m.convCollection.FindOneAndDelete(ctx, filter).Decode(&conv)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to delete conversation: %w", err)
+	}
+
+	return conv.ExpiresAt.After(time.Now()), nil
+}
+
+// UpsertChatMember creates or updates a group member's names
+func (m *MongoDB) UpsertChatMember(ctx context.Context, member *ChatMember) error {
+	member.UpdatedAt = time.Now()
+
+	filter := bson.M{"chat_id": member.ChatID, "user_id": member.UserID}
+	update := bson.M{
+		"$set": bson.M{
+			"username":   member.Username,
+			"first_name": member.FirstName,
+			"last_name":  member.LastName,
+			"updated_at": member.UpdatedAt,
+		},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	result, err := m.membersCollection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		return fmt.Errorf("failed to update chat member: %w", err)
+	}
+
+	if result.UpsertedID != nil {
+		member.ID = result.UpsertedID.(primitive.ObjectID)
+	}
+
+	return nil
+}
+
+// RemoveChatMember forgets a member who left the group
+func (m *MongoDB) RemoveChatMember(ctx context.Context, chatID, userID int64) error {
+	filter := bson.M{"chat_id": chatID, "user_id": userID}
+
+	if _, err := m.membersCollection.DeleteOne(ctx, filter); err != nil {
+		return fmt.Errorf("failed to delete chat member: %w", err)
+	}
+
+	return nil
+}
+
+// SetChatMemberPaused pauses or resumes a member's turns in rotations.
+// It returns false if the bot hasn't seen the member.
+func (m *MongoDB) SetChatMemberPaused(ctx context.Context, chatID, userID int64, paused bool) (bool, error) {
+	filter := bson.M{"chat_id": chatID, "user_id": userID}
+	update := bson.M{"$set": bson.M{"paused": paused}}
+
+	result, err := m.membersCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to update chat member: %w", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// GetChatMembers retrieves the known members of a group, in the order they were first seen
+func (m *MongoDB) GetChatMembers(ctx context.Context, chatID int64) ([]ChatMember, error) {
+	filter := bson.M{"chat_id": chatID}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := m.membersCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find chat members: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var members []ChatMember
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, fmt.Errorf("failed to decode chat members: %w", err)
+	}
+
+	return members, nil
+}
+
+// GetChatMemberByUsername finds a group member by username (lowercase, without the @).
+// It returns nil if the bot hasn't seen such a member.
+func (m *MongoDB) GetChatMemberByUsername(ctx context.Context, chatID int64, username string) (*ChatMember, error) {
+	filter := bson.M{"chat_id": chatID, "username": username}
+
+	var member ChatMember
+	err := m.membersCollection.FindOne(ctx, filter).Decode(&member)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find chat member: %w", err)
+	}
+
+	return &member, nil
+}
+
+// CreateShareInvite stores a new invite to a chat's task list
+func (m *MongoDB) CreateShareInvite(ctx context.Context, invite *ShareInvite) error {
+	invite.CreatedAt = time.Now()
+
+	result, err := m.invitesCollection.InsertOne(ctx, invite)
+	if err != nil {
+		return fmt.Errorf("failed to insert share invite: %w", err)
+	}
+
+	invite.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetShareInvite finds an invite by its token. It returns nil if there is no such
+// invite or it was revoked.
+func (m *MongoDB) GetShareInvite(ctx context.Context, token string) (*ShareInvite, error) {
+	filter := bson.M{"token": token, "revoked_at": bson.M{"$exists": false}}
+
+	var invite ShareInvite
+	err := m.invitesCollection.FindOne(ctx, filter).Decode(&invite)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find share invite: %w", err)
+	}
+
+	return &invite, nil
+}
+
+// GetShareInvites retrieves the invites of a chat that weren't revoked, oldest first
+func (m *MongoDB) GetShareInvites(ctx context.Context, chatID int64) ([]ShareInvite, error) {
+	filter := bson.M{"chat_id": chatID, "revoked_at": bson.M{"$exists": false}}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := m.invitesCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find share invites: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var invites []ShareInvite
+	if err := cursor.All(ctx, &invites); err != nil {
+		return nil, fmt.Errorf("failed to decode share invites: %w", err)
+	}
+
+	return invites, nil
+}
+
+// RevokeShareInvite invalidates an invite and ends the shares of the chats that joined
+// with it. It returns the number of chats that lost access.
+func (m *MongoDB) RevokeShareInvite(ctx context.Context, chatID int64, token string) (int64, error) {
+	filter := bson.M{"chat_id": chatID, "token": token}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+
+	if _, err := m.invitesCollection.UpdateOne(ctx, filter, update); err != nil {
+		return 0, fmt.Errorf("failed to revoke share invite: %w", err)
+	}
+
+	result, err := m.sharesCollection.DeleteMany(ctx, bson.M{"owner_chat_id": chatID, "token": token})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete list shares: %w", err)
+	}
+
+	return result.DeletedCount, nil
+}
+
+// JoinSharedList gives a chat access to another chat's tasks, or changes the access
+// of a chat that joined before
+func (m *MongoDB) JoinSharedList(ctx context.Context, share *ListShare) error {
+	filter := bson.M{"owner_chat_id": share.OwnerChatID, "chat_id": share.ChatID}
+	update := bson.M{
+		"$set": bson.M{
+			"name":      share.Name,
+			"access":    share.Access,
+			"token":     share.Token,
+			"joined_by": share.JoinedBy,
+		},
+		"$setOnInsert": bson.M{
+			"created_at": time.Now(),
+		},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	result, err := m.sharesCollection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		return fmt.Errorf("failed to update list share: %w", err)
+	}
+
+	if result.UpsertedID != nil {
+		share.ID = result.UpsertedID.(primitive.ObjectID)
+	}
+
+	return nil
+}
+
+// LeaveSharedList ends a chat's access to another chat's tasks.
+// It returns false if the chat hadn't joined the list.
+func (m *MongoDB) LeaveSharedList(ctx context.Context, ownerChatID, chatID int64) (bool, error) {
+	filter := bson.M{"owner_chat_id": ownerChatID, "chat_id": chatID}
+
+	result, err := m.sharesCollection.DeleteOne(ctx, filter)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete list share: %w", err)
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
+// GetListShares retrieves the lists a chat joined, oldest first
+func (m *MongoDB) GetListShares(ctx context.Context, chatID int64) ([]ListShare, error) {
+	return m.findListShares(ctx, bson.M{"chat_id": chatID})
+}
+
+// GetListSubscribers retrieves the chats that joined a chat's list, oldest first
+func (m *MongoDB) GetListSubscribers(ctx context.Context, ownerChatID int64) ([]ListShare, error) {
+	return m.findListShares(ctx, bson.M{"owner_chat_id": ownerChatID})
+}
+
+func (m *MongoDB) findListShares(ctx context.Context, filter bson.M) ([]ListShare, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := m.sharesCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find list shares: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var shares []ListShare
+	if err := cursor.All(ctx, &shares); err != nil {
+		return nil, fmt.Errorf("failed to decode list shares: %w", err)
+	}
+
+	return shares, nil
+}
+
+// AddReminderMessage tracks a daily reminder sent to a chat
+func (m *MongoDB) AddReminderMessage(ctx context.Context, reminder *ReminderMessage) error {
+	if reminder.SentAt.IsZero() {
+		reminder.SentAt = time.Now()
+	}
+
+	result, err := m.remindersCollection.InsertOne(ctx, reminder)
+	if err != nil {
+		return fmt.Errorf("failed to insert reminder message: %w", err)
+	}
+
+	reminder.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetReminderMessages retrieves the reminders sent to a chat on a day, oldest first
+func (m *MongoDB) GetReminderMessages(ctx context.Context, chatID int64, day string) ([]ReminderMessage, error) {
+	filter := bson.M{"chat_id": chatID, "day": day}
+	opts := options.Find().SetSort(bson.D{{Key: "sent_at", Value: 1}})
+
+	cursor, err := m.remindersCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reminder messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var reminders []ReminderMessage
+	if err := cursor.All(ctx, &reminders); err != nil {
+		return nil, fmt.Errorf("failed to decode reminder messages: %w", err)
+	}
+
+	return reminders, nil
+}
+
+// UpdateReminderMessage records the page and keyboard a tracked reminder shows now.
+// Reminders that aren't tracked are left alone.
+func (m *MongoDB) UpdateReminderMessage(ctx context.Context, chatID int64, messageID, page int, keyboardHash string) error {
+	filter := bson.M{"chat_id": chatID, "message_id": messageID}
+	update := bson.M{
+		"$set": bson.M{
+			"page":          page,
+			"keyboard_hash": keyboardHash,
+		},
+	}
+
+	if _, err := m.remindersCollection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to update reminder message: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteReminderMessage stops tracking a reminder, e.g. after it was deleted from the chat
+func (m *MongoDB) DeleteReminderMessage(ctx context.Context, chatID int64, messageID int) error {
+	filter := bson.M{"chat_id": chatID, "message_id": messageID}
+	if _, err := m.remindersCollection.DeleteOne(ctx, filter); err != nil {
+		return fmt.Errorf("failed to delete reminder message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxStatus represents the delivery state of an outbound message
+type OutboxStatus string
+
+const (
+	// OutboxStatusPending means the message is waiting for its next delivery attempt
+	OutboxStatusPending OutboxStatus = "pending"
+	// OutboxStatusSending means a delivery worker has claimed the message
+	OutboxStatusSending OutboxStatus = "sending"
+	// OutboxStatusSent means the message was delivered to Telegram
+	OutboxStatusSent OutboxStatus = "sent"
+	// OutboxStatusDead means delivery failed permanently and the message needs manual replay
+	OutboxStatusDead OutboxStatus = "dead"
+)
+
+// OutboxMessage represents a message queued for delivery to a chat
+type OutboxMessage struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID        int64              `bson:"chat_id"`
+	Kind          string             `bson:"kind"` // e.g., "reminder"
+	Text          string             `bson:"text"`
+	Entities      string             `bson:"entities,omitempty"`     // JSON-encoded message entities, e.g. mentions
+	ReplyMarkup   string             `bson:"reply_markup,omitempty"` // JSON-encoded inline keyboard
+	ParseMode     string             `bson:"parse_mode,omitempty"`   // e.g., "HTML"; Entities are not used then
+	Status        OutboxStatus       `bson:"status"`
+	Attempts      int                `bson:"attempts"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at"`
+	LockedUntil   *time.Time         `bson:"locked_until,omitempty"` // When a crashed worker's claim can be taken over
+	LastError     string             `bson:"last_error,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at"`
+	SentAt        *time.Time         `bson:"sent_at,omitempty"`
+}
@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReminderMessage is a daily reminder sent to a chat. While it's the chat's day of
+// the reminder, its keyboard is re-rendered whenever the chat's tasks change.
+type ReminderMessage struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID       int64              `bson:"chat_id"`
+	MessageID    int                `bson:"message_id"`
+	Day          string             `bson:"day"`                     // Date in the chat's timezone, format: "YYYY-MM-DD"
+	Page         int                `bson:"page"`                    // Page of tasks the keyboard shows
+	KeyboardHash string             `bson:"keyboard_hash,omitempty"` // Of the keyboard last shown, to skip edits that change nothing
+	SentAt       time.Time          `bson:"sent_at"`
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareAccess is what a chat may do with a task list shared with it
+type ShareAccess string
+
+const (
+	// ShareAccessView lets the chat see the tasks and get reminders about them
+	ShareAccessView ShareAccess = "view"
+	// ShareAccessEdit also lets the chat complete, edit and close the tasks
+	ShareAccessEdit ShareAccess = "edit"
+)
+
+// ShareInvite is a revocable invite to a chat's task list, sent as a join_<token> deep link
+type ShareInvite struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Token     string             `bson:"token"`
+	ChatID    int64              `bson:"chat_id"` // The chat whose tasks are shared
+	Name      string             `bson:"name"`    // Shown to the chats that join, e.g. the group title
+	Access    ShareAccess        `bson:"access"`
+	CreatedBy int64              `bson:"created_by"`
+	CreatedAt time.Time          `bson:"created_at"`
+	RevokedAt *time.Time         `bson:"revoked_at,omitempty"`
+}
+
+// ListShare gives a chat access to another chat's task list
+type ListShare struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	OwnerChatID int64              `bson:"owner_chat_id"` // The chat whose tasks are shared
+	ChatID      int64              `bson:"chat_id"`       // The chat that joined
+	Name        string             `bson:"name"`
+	Access      ShareAccess        `bson:"access"`
+	Token       string             `bson:"token"` // The invite used to join; revoking it ends the share
+	JoinedBy    int64              `bson:"joined_by"`
+	CreatedAt   time.Time          `bson:"created_at"`
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"sort"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -18,14 +19,107 @@ const (
 	TaskStatusClosed TaskStatus = "closed"
 )
 
+// TaskPriority ranks a task in lists and reminders
+type TaskPriority int
+
+const (
+	// TaskPriorityLow marks a task that can wait
+	TaskPriorityLow TaskPriority = -1
+	// TaskPriorityNormal is the default priority
+	TaskPriorityNormal TaskPriority = 0
+	// TaskPriorityHigh marks an important task
+	TaskPriorityHigh TaskPriority = 1
+)
+
+// RotationPeriod is how long each member's turn on a rotating task lasts
+type RotationPeriod string
+
+const (
+	// RotationDaily passes the task to the next member every day
+	RotationDaily RotationPeriod = "daily"
+	// RotationWeekly passes the task to the next member every Monday
+	RotationWeekly RotationPeriod = "weekly"
+	// RotationOnCompletion passes the task to the next member when it is completed
+	RotationOnCompletion RotationPeriod = "completion"
+)
+
 // Task represents a task to be completed
 type Task struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty"`
-	ChatID      int64              `bson:"chat_id"`
-	UserID      int64              `bson:"user_id"`
-	Description string             `bson:"description"`
-	CreatedAt   time.Time          `bson:"created_at"`
-	Completed   bool               `bson:"completed"` // Deprecated: kept for backward compatibility
-	Status      TaskStatus         `bson:"status"`
-	CompletedAt *time.Time         `bson:"completed_at,omitempty"` // When the task was completed
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID       int64              `bson:"chat_id"`
+	UserID       int64              `bson:"user_id"`
+	Description  string             `bson:"description"`
+	CreatedAt    time.Time          `bson:"created_at"`
+	Completed    bool               `bson:"completed"` // Deprecated: kept for backward compatibility
+	Status       TaskStatus         `bson:"status"`
+	CompletedAt  *time.Time         `bson:"completed_at,omitempty"` // When the task was completed
+	Priority     TaskPriority       `bson:"priority,omitempty"`
+	SnoozedUntil *time.Time         `bson:"snoozed_until,omitempty"` // No reminders about the task until then
+	CaptureID    string             `bson:"capture_id,omitempty"`    // Groups tasks created from one quick-capture message
+	Source       *TaskSource        `bson:"source,omitempty"`        // Set for tasks created from forwarded messages
+	Assignees    []int64            `bson:"assignees,omitempty"`     // User IDs of the group members responsible for the task
+	PerMember    bool               `bson:"per_member,omitempty"`    // Every member (or assignee) completes the task individually
+	CompletedBy  []int64            `bson:"completed_by,omitempty"`  // Members who completed a per-member task
+	Rotation     *TaskRotation      `bson:"rotation,omitempty"`      // Set for chores the members take turns on
+}
+
+// TaskRotation is the turn order of a rotating task. The assignee of the task is the
+// member whose turn it is.
+type TaskRotation struct {
+	Members []int64        `bson:"members"` // User IDs in turn order
+	Current int            `bson:"current"` // Index in Members of the member whose turn it is
+	Period  RotationPeriod `bson:"period"`
+	Since   time.Time      `bson:"since"` // When the current turn started
+}
+
+// OnDuty returns the member whose turn it is
+func (r TaskRotation) OnDuty() int64 {
+	return r.Members[r.Current]
+}
+
+// TaskSource describes the original message a forwarded task came from
+type TaskSource struct {
+	SenderName string `bson:"sender_name,omitempty"` // User, channel or hidden sender name
+	Username   string `bson:"username,omitempty"`    // @username of the sender or channel, if public
+	Link       string `bson:"link,omitempty"`        // t.me link to the original message, if public
+}
+
+// IsSnoozed reports whether reminders about the task are put off at now
+func (t Task) IsSnoozed(now time.Time) bool {
+	return t.SnoozedUntil != nil && t.SnoozedUntil.After(now)
+}
+
+// IsAssignedTo reports whether the user is one of the task's assignees
+func (t Task) IsAssignedTo(userID int64) bool {
+	return containsID(t.Assignees, userID)
+}
+
+// IsCompletedBy reports whether the member completed a per-member task
+func (t Task) IsCompletedBy(userID int64) bool {
+	return containsID(t.CompletedBy, userID)
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// sortTasks orders tasks by priority, keeping the order they were added within a priority.
+// Task numbers in commands refer to this order.
+func sortTasks(tasks []Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Priority > tasks[j].Priority
+	})
+}
+
+// sortTasksByChat groups tasks by chat, keeping the order of sortTasks within each chat
+func sortTasksByChat(tasks []Task) {
+	sortTasks(tasks)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].ChatID < tasks[j].ChatID
+	})
 }
@@ -6,13 +6,86 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// OnboardingStep is the step of the /start wizard a chat has reached
+type OnboardingStep string
+
+const (
+	// OnboardingLanguage asks for the interface language
+	OnboardingLanguage OnboardingStep = "language"
+	// OnboardingTimezone asks for a location or timezone
+	OnboardingTimezone OnboardingStep = "timezone"
+	// OnboardingTime asks for the daily reminder time
+	OnboardingTime OnboardingStep = "time"
+	// OnboardingTask asks for the first task
+	OnboardingTask OnboardingStep = "task"
+	// OnboardingDone means the wizard was completed or skipped
+	OnboardingDone OnboardingStep = "done"
+)
+
 // UserSettings represents user-specific settings
 type UserSettings struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty"`
-	ChatID       int64              `bson:"chat_id"`
-	UserID       int64              `bson:"user_id"`
-	ReminderTime string             `bson:"reminder_time"` // Format: "HH:MM" (24-hour format)
-	Timezone     string             `bson:"timezone"`      // e.g., "UTC", "America/New_York"
-	CreatedAt    time.Time          `bson:"created_at"`
-	UpdatedAt    time.Time          `bson:"updated_at"`
+	ID               primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID           int64              `bson:"chat_id"`
+	UserID           int64              `bson:"user_id"`
+	ReminderTime     string             `bson:"reminder_time"`               // Format: "HH:MM" (24-hour format)
+	Timezone         string             `bson:"timezone"`                    // e.g., "UTC", "America/New_York"
+	Paused           bool               `bson:"paused"`                      // Daily reminders are turned off
+	SkipWeekends     bool               `bson:"skip_weekends"`               // No reminders on Saturday and Sunday
+	QuickCapture     bool               `bson:"quick_capture"`               // Plain messages in private chats become tasks
+	Digest           bool               `bson:"digest"`                      // The private chat's reminder lists the user's tasks from all chats
+	Language         string             `bson:"language,omitempty"`          // e.g., "en", "ru"
+	Onboarding       OnboardingStep     `bson:"onboarding_step,omitempty"`   // Empty for chats set up before onboarding existed
+	Permissions      Permissions        `bson:"permissions"`                 // Who may do what in a group chat
+	ReminderTemplate string             `bson:"reminder_template,omitempty"` // text/template for the daily reminder, empty for the default
+	CreatedAt        time.Time          `bson:"created_at"`
+	UpdatedAt        time.Time          `bson:"updated_at"`
+}
+
+// PermissionPolicy decides which members of a group chat may perform an action
+type PermissionPolicy string
+
+const (
+	// PolicyEveryone lets every member perform the action
+	PolicyEveryone PermissionPolicy = "everyone"
+	// PolicyOwners lets the task's creator and assignees perform the action, and chat admins
+	PolicyOwners PermissionPolicy = "owners"
+	// PolicyAdmins lets only chat admins perform the action
+	PolicyAdmins PermissionPolicy = "admins"
+)
+
+// Valid reports whether p is a known policy
+func (p PermissionPolicy) Valid() bool {
+	return p == PolicyEveryone || p == PolicyOwners || p == PolicyAdmins
+}
+
+// Permissions holds the policies of a group chat. Empty policies mean the default.
+type Permissions struct {
+	Complete PermissionPolicy `bson:"complete,omitempty"` // Mark tasks done or not done
+	Edit     PermissionPolicy `bson:"edit,omitempty"`     // Change a task's text, assignees, priority, snooze or turns
+	Close    PermissionPolicy `bson:"close,omitempty"`    // Close tasks
+	Settings PermissionPolicy `bson:"settings,omitempty"` // Change the chat's settings
+}
+
+// DefaultPermissions apply to chats that didn't choose their own
+var DefaultPermissions = Permissions{
+	Complete: PolicyEveryone,
+	Edit:     PolicyOwners,
+	Close:    PolicyOwners,
+	Settings: PolicyAdmins,
+}
+
+// WithDefaults returns the permissions with empty or unknown policies set to the default
+func (p Permissions) WithDefaults() Permissions {
+	orDefault := func(policy, def PermissionPolicy) PermissionPolicy {
+		if policy.Valid() {
+			return policy
+		}
+		return def
+	}
+	return Permissions{
+		Complete: orDefault(p.Complete, DefaultPermissions.Complete),
+		Edit:     orDefault(p.Edit, DefaultPermissions.Edit),
+		Close:    orDefault(p.Close, DefaultPermissions.Close),
+		Settings: orDefault(p.Settings, DefaultPermissions.Settings),
+	}
 }
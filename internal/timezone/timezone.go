@@ -0,0 +1,425 @@
+// Package timezone resolves free-form user input and coordinates to timezones.
+package timezone
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed cities.csv
+var citiesCSV string
+
+//go:embed boundaries.csv
+var boundariesCSV string
+
+// City is a reference location used to resolve names and coordinates to a timezone
+type City struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+	Zone      string   // IANA timezone name
+	Aliases   []string // Alternative spellings, abbreviations and translations
+}
+
+// cities is the embedded offline dataset of reference locations
+var cities = mustParseCities(citiesCSV)
+
+// boundary is a simplified timezone boundary polygon
+type boundary struct {
+	zone           string
+	points         [][2]float64 // Latitude and longitude of each vertex
+	minLat, maxLat float64
+	minLon, maxLon float64
+}
+
+// boundaries is the embedded offline dataset of timezone boundaries, in lookup order
+var boundaries = mustParseBoundaries(boundariesCSV)
+
+// abbreviations maps common timezone abbreviations to a representative IANA zone.
+// Ambiguous abbreviations resolve to the most widely used meaning (IST is India).
+var abbreviations = map[string]string{
+	"utc": "UTC", "gmt": "UTC", "z": "UTC",
+	"et": "America/New_York", "est": "America/New_York", "edt": "America/New_York",
+	"ct": "America/Chicago", "cst": "America/Chicago", "cdt": "America/Chicago",
+	"mt": "America/Denver", "mst": "America/Denver", "mdt": "America/Denver",
+	"pt": "America/Los_Angeles", "pst": "America/Los_Angeles", "pdt": "America/Los_Angeles",
+	"akst": "America/Anchorage", "akdt": "America/Anchorage",
+	"hst": "Pacific/Honolulu",
+	"ast": "America/Halifax", "adt": "America/Halifax",
+	"nst": "America/St_Johns", "ndt": "America/St_Johns",
+	"brt": "America/Sao_Paulo", "art": "America/Argentina/Buenos_Aires",
+	"wet": "Europe/Lisbon", "west": "Europe/Lisbon",
+	"bst": "Europe/London",
+	"cet": "Europe/Berlin", "cest": "Europe/Berlin",
+	"eet": "Europe/Athens", "eest": "Europe/Athens",
+	"msk": "Europe/Moscow", "trt": "Europe/Istanbul",
+	"gst": "Asia/Dubai", "pkt": "Asia/Karachi", "ist": "Asia/Kolkata",
+	"ict": "Asia/Bangkok", "wib": "Asia/Jakarta", "sgt": "Asia/Singapore",
+	"hkt": "Asia/Hong_Kong", "pht": "Asia/Manila",
+	"kst": "Asia/Seoul", "jst": "Asia/Tokyo",
+	"awst": "Australia/Perth", "acst": "Australia/Adelaide",
+	"aest": "Australia/Sydney", "aedt": "Australia/Sydney",
+	"nzst": "Pacific/Auckland", "nzdt": "Pacific/Auckland",
+	"wat": "Africa/Lagos", "cat": "Africa/Maputo", "eat": "Africa/Nairobi",
+	"sast": "Africa/Johannesburg",
+}
+
+// offsetInput matches UTC offsets such as "+3", "+03:00", "UTC-5", "GMT+05:30" and "utc+0530"
+var offsetInput = regexp.MustCompile(`^(?:utc|gmt)?\s*([+-])\s*(\d{1,2})(?::?(\d{2}))?$`)
+
+// offsetName matches the normalized fixed-offset names produced by Parse
+var offsetName = regexp.MustCompile(`^UTC([+-])(\d{2}):(\d{2})$`)
+
+// maxLocationDistance is how far from the nearest reference city a shared
+// location may be before it is treated as open sea
+const maxLocationDistance = 1500.0 // km
+
+// NotFoundError is returned by Parse when the input does not match any timezone
+type NotFoundError struct {
+	Input       string
+	Suggestions []string // Close matches, best first
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("unknown timezone %q", e.Input)
+}
+
+// Load loads a timezone name returned by Parse: an IANA name or a fixed
+// UTC offset such as "UTC+03:00"
+func Load(name string) (*time.Location, error) {
+	if m := offsetName.FindStringSubmatch(name); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		seconds := hours*3600 + minutes*60
+		if m[1] == "-" {
+			seconds = -seconds
+		}
+		return time.FixedZone(name, seconds), nil
+	}
+
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return time.LoadLocation(name)
+}
+
+// Parse resolves user input to a timezone name that Load understands.
+// It accepts IANA names in any case, UTC offsets, common abbreviations and
+// city names from the embedded dataset. If nothing matches, it returns a
+// *NotFoundError with suggestions for near misses.
+func Parse(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", &NotFoundError{Input: input}
+	}
+
+	if m := offsetInput.FindStringSubmatch(strings.ToLower(input)); m != nil {
+		return parseOffset(input, m)
+	}
+
+	key := normalize(input)
+	if zone, ok := abbreviations[key]; ok {
+		return zone, nil
+	}
+
+	if strings.Contains(input, "/") {
+		if _, err := Load(input); err == nil {
+			return input, nil
+		}
+	}
+
+	for _, city := range cities {
+		if normalize(city.Zone) == key {
+			return city.Zone, nil
+		}
+		if normalize(city.Name) == key {
+			return city.Zone, nil
+		}
+		for _, alias := range city.Aliases {
+			if normalize(alias) == key {
+				return city.Zone, nil
+			}
+		}
+	}
+
+	return "", &NotFoundError{Input: input, Suggestions: suggest(key)}
+}
+
+func parseOffset(input string, m []string) (string, error) {
+	hours, _ := strconv.Atoi(m[2])
+	minutes := 0
+	if m[3] != "" {
+		minutes, _ = strconv.Atoi(m[3])
+	}
+
+	// Real offsets range from UTC-12:00 to UTC+14:00
+	if hours > 14 || minutes >= 60 || (m[1] == "-" && hours > 12) || (hours == 14 && minutes > 0) {
+		return "", &NotFoundError{Input: input}
+	}
+
+	if hours == 0 && minutes == 0 {
+		return "UTC", nil
+	}
+	return fmt.Sprintf("UTC%s%02d:%02d", m[1], hours, minutes), nil
+}
+
+// FromCoordinates returns the timezone of the boundary polygon containing
+// the point, and true. The embedded boundaries only cover most of Europe and
+// the US, Canadian and Mexican border regions, so elsewhere it returns a guess
+// and false: the timezone of the nearest reference city, or for points far
+// away from any city, such as ships at sea, the nautical timezone for their
+// longitude.
+func FromCoordinates(latitude, longitude float64) (string, bool) {
+	for _, b := range boundaries {
+		if b.contains(latitude, longitude) {
+			return b.zone, true
+		}
+	}
+
+	best := -1
+	bestDistance := math.Inf(1)
+	for i, city := range cities {
+		d := distance(latitude, longitude, city.Latitude, city.Longitude)
+		if d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+
+	if best >= 0 && bestDistance <= maxLocationDistance {
+		return cities[best].Zone, false
+	}
+
+	hours := int(math.Round(longitude / 15))
+	if hours == 0 {
+		return "UTC", false
+	}
+	sign := "+"
+	if hours < 0 {
+		sign, hours = "-", -hours
+	}
+	return fmt.Sprintf("UTC%s%02d:00", sign, hours), false
+}
+
+// contains reports whether a point lies inside the polygon, using ray casting
+func (b *boundary) contains(latitude, longitude float64) bool {
+	if latitude < b.minLat || latitude > b.maxLat || longitude < b.minLon || longitude > b.maxLon {
+		return false
+	}
+
+	inside := false
+	for i, j := 0, len(b.points)-1; i < len(b.points); j, i = i, i+1 {
+		lat1, lon1 := b.points[i][0], b.points[i][1]
+		lat2, lon2 := b.points[j][0], b.points[j][1]
+		if (lat1 > latitude) != (lat2 > latitude) &&
+			longitude < lon1+(latitude-lat1)*(lon2-lon1)/(lat2-lat1) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Cities returns the embedded reference cities
+func Cities() []City {
+	return cities
+}
+
+// Regions returns the continents and oceans of the zones in the dataset, sorted by name
+func Regions() []string {
+	seen := make(map[string]bool)
+	var regions []string
+	for _, city := range cities {
+		region, _, ok := strings.Cut(city.Zone, "/")
+		if !ok || seen[region] {
+			continue
+		}
+		seen[region] = true
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// ZonesInRegion returns the distinct zones of a region in the dataset, sorted by name
+func ZonesInRegion(region string) []string {
+	seen := make(map[string]bool)
+	var zones []string
+	for _, city := range cities {
+		if !strings.HasPrefix(city.Zone, region+"/") || seen[city.Zone] {
+			continue
+		}
+		seen[city.Zone] = true
+		zones = append(zones, city.Zone)
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+// CityName returns the human-readable city part of a zone name, e.g. "New York" for "America/New_York"
+func CityName(zone string) string {
+	if i := strings.LastIndex(zone, "/"); i >= 0 {
+		zone = zone[i+1:]
+	}
+	return strings.ReplaceAll(zone, "_", " ")
+}
+
+// suggest returns up to three names closest to key
+func suggest(key string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	best := make(map[string]int)
+	consider := func(display, name string) {
+		d := levenshtein(key, normalize(name))
+		// Allow roughly one typo per three characters
+		if d > 1+len([]rune(key))/3 {
+			return
+		}
+		if prev, ok := best[display]; !ok || d < prev {
+			best[display] = d
+		}
+	}
+
+	for _, city := range cities {
+		consider(city.Name, city.Name)
+		consider(city.Zone, city.Zone)
+		for _, alias := range city.Aliases {
+			consider(city.Name, alias)
+		}
+	}
+
+	candidates := make([]candidate, 0, len(best))
+	for name, d := range best {
+		candidates = append(candidates, candidate{name, d})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	var suggestions []string
+	for i := 0; i < len(candidates) && i < 3; i++ {
+		suggestions = append(suggestions, candidates[i].name)
+	}
+	return suggestions
+}
+
+// normalize lowercases s and treats underscores, dashes and repeated spaces alike
+func normalize(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.NewReplacer("_", " ", "-", " ", ".", "", "ё", "е").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// distance returns the great-circle distance between two points in kilometres
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+func mustParseCities(data string) []City {
+	var result []City
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		if len(fields) != 5 {
+			panic(fmt.Sprintf("timezone: invalid cities.csv line %d: %q", i+1, line))
+		}
+		lat, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			panic(fmt.Sprintf("timezone: invalid latitude on cities.csv line %d: %v", i+1, err))
+		}
+		lon, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			panic(fmt.Sprintf("timezone: invalid longitude on cities.csv line %d: %v", i+1, err))
+		}
+
+		city := City{
+			Name:      fields[0],
+			Latitude:  lat,
+			Longitude: lon,
+			Zone:      fields[3],
+		}
+		if fields[4] != "" {
+			city.Aliases = strings.Split(fields[4], "|")
+		}
+		result = append(result, city)
+	}
+	return result
+}
+
+func mustParseBoundaries(data string) []boundary {
+	var result []boundary
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		zone, polygon, ok := strings.Cut(line, ",")
+		if !ok {
+			panic(fmt.Sprintf("timezone: invalid boundaries.csv line %d: %q", i+1, line))
+		}
+		b := boundary{
+			zone:   zone,
+			minLat: math.Inf(1), maxLat: math.Inf(-1),
+			minLon: math.Inf(1), maxLon: math.Inf(-1),
+		}
+		for _, point := range strings.Split(polygon, "|") {
+			var lat, lon float64
+			if _, err := fmt.Sscanf(point, "%g %g", &lat, &lon); err != nil {
+				panic(fmt.Sprintf("timezone: invalid point %q on boundaries.csv line %d: %v", point, i+1, err))
+			}
+			b.points = append(b.points, [2]float64{lat, lon})
+			b.minLat, b.maxLat = min(b.minLat, lat), max(b.maxLat, lat)
+			b.minLon, b.maxLon = min(b.minLon, lon), max(b.maxLon, lon)
+		}
+		if len(b.points) < 3 {
+			panic(fmt.Sprintf("timezone: boundaries.csv line %d has fewer than 3 points", i+1))
+		}
+		result = append(result, b)
+	}
+	return result
+}
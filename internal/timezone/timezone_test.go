@@ -0,0 +1,173 @@
+package timezone
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"UTC", "UTC"},
+		{"Europe/Berlin", "Europe/Berlin"},
+		{"europe/berlin", "Europe/Berlin"},
+		{"America/New_York", "America/New_York"},
+		{"america/new york", "America/New_York"},
+		{"+03:00", "UTC+03:00"},
+		{"+3", "UTC+03:00"},
+		{"UTC-5", "UTC-05:00"},
+		{"utc -5", "UTC-05:00"},
+		{"GMT+5:30", "UTC+05:30"},
+		{"+0545", "UTC+05:45"},
+		{"UTC+0", "UTC"},
+		{"EST", "America/New_York"},
+		{"cet", "Europe/Berlin"},
+		{"MSK", "Europe/Moscow"},
+		{"moscow", "Europe/Moscow"},
+		{"Москва", "Europe/Moscow"},
+		{"new york", "America/New_York"},
+		{"New-York", "America/New_York"},
+		{"saint petersburg", "Europe/Moscow"},
+		{"  Tokyo ", "Asia/Tokyo"},
+		{"kiev", "Europe/Kyiv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if _, err := Load(got); err != nil {
+				t.Errorf("Load(%q) error = %v", got, err)
+			}
+		})
+	}
+}
+
+func TestParseSuggestions(t *testing.T) {
+	tests := []struct {
+		input          string
+		wantSuggestion string
+	}{
+		{"moskow", "Moscow"},
+		{"new yrok", "New York"},
+		{"berlinn", "Berlin"},
+		{"Europe/Berln", "Europe/Berlin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			var notFound *NotFoundError
+			if !errors.As(err, &notFound) {
+				t.Fatalf("Parse(%q) error = %v, want *NotFoundError", tt.input, err)
+			}
+			if len(notFound.Suggestions) == 0 || notFound.Suggestions[0] != tt.wantSuggestion {
+				t.Errorf("Parse(%q) suggestions = %v, want %q first", tt.input, notFound.Suggestions, tt.wantSuggestion)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidInput(t *testing.T) {
+	for _, input := range []string{"", "Local", "+15:00", "UTC-13", "+03:75", "Narnia"} {
+		if got, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) = %q, want error", input, got)
+		}
+	}
+}
+
+func TestLoadFixedOffset(t *testing.T) {
+	loc, err := Load("UTC+05:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, offset := time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone()
+	if offset != 5*3600+30*60 {
+		t.Errorf("offset = %d, want %d", offset, 5*3600+30*60)
+	}
+
+	loc, err = Load("UTC-05:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, offset = time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone()
+	if offset != -5*3600 {
+		t.Errorf("offset = %d, want %d", offset, -5*3600)
+	}
+}
+
+func TestFromCoordinates(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     string
+		exact    bool // Inside the boundary map rather than a guess
+	}{
+		{"Moscow suburbs", 55.9, 37.4, "Europe/Moscow", false},
+		{"Brooklyn", 40.65, -73.95, "America/New_York", true},
+		{"Near Novosibirsk", 54.9, 83.1, "Asia/Novosibirsk", false},
+		{"Potsdam", 52.39, 13.06, "Europe/Berlin", true},
+		{"Strasbourg", 48.58, 7.75, "Europe/Paris", true},
+		{"Kehl", 48.57, 7.82, "Europe/Berlin", true},
+		{"Annemasse", 46.19, 6.24, "Europe/Paris", true},
+		{"Maastricht", 50.85, 5.69, "Europe/Amsterdam", true},
+		{"Brest, Belarus", 52.09, 23.70, "Europe/Minsk", true},
+		{"Terespol", 52.07, 23.62, "Europe/Warsaw", true},
+		{"Haparanda", 65.83, 24.13, "Europe/Stockholm", true},
+		{"Tornio", 65.85, 24.18, "Europe/Helsinki", true},
+		{"Derry", 55.00, -7.32, "Europe/London", true},
+		{"Ciudad Juárez", 31.69, -106.42, "America/Ciudad_Juarez", true},
+		{"El Paso", 31.80, -106.45, "America/Denver", true},
+		{"Nogales, Sonora", 31.30, -110.94, "America/Hermosillo", true},
+		{"Nuevo Laredo", 27.45, -99.52, "America/Matamoros", true},
+		{"Louisville", 38.25, -85.76, "America/New_York", true},
+		{"Evansville", 37.97, -87.57, "America/Chicago", true},
+		{"Victoria", 48.43, -123.37, "America/Vancouver", true},
+		// Outside the boundary map the nearest city is only a guess, and near
+		// borders a wrong one: Oral in western Kazakhstan is on Asia/Oral
+		{"Oral", 51.2, 51.4, "Europe/Samara", false},
+		{"São Paulo", -23.5, -46.7, "America/Sao_Paulo", false},
+		{"Outback New South Wales", -29.0, 141.5, "Australia/Adelaide", false},
+		{"Mid Atlantic", 30.0, -40.0, "UTC-03:00", false},
+		{"South Pacific", -50.0, -130.0, "UTC-09:00", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, exact := FromCoordinates(tt.lat, tt.lon)
+			if got != tt.want || exact != tt.exact {
+				t.Errorf("FromCoordinates(%v, %v) = %q, %v; want %q, %v", tt.lat, tt.lon, got, exact, tt.want, tt.exact)
+			}
+		})
+	}
+}
+
+func TestCitiesDatasetZonesLoad(t *testing.T) {
+	if len(Cities()) == 0 {
+		t.Fatal("embedded cities dataset is empty")
+	}
+	for _, city := range Cities() {
+		if _, err := time.LoadLocation(city.Zone); err != nil {
+			t.Errorf("city %s has unknown zone %s: %v", city.Name, city.Zone, err)
+		}
+	}
+}
+
+func TestBoundariesDatasetZonesLoad(t *testing.T) {
+	if len(boundaries) == 0 {
+		t.Fatal("embedded boundaries dataset is empty")
+	}
+	for _, b := range boundaries {
+		if _, err := time.LoadLocation(b.zone); err != nil {
+			t.Errorf("boundary has unknown zone %s: %v", b.zone, err)
+		}
+	}
+}